@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/segmentio/kafka-go"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"transaction-system/config"
+	initkafka "transaction-system/initializers/kafka"
+	"transaction-system/pkg/eventbus"
+)
+
+/*
+	Утилита для разбора dead-letter топика Kafka (EVENT_BUS.BACKEND: kafka)
+	dlq -inspect [-limit 50]                 - выводит сообщения из DLQ вместе с заголовками ошибки
+	dlq -replay 0:15,1:42                    - возвращает выбранные сообщения (partition:offset) в основной топик
+*/
+
+const readTimeout = 10 * time.Second
+
+func main() {
+	inspect := flag.Bool("inspect", false, "print messages stored in the dead-letter topic")
+	replay := flag.String("replay", "", "comma separated partition:offset list to replay into the main topic")
+	limit := flag.Int("limit", 100, "max messages per partition to inspect")
+	flag.Parse()
+
+	_, cfg, errViper := config.NewViper("conf_local")
+	if errViper != nil {
+		log.Fatal(errors.WithMessage(errViper, "Viper startup error"))
+	}
+
+	if len(cfg.Kafka.Brokers) == 0 || cfg.Kafka.DLQTopic == "" {
+		log.Fatal("kafka brokers and dlq topic must be configured")
+	}
+
+	switch {
+	case *inspect:
+		err := inspectDLQ(cfg, *limit)
+		if err != nil {
+			log.Fatal(errors.WithMessage(err, "inspect DLQ"))
+		}
+	case *replay != "":
+		err := replayDLQ(cfg, *replay)
+		if err != nil {
+			log.Fatal(errors.WithMessage(err, "replay DLQ"))
+		}
+	default:
+		flag.Usage()
+	}
+}
+
+func inspectDLQ(cfg *config.Config, limit int) error {
+	partitions, err := dlqPartitions(cfg)
+	if err != nil {
+		return err
+	}
+
+	for _, partition := range partitions {
+		messages, err := readPartition(cfg, partition, limit)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range messages {
+			fmt.Printf("%d:%d key=%s\n", m.Partition, m.Offset, m.Key)
+			for _, h := range m.Headers {
+				fmt.Printf("\t%s: %s\n", h.Key, h.Value)
+			}
+			fmt.Printf("\tvalue: %s\n", m.Value)
+		}
+	}
+
+	return nil
+}
+
+func replayDLQ(cfg *config.Config, selection string) error {
+	selected, err := parseSelection(selection)
+	if err != nil {
+		return err
+	}
+
+	writer, err := initkafka.NewWriter(cfg, cfg.Kafka.Topic)
+	if err != nil {
+		return err
+	}
+	defer writer.Close()
+
+	for partition, offsets := range selected {
+		err = replayPartition(cfg, writer, partition, offsets)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// replayPartition возвращает в основной топик сообщения одной партиции, читая их одним ридером
+func replayPartition(cfg *config.Config, writer *kafka.Writer, partition int, offsets []int64) error {
+	reader, err := newPartitionReader(cfg, partition)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	for _, offset := range offsets {
+		m, err := readMessage(reader, partition, offset)
+		if err != nil {
+			return err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), readTimeout)
+		err = writer.WriteMessages(ctx, kafka.Message{
+			Key:     m.Key,
+			Value:   m.Value,
+			Headers: eventbus.ToKafkaHeaders(eventbus.StripDeadLetterHeaders(eventbus.FromKafkaMessage(m).Headers)),
+		})
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("replayed %d:%d\n", partition, offset)
+	}
+
+	return nil
+}
+
+func dlqPartitions(cfg *config.Config) ([]int, error) {
+	dialer, err := initkafka.NewDialer(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := dialer.Dial("tcp", cfg.Kafka.Brokers[0])
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	partitions, err := conn.ReadPartitions(cfg.Kafka.DLQTopic)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(partitions))
+	for _, p := range partitions {
+		ids = append(ids, p.ID)
+	}
+
+	return ids, nil
+}
+
+// readPartition читает из партиции не больше limit сообщений, начиная с самого раннего
+func readPartition(cfg *config.Config, partition int, limit int) ([]kafka.Message, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), readTimeout)
+	defer cancel()
+
+	dialer, err := initkafka.NewDialer(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := dialer.DialLeader(ctx, "tcp", cfg.Kafka.Brokers[0], cfg.Kafka.DLQTopic, partition)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	first, last, err := conn.ReadOffsets()
+	if err != nil {
+		return nil, err
+	}
+	if first >= last {
+		return nil, nil
+	}
+
+	reader, err := newPartitionReader(cfg, partition)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	err = reader.SetOffset(first)
+	if err != nil {
+		return nil, err
+	}
+
+	// Оффсеты в партиции могут идти с пропусками, поэтому читаем подряд до последнего оффсета, а не по номерам
+	var messages []kafka.Message
+	for len(messages) < limit {
+		m, err := reader.ReadMessage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+
+		if m.Offset >= last-1 {
+			break
+		}
+	}
+
+	return messages, nil
+}
+
+func newPartitionReader(cfg *config.Config, partition int) (*kafka.Reader, error) {
+	dialer, err := initkafka.NewDialer(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   cfg.Kafka.Brokers,
+		Topic:     cfg.Kafka.DLQTopic,
+		Partition: partition,
+		Dialer:    dialer,
+	}), nil
+}
+
+// readMessage читает сообщение с оффсетом offset. Если его нет, например, он удален по retention,
+// Kafka отдает следующее сообщение, и вместо него возвращается ошибка
+func readMessage(reader *kafka.Reader, partition int, offset int64) (kafka.Message, error) {
+	err := reader.SetOffset(offset)
+	if err != nil {
+		return kafka.Message{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), readTimeout)
+	defer cancel()
+
+	m, err := reader.ReadMessage(ctx)
+	if err != nil {
+		return kafka.Message{}, err
+	}
+	if m.Offset != offset {
+		return kafka.Message{}, fmt.Errorf("message %d:%d not found, next message is %d:%d", partition, offset, partition, m.Offset)
+	}
+
+	return m, nil
+}
+
+// parseSelection разбирает строку вида "0:15,0:16,1:42" в набор оффсетов по партициям
+func parseSelection(selection string) (map[int][]int64, error) {
+	selected := make(map[int][]int64)
+	for _, item := range strings.Split(selection, ",") {
+		parts := strings.Split(strings.TrimSpace(item), ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid message reference %q, expected partition:offset", item)
+		}
+
+		partition, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid partition in %q: %w", item, err)
+		}
+
+		offset, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid offset in %q: %w", item, err)
+		}
+
+		selected[partition] = append(selected[partition], offset)
+	}
+
+	return selected, nil
+}
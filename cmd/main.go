@@ -1,25 +1,39 @@
 package main
 
 import (
-	"fmt"
+	"context"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 	"log"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
+	"time"
 	"transaction-system/config"
-	"transaction-system/initializers/kafka"
+	"transaction-system/initializers/eventbus"
 	"transaction-system/initializers/postgre"
 	_ "transaction-system/initializers/postgre/migration"
+	"transaction-system/initializers/tracing"
+	pkgeventbus "transaction-system/pkg/eventbus"
+	"transaction-system/pkg/metrics"
 	"transaction-system/pkg/postgres"
 	"transaction-system/pkg/zaplogger"
 	"transaction-system/service"
 	"transaction-system/sheduler"
 	"transaction-system/storage"
+	"transaction-system/transport/grpc"
 	"transaction-system/transport/http"
 )
 
+const (
+	defaultShutdownTimeout = 30 * time.Second
+	// Отдельный срок на отправку спанов: общий срок остановки к этому шагу может быть уже израсходован
+	traceFlushTimeout = 5 * time.Second
+	// Доля занятых соединений пула БД, начиная с которой /readyz отвечает 503
+	defaultPoolUsage = 0.9
+)
+
 func main() {
 	// Viper
 	_, cfg, errViper := config.NewViper("conf_local")
@@ -33,50 +47,144 @@ func main() {
 		log.Fatal(errors.WithMessage(errZapLogger, "Zap logger startup error"))
 	}
 
+	// OpenTelemetry: экспорт спанов по OTLP или в файл
+	tracingCleanup, err := tracing.NewTracerProvider(cfg, logger)
+	if err != nil {
+		logger.Fatal("failed to initialize tracing", zap.Error(err))
+	}
+
 	// Postgre
 	db, postgreCleanup, err := postgre.NewDB(cfg, logger)
 	if err != nil {
 		logger.Fatal("failed to connect to WeatherDB", zap.Error(err))
 	}
 
-	// Postgre migration
-	_, _, err = postgres.Migrate(db, postgres.ActionUp)
+	// Схема меняется только через cmd/txadmin, сервер лишь проверяет, что она не отстает от кода
+	schemaVersion, latestVersion, err := postgres.Status(db)
 	if err != nil {
-		logger.Fatal("migration error", zap.Error(err))
+		logger.Fatal("failed to check schema version", zap.Error(err))
+	}
+	if schemaVersion < latestVersion {
+		logger.Fatal("database schema is behind, run txadmin migrate up",
+			zap.Int64("version", schemaVersion), zap.Int64("latest", latestVersion))
 	}
 
-	// Kafka
-	producer, producerCleanup, err := kafka.NewProducer(cfg, logger)
+	// Event bus (Kafka, NATS или in-memory)
+	bus, busCleanup, err := eventbus.NewEventBus(cfg, logger)
 	if err != nil {
-		logger.Fatal("failed to initialize Producer", zap.Error(err))
+		logger.Fatal("failed to initialize event bus", zap.Error(err))
 	}
 
-	consumer, consumerCleanup, err := kafka.NewConsumer(cfg, logger)
+	dataBaseRepo := storage.NewDataBaseRepositoryImpl(db, bus.Publisher, logger)
+	DBWorker := service.NewDataBaseWorker(dataBaseRepo, cfg)
+	operationWorker := service.NewOperationWorker(dataBaseRepo, DBWorker, cfg, logger)
+	invoiceController := http.NewWatController(DBWorker, operationWorker, cfg.Async.Default, logger)
+	if cfg.Auth.APIKeys.Enabled && cfg.Auth.APIKeys.EncryptionKey == "" {
+		logger.Fatal("failed to initialize api keys", zap.Error(service.ErrEncryptionKeyRequired))
+	}
+	apiKeyWorker, err := service.NewAPIKeyWorker(dataBaseRepo, cfg.Auth.APIKeys.EncryptionKey)
 	if err != nil {
-		logger.Fatal("failed to initialize Consumer", zap.Error(err))
+		logger.Fatal("failed to initialize api keys", zap.Error(err))
 	}
-
-	dataBaseRepo := storage.NewDataBaseRepositoryImpl(db, producer, consumer, logger)
-	DBWorker := service.NewDataBaseWorker(dataBaseRepo)
-	invoiceController := http.NewWatController(DBWorker, logger)
-	router := http.NewRouter(cfg, logger, invoiceController)
-	router.RegisterRoutes()
+	adminController := http.NewAdminController(apiKeyWorker, DBWorker, logger)
+	batchWorker := service.NewBatchWorker(dataBaseRepo, DBWorker, cfg, logger)
+	batchController := http.NewBatchController(batchWorker, logger)
+	router := http.NewRouter(cfg, logger, invoiceController, adminController, apiKeyWorker)
+	streamHub := service.NewStreamHub(dataBaseRepo, DBWorker, cfg, logger)
+	streamController := http.NewStreamController(streamHub, cfg, logger)
+	router.MountVersion(http.CurrentVersion, batchController)
+	webhookWorker := service.NewWebhookWorker(dataBaseRepo, cfg, logger)
+	webhookController := http.NewWebhookController(webhookWorker, logger)
+	router.MountVersion(http.CurrentVersion, streamController)
+	router.MountVersion(http.CurrentVersion, webhookController)
+	transactionController := http.NewTransactionController(DBWorker, logger)
+	router.MountVersion(http.CurrentVersion, transactionController)
 
 	// scheduler
 	sch := sheduler.NewScheduler(cfg, dataBaseRepo, logger)
 	sch.Run()
 
-	// создаем канал ошибок errChain
-	errChain := make(chan error, 1)
+	// Консьюмеры шины переподписываются после ошибок брокера, пока подписки нет, /readyz сообщает об этом.
+	// Начатое сообщение дообрабатывается и после остановки, чтобы не обрывать его транзакцию БД
+	handle := func(ctx context.Context, m pkgeventbus.Message) error {
+		return dataBaseRepo.HandleEvent(context.WithoutCancel(ctx), m)
+	}
+	handler := pkgeventbus.WithRetry(handle, eventbus.NewRetryPolicy(cfg), bus.DLQ, logger)
+	eventConsumer := pkgeventbus.NewConsumer("events", bus.Subscriber, handler, pkgeventbus.RetryPolicy{}, logger)
+	// поток событий кошелька: каждый экземпляр получает все события из шины
+	streamConsumer := pkgeventbus.NewConsumer("stream", bus.Stream, streamHub.HandleEvent, pkgeventbus.RetryPolicy{}, logger)
+
+	// /healthz и /readyz
+	poolUsage := cfg.Health.PoolUsage
+	if poolUsage <= 0 {
+		poolUsage = defaultPoolUsage
+	}
+	checks := []service.HealthCheck{
+		{Name: "postgres", Check: func(ctx context.Context) error { return postgres.Ping(ctx, db, poolUsage) }},
+		{Name: "migrations", Check: func(context.Context) error { return postgres.CheckVersion(db) }},
+		{Name: "scheduler", Check: sch.Alive},
+		{Name: "event_consumer", Check: eventConsumer.Alive},
+		{Name: "event_stream_consumer", Check: streamConsumer.Alive},
+	}
+	checks = append(checks, busChecks(bus)...)
+	router.MountRoot(http.NewHealthController(service.NewHealthChecker(cfg, checks...)))
+
+	// /metrics
+	metrics.Registry.MustRegister(
+		metrics.NewPoolCollector(db),
+		metrics.NewPendingCollector(dataBaseRepo.PendingTransactions, logger),
+		busCollector(bus),
+	)
+	router.RegisterRoutes()
+
+	// Фоновые обработчики и консьюмеры останавливаются отменой своих контекстов, WaitGroup позволяет дождаться их выхода
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+
+	// фоновое проведение асинхронных операций
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		operationWorker.Run(workersCtx)
+	}()
+
+	// фоновая обработка пакетов
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		batchWorker.Run(workersCtx)
+	}()
+
+	// доставка вебхуков
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		webhookWorker.Run(workersCtx)
+	}()
+
+	consumersCtx, stopConsumers := context.WithCancel(context.Background())
+	var consumers sync.WaitGroup
+
+	// consumer и поток событий кошелька
+	for _, consumer := range []*pkgeventbus.Consumer{eventConsumer, streamConsumer} {
+		consumers.Add(1)
+		go func(consumer *pkgeventbus.Consumer) {
+			defer consumers.Done()
+			consumer.Run(consumersCtx)
+		}(consumer)
+	}
+
+	// создаем канал ошибок errChain, nil - штатная остановка по сигналу
+	errChain := make(chan error, 3)
 
 	/*
 		Запускаем горутину, которая содержит код для запуска роутера
 		Если происходит ошибка при запуске, она отправляется в errChain
 	*/
 	go func() {
-		err = router.Start()
+		err := router.Start()
 		if err != nil {
-			fmt.Print("exit router start with error:", err)
+			logger.Error("http server stopped", zap.Error(err))
 		}
 
 		errChain <- err
@@ -84,24 +192,151 @@ func main() {
 
 	/*
 		Еще одна асинхронная горутина, которая слушает сигналы прерывания (Ctrl+C) или завершения программы (SIGTERM)
-		При получении сигнала она отправляет ошибку в errChain
+		При получении сигнала она отправляет nil в errChain
 	*/
 	go func() {
 		signals := make(chan os.Signal, 1)
 		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
-		fmt.Print("\t<-signals")
 		//Ожидаем сигнала завершения
 		s := <-signals
 
-		errChain <- errors.New("get os signal" + s.String())
+		logger.Info("Received signal, shutting down", zap.String("signal", s.String()))
+		errChain <- nil
 	}()
 
+	// gRPC-сервер рядом с HTTP API
+	var grpcServer *grpc.Server
+	if cfg.GRPC.Enabled {
+		grpcServer, err = grpc.NewServer(cfg, DBWorker, streamHub, logger)
+		if err != nil {
+			logger.Fatal("failed to initialize grpc server", zap.Error(err))
+		}
+		go func() {
+			err := grpcServer.Start()
+			if err != nil {
+				logger.Error("grpc server stopped", zap.Error(err))
+			}
+
+			errChain <- err
+		}()
+	}
+
 	errRun := <-errChain
-	logger.Info("Application error", zap.Error(errRun))
+	failed := errRun != nil
 
+	shutdownTimeout := time.Duration(cfg.HTTP.ShutdownTimeout) * time.Second
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = defaultShutdownTimeout
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// Остановка идет в обратном порядке зависимостей: каждый следующий шаг закрывает то, чем пользовались предыдущие
+	step := func(name string, err error) {
+		if err != nil {
+			logger.Error("Shutdown step failed", zap.String("step", name), zap.Error(err))
+			failed = true
+		}
+	}
+
+	// 1. Перестаем принимать запросы и дожидаемся текущих
+	step("http server", router.Shutdown(shutdownCtx))
+	if grpcServer != nil {
+		step("grpc server", grpcServer.Stop(shutdownCtx))
+	}
+
+	// 2. Планировщик дожидается запущенного проведения транзакций
+	sch.Stop()
+
+	// 3. Фоновые обработчики операций и вебхуков
+	stopWorkers()
+	step("background workers", wait(shutdownCtx, &workers))
+
+	// 4. Консьюмеры дообрабатывают текущие сообщения, затем закрываются
+	stopConsumers()
+	step("event consumers", wait(shutdownCtx, &consumers))
+	step("close event consumers", busCleanup.Consumers())
+
+	// 5. Продюсеры дописывают накопленные сообщения
+	step("close event producers", busCleanup.Producers())
+
+	// 6. БД закрывается последней
+	step("close database", postgreCleanup())
+
+	// 7. Накопленные спаны отправляются после остановки всего, что их пишет
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), traceFlushTimeout)
+	step("flush traces", tracingCleanup(flushCtx))
+	cancelFlush()
+
+	if failed {
+		logger.Error("Application stopped with errors")
+		loggerCleanup()
+		cancel()
+		os.Exit(1)
+	}
+
+	logger.Info("Application stopped")
 	loggerCleanup()
-	err = postgreCleanup()
-	err = consumerCleanup()
-	err = producerCleanup()
+}
+
+// busChecks - проверки доступности брокера для каналов шины, которым нужна сеть
+func busChecks(bus *pkgeventbus.Bus) []service.HealthCheck {
+	channels := []struct {
+		name    string
+		channel any
+	}{
+		{"event_publisher", bus.Publisher},
+		{"event_subscriber", bus.Subscriber},
+		{"event_stream", bus.Stream},
+	}
+
+	var checks []service.HealthCheck
+	for _, ch := range channels {
+		if pinger, ok := ch.channel.(pkgeventbus.Pinger); ok {
+			checks = append(checks, service.HealthCheck{Name: ch.name, Check: pinger.Ping})
+		}
+	}
+
+	return checks
+}
+
+// wait дожидается wg, но не дольше ctx
+func wait(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// busCollector - метрики писателей и ридеров шины, если она работает поверх Kafka
+func busCollector(bus *pkgeventbus.Bus) *metrics.KafkaCollector {
+	collector := metrics.NewKafkaCollector()
+	channels := []struct {
+		name    string
+		channel any
+	}{
+		{"publisher", bus.Publisher},
+		{"dlq", bus.DLQ},
+		{"subscriber", bus.Subscriber},
+		{"stream", bus.Stream},
+	}
+
+	for _, ch := range channels {
+		switch c := ch.channel.(type) {
+		case *pkgeventbus.KafkaPublisher:
+			collector.AddWriter(ch.name, c.Stats)
+		case *pkgeventbus.KafkaSubscriber:
+			collector.AddReader(ch.name, c.Stats)
+		}
+	}
 
+	return collector
 }
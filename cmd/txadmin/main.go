@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/go-pg/pg/v10"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"transaction-system/config"
+	"transaction-system/initializers/eventbus"
+	"transaction-system/initializers/postgre"
+	_ "transaction-system/initializers/postgre/migration"
+	"transaction-system/pkg/postgres"
+	"transaction-system/pkg/zaplogger"
+	"transaction-system/service"
+	"transaction-system/storage"
+)
+
+/*
+	Утилита обслуживания: схема БД меняется только ей, сервер при старте лишь проверяет версию схемы
+	txadmin migrate [-dry-run] up [version]  - применяет миграции, все или до указанной версии
+	txadmin migrate [-dry-run] down          - откатывает последнюю миграцию
+	txadmin migrate [-dry-run] reset         - откатывает все миграции
+	txadmin migrate version                  - выводит текущую и последнюю версии схемы
+	txadmin migrate [-dry-run] set_version N - записывает версию без выполнения миграций
+	txadmin settle                           - проводит созданные транзакции, как планировщик
+	txadmin reconcile                        - сверка данных, код выхода 1, если найдены нарушения
+	txadmin rekey api-key ID                 - новый секрет API-ключа
+	txadmin rekey webhook ID                 - новый секрет подписи вебхука
+	txadmin encrypt api-keys                 - шифрует ключи подписи, выпущенные до AUTH.API_KEYS.ENCRYPTION_KEY
+	-dry-run только печатает SQL миграций, не выполняя его: из БД читается лишь текущая версия схемы
+*/
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: txadmin migrate [-dry-run] up [version] | down | reset | version | set_version N")
+		fmt.Fprintln(os.Stderr, "       txadmin settle | reconcile | rekey api-key ID | rekey webhook ID | encrypt api-keys")
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	_, cfg, errViper := config.NewViper("conf_local")
+	if errViper != nil {
+		log.Fatal(errors.WithMessage(errViper, "Viper startup error"))
+	}
+
+	logger, loggerCleanup, errZapLogger := zaplogger.New(zaplogger.Mode(cfg.Logger.Development))
+	if errZapLogger != nil {
+		log.Fatal(errors.WithMessage(errZapLogger, "Zap logger startup error"))
+	}
+	defer loggerCleanup()
+
+	db, postgreCleanup, err := postgre.NewDB(cfg, logger)
+	if err != nil {
+		log.Fatal(errors.WithMessage(err, "connect to DB"))
+	}
+	defer postgreCleanup()
+
+	args := flag.Args()
+	switch args[0] {
+	case "migrate":
+		err = migrate(db, args[1:])
+	case "settle":
+		err = settle(cfg, db, logger)
+	case "reconcile":
+		err = reconcile(db, logger)
+	case "rekey":
+		err = rekey(db, cfg, logger, args[1:])
+	case "encrypt":
+		err = encrypt(db, cfg, logger, args[1:])
+	default:
+		flag.Usage()
+		err = fmt.Errorf("unknown command %q", args[0])
+	}
+	if err != nil {
+		postgreCleanup()
+		log.Fatal(errors.WithMessage(err, args[0]))
+	}
+}
+
+func migrate(db *pg.DB, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "print SQL without changing the database")
+	_ = fs.Parse(args)
+
+	actions := fs.Args()
+	if len(actions) == 0 {
+		return errors.New("action is required: up, down, reset, version or set_version")
+	}
+
+	if actions[0] == postgres.ActionVersion {
+		current, latest, err := postgres.Status(db)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("current version %d, latest %d\n", current, latest)
+		return nil
+	}
+
+	var (
+		oldVersion, newVersion int64
+		err                    error
+	)
+	if *dryRun {
+		oldVersion, newVersion, err = postgres.DryRun(db, os.Stdout, actions...)
+	} else {
+		oldVersion, newVersion, err = postgres.Migrate(db, actions...)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		fmt.Printf("-- dry run: version %d -> %d, nothing was changed\n", oldVersion, newVersion)
+	} else {
+		fmt.Printf("version %d -> %d\n", oldVersion, newVersion)
+	}
+
+	return nil
+}
+
+func settle(cfg *config.Config, db *pg.DB, logger *zap.Logger) error {
+	// События о проведении публикуются так же, как из планировщика сервера
+	bus, busCleanup, err := eventbus.NewEventBus(cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer busCleanup.Close()
+
+	count, err := storage.NewDataBaseRepositoryImpl(db, bus.Publisher, logger).UpdateTransactionStatusToSuccess(context.Background())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("settled %d transactions\n", count)
+	return nil
+}
+
+func reconcile(db *pg.DB, logger *zap.Logger) error {
+	discrepancies, err := storage.NewDataBaseRepositoryImpl(db, nil, logger).Reconcile(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if len(discrepancies) == 0 {
+		fmt.Println("no discrepancies found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHECK\tCLIENT\tID\tDETAIL")
+	for _, d := range discrepancies {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", d.Check, optional(d.ClientID), optional(d.ID), d.Detail)
+	}
+	_ = w.Flush()
+
+	return fmt.Errorf("found %d discrepancies", len(discrepancies))
+}
+
+func rekey(db *pg.DB, cfg *config.Config, logger *zap.Logger, args []string) error {
+	if len(args) != 2 {
+		return errors.New("expected rekey api-key ID or rekey webhook ID")
+	}
+
+	id, err := strconv.Atoi(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid id %q", args[1])
+	}
+
+	repo := storage.NewDataBaseRepositoryImpl(db, nil, logger)
+	ctx := context.Background()
+
+	switch args[0] {
+	case "api-key":
+		apiKeys, err := service.NewAPIKeyWorker(repo, cfg.Auth.APIKeys.EncryptionKey)
+		if err != nil {
+			return err
+		}
+
+		key, secret, err := apiKeys.RotateAPIKey(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("key_id %s\nsecret %s\n", key.KeyID, secret)
+		fmt.Println("the previous secret is no longer accepted, the new one is shown only once")
+	case "webhook":
+		hook, err := service.NewWebhookWorker(repo, cfg, logger).RotateWebhookSecret(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("webhook %d %s\nsecret %s\n", hook.ID, hook.URL, hook.Secret)
+	default:
+		return fmt.Errorf("unknown rekey target %q, expected api-key or webhook", args[0])
+	}
+
+	return nil
+}
+
+func encrypt(db *pg.DB, cfg *config.Config, logger *zap.Logger, args []string) error {
+	if len(args) != 1 || args[0] != "api-keys" {
+		return errors.New("expected encrypt api-keys")
+	}
+
+	apiKeys, err := service.NewAPIKeyWorker(storage.NewDataBaseRepositoryImpl(db, nil, logger), cfg.Auth.APIKeys.EncryptionKey)
+	if err != nil {
+		return err
+	}
+
+	count, err := apiKeys.EncryptPlaintextAPIKeys(context.Background())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("encrypted %d api keys, merchant secrets are unchanged\n", count)
+	return nil
+}
+
+func optional(v int) string {
+	if v == 0 {
+		return "-"
+	}
+
+	return strconv.Itoa(v)
+}
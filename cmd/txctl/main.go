@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+	"transaction-system/pkg/client"
+)
+
+/*
+	Клиент командной строки для API transaction-system. Адрес и учетные данные берутся из профиля,
+	см. cmd/txctl/config_example.yaml
+	txctl [-config path] [-profile name] [-url url] [-o table|json] <команда>
+
+	invoice  -currency 840 -amount 100.5 -wallet 101234567 [-card N] [-key K] [-async [-wait]]
+	withdraw -currency 840 -amount 50 -card 5267890123456789 [-key K] [-async [-wait]]
+	balance  -wallet 101234567 | -card 5267890123456789
+	tx get <id>
+	tx list  -wallet 101234567 | -card N [-status Success] [-limit 20] [-before ID]
+	refund   <id>
+	client create -wallet 101234567 [-card N]
+*/
+
+const (
+	exitError = 1
+	exitUsage = 2
+
+	waitInterval = time.Second
+)
+
+var errUsage = errors.New("usage")
+
+func main() {
+	global := flag.NewFlagSet("txctl", flag.ContinueOnError)
+	configPath := global.String("config", defaultConfigPath(), "profile file")
+	profileName := global.String("profile", "", "profile name, $TXCTL_PROFILE or DEFAULT from the profile file if empty")
+	url := global.String("url", "", "service url, overrides the profile")
+	format := global.String("o", outputTable, "output format: table or json")
+	timeout := global.Duration("timeout", 30*time.Second, "command timeout including retries")
+	global.Usage = usage(global)
+
+	if err := global.Parse(os.Args[1:]); err != nil {
+		os.Exit(exitUsage)
+	}
+
+	if *format != outputTable && *format != outputJSON || global.NArg() == 0 {
+		global.Usage()
+		os.Exit(exitUsage)
+	}
+
+	profile, err := loadProfile(*configPath, *profileName, *url)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "txctl:", err)
+		os.Exit(exitError)
+	}
+
+	c, err := profile.client()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "txctl:", err)
+		os.Exit(exitError)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	out := &printer{w: os.Stdout, format: *format}
+	err = run(ctx, c, out, global.Args())
+	switch {
+	case errors.Is(err, errUsage):
+		global.Usage()
+		cancel()
+		os.Exit(exitUsage)
+	case err != nil:
+		fmt.Fprintln(os.Stderr, "txctl:", describe(err))
+		cancel()
+		os.Exit(exitError)
+	}
+}
+
+func usage(global *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintln(os.Stderr, `usage: txctl [flags] <command>
+
+commands:
+  invoice  -currency CODE -amount N -wallet N | -card N [-key K] [-async [-wait]]
+  withdraw -currency CODE -amount N -wallet N | -card N [-key K] [-async [-wait]]
+  balance  -wallet N | -card N
+  tx get ID
+  tx list  -wallet N | -card N [-status S] [-limit N] [-before ID]
+  refund   ID
+  client create -wallet N [-card N]
+
+flags:`)
+		global.PrintDefaults()
+	}
+}
+
+func run(ctx context.Context, c *client.Client, out *printer, args []string) error {
+	switch args[0] {
+	case "invoice":
+		return operation(ctx, c, out, client.OperationInvoice, args[1:])
+	case "withdraw":
+		return operation(ctx, c, out, client.OperationWithdraw, args[1:])
+	case "balance":
+		return balance(ctx, c, out, args[1:])
+	case "refund":
+		id, err := idArg(args[1:])
+		if err != nil {
+			return err
+		}
+		refund, err := c.Refund(ctx, id)
+		if err != nil {
+			return err
+		}
+		return out.print(refund)
+	}
+
+	if len(args) < 2 {
+		return errUsage
+	}
+
+	switch args[0] + " " + args[1] {
+	case "tx get":
+		id, err := idArg(args[2:])
+		if err != nil {
+			return err
+		}
+		transaction, err := c.GetTransaction(ctx, id)
+		if err != nil {
+			return err
+		}
+		return out.print(transaction)
+	case "tx list":
+		return listTransactions(ctx, c, out, args[2:])
+	case "client create":
+		return createClient(ctx, c, out, args[2:])
+	}
+
+	return errUsage
+}
+
+func operation(ctx context.Context, c *client.Client, out *printer, operation string, args []string) error {
+	fs := flag.NewFlagSet(operation, flag.ContinueOnError)
+	currency := fs.Int("currency", 0, "ISO 4217 numeric currency code, e.g. 840")
+	amountValue := fs.Float64("amount", 0, "amount")
+	wallet := fs.Int("wallet", 0, "wallet number")
+	card := fs.Int("card", 0, "card number")
+	key := fs.String("key", "", "idempotency key, random if empty")
+	async := fs.Bool("async", false, "queue the operation and print its status")
+	wait := fs.Bool("wait", false, "with -async: wait until the operation is completed or failed")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	req := client.OperationRequest{CurrencyCode: *currency, Amount: *amountValue, WalletNumber: *wallet, CardNumber: *card, IdempotencyKey: *key}
+
+	if !*async {
+		var (
+			transaction *client.Transaction
+			err         error
+		)
+		if operation == client.OperationInvoice {
+			transaction, err = c.Invoice(ctx, req)
+		} else {
+			transaction, err = c.Withdraw(ctx, req)
+		}
+
+		var accepted *client.AcceptedError
+		if errors.As(err, &accepted) {
+			return out.print(accepted.Operation)
+		}
+		if err != nil {
+			return err
+		}
+		return out.print(transaction)
+	}
+
+	var (
+		op  *client.Operation
+		err error
+	)
+	if operation == client.OperationInvoice {
+		op, err = c.InvoiceAsync(ctx, req)
+	} else {
+		op, err = c.WithdrawAsync(ctx, req)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *wait {
+		op, err = c.WaitOperation(ctx, op.ID, waitInterval)
+		if err != nil {
+			return err
+		}
+	}
+
+	return out.print(op)
+}
+
+func balance(ctx context.Context, c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("balance", flag.ContinueOnError)
+	wallet := fs.Int("wallet", 0, "wallet number")
+	card := fs.Int("card", 0, "card number")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	var (
+		result *client.Balance
+		err    error
+	)
+	switch {
+	case *wallet != 0:
+		result, err = c.WalletBalance(ctx, *wallet)
+	case *card != 0:
+		result, err = c.CardBalance(ctx, *card)
+	default:
+		return errUsage
+	}
+	if err != nil {
+		return err
+	}
+
+	return out.print(result)
+}
+
+func listTransactions(ctx context.Context, c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("tx list", flag.ContinueOnError)
+	wallet := fs.Int("wallet", 0, "wallet number")
+	card := fs.Int("card", 0, "card number")
+	status := fs.String("status", "", "Created, Success or Error")
+	limit := fs.Int("limit", 20, "page size, up to 500")
+	before := fs.Int("before", 0, "show transactions with id below this one")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	if *wallet == 0 && *card == 0 {
+		return errUsage
+	}
+
+	transactions, err := c.ListTransactions(ctx, client.TransactionFilter{
+		WalletNumber: *wallet,
+		CardNumber:   *card,
+		Status:       *status,
+		BeforeID:     *before,
+		Limit:        *limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	return out.print(transactions)
+}
+
+func createClient(ctx context.Context, c *client.Client, out *printer, args []string) error {
+	fs := flag.NewFlagSet("client create", flag.ContinueOnError)
+	wallet := fs.Int("wallet", 0, "wallet number")
+	card := fs.Int("card", 0, "card number")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	created, err := c.CreateClient(ctx, client.CreateClientRequest{WalletNumber: *wallet, CardNumber: *card})
+	if err != nil {
+		return err
+	}
+
+	return out.print(created)
+}
+
+func idArg(args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, errUsage
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil || id <= 0 {
+		return 0, errUsage
+	}
+
+	return id, nil
+}
+
+// describe добавляет к ошибке сервиса ошибки полей из details
+func describe(err error) string {
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) {
+		return err.Error()
+	}
+
+	message := apiErr.Error()
+	for _, d := range apiErr.Details {
+		message += fmt.Sprintf("\n  %s: %s (%s)", d.Field, d.Message, d.Code)
+	}
+
+	return message
+}
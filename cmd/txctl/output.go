@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+	"transaction-system/pkg/client"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// printer выводит результат команды таблицей или JSON как его вернул сервис
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+func (p *printer) print(v any) error {
+	if p.format == outputJSON {
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+
+	switch v := v.(type) {
+	case *client.Transaction:
+		transactionTable(tw, []client.Transaction{*v})
+	case []client.Transaction:
+		transactionTable(tw, v)
+	case *client.Balance:
+		fmt.Fprintln(tw, "CURRENCY\tNAME\tAVAILABLE\tFROZEN")
+		for _, currency := range v.Currencies {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", currency.CurrencyCode, currency.CurrencyName, amount(currency.Available), amount(currency.Frozen))
+		}
+	case *client.Operation:
+		fmt.Fprintln(tw, "ID\tOPERATION\tSTATUS\tCURRENCY\tAMOUNT\tTRANSACTION\tATTEMPTS\tERROR")
+		errorCode := ""
+		if v.Error != nil {
+			errorCode = v.Error.Code
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%s\t%s\t%d\t%s\n", v.ID, v.Operation, v.Status, v.CurrencyCode, amount(v.Amount), optional(v.TransactionID), v.Attempts, errorCode)
+	case *client.ClientInfo:
+		fmt.Fprintln(tw, "ID\tWALLET\tCARD")
+		fmt.Fprintf(tw, "%d\t%d\t%s\n", v.ID, v.WalletNumber, optional(v.CardNumber))
+	default:
+		return fmt.Errorf("no table layout for %T", v)
+	}
+
+	return tw.Flush()
+}
+
+func transactionTable(w io.Writer, transactions []client.Transaction) {
+	fmt.Fprintln(w, "ID\tCLIENT\tCURRENCY_ID\tAMOUNT\tSTATUS\tSEQUENCE\tREFUND_OF\tCREATED_AT")
+	for _, t := range transactions {
+		fmt.Fprintf(w, "%d\t%d\t%d\t%s\t%s\t%d\t%s\t%s\n",
+			t.ID, t.ClientID, t.CurrencyID, amount(t.Amount), t.Status, t.Sequence, optional(t.RefundOf), t.CreatedAt.Format(time.RFC3339))
+	}
+}
+
+func amount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// optional - пустая ячейка вместо нулевого ID
+func optional(id int) string {
+	if id == 0 {
+		return "-"
+	}
+
+	return strconv.Itoa(id)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"strings"
+	"transaction-system/pkg/client"
+)
+
+const (
+	configEnv      = "TXCTL_CONFIG"
+	profileEnv     = "TXCTL_PROFILE"
+	defaultProfile = "default"
+)
+
+// Profile - адрес сервиса и учетные данные. Используется TOKEN, если он задан, иначе подпись API_KEY и API_SECRET.
+// ADMIN_TOKEN нужен только для административных команд
+type Profile struct {
+	URL        string `mapstructure:"URL"`
+	Token      string `mapstructure:"TOKEN"`
+	APIKey     string `mapstructure:"API_KEY"`
+	APISecret  string `mapstructure:"API_SECRET"`
+	AdminToken string `mapstructure:"ADMIN_TOKEN"`
+}
+
+type profileFile struct {
+	Default  string             `mapstructure:"DEFAULT"`
+	Profiles map[string]Profile `mapstructure:"PROFILES"`
+}
+
+// defaultConfigPath - $TXCTL_CONFIG или ~/.config/txctl/config.yaml
+func defaultConfigPath() string {
+	if path := os.Getenv(configEnv); path != "" {
+		return path
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "txctl.yaml"
+	}
+
+	return filepath.Join(dir, "txctl", "config.yaml")
+}
+
+// loadProfile читает профиль name из файла path. Пустое имя - $TXCTL_PROFILE, затем DEFAULT из файла.
+// Отсутствующий файл не ошибка, если адрес передан флагом -url
+func loadProfile(path string, name string, url string) (*Profile, error) {
+	file := profileFile{}
+
+	v := viper.New()
+	v.SetConfigFile(path)
+	err := v.ReadInConfig()
+	switch {
+	case err == nil:
+		err = v.Unmarshal(&file)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+	case errors.Is(err, os.ErrNotExist) && url != "":
+	default:
+		return nil, fmt.Errorf("read profile file: %w", err)
+	}
+
+	if name == "" {
+		name = os.Getenv(profileEnv)
+	}
+	if name == "" {
+		name = file.Default
+	}
+	if name == "" {
+		name = defaultProfile
+	}
+
+	// viper приводит ключи к нижнему регистру, в том числе имена профилей
+	profile, ok := file.Profiles[strings.ToLower(name)]
+	if !ok && url == "" {
+		return nil, fmt.Errorf("profile %q not found in %s", name, path)
+	}
+
+	if url != "" {
+		profile.URL = url
+	}
+
+	return &profile, nil
+}
+
+func (p *Profile) client() (*client.Client, error) {
+	var opts []client.Option
+
+	switch {
+	case p.Token != "":
+		opts = append(opts, client.WithBearerToken(p.Token))
+	case p.APIKey != "":
+		opts = append(opts, client.WithAPIKey(p.APIKey, p.APISecret))
+	}
+
+	if p.AdminToken != "" {
+		opts = append(opts, client.WithAdminToken(p.AdminToken))
+	}
+
+	opts = append(opts, client.WithUserAgent("txctl"))
+
+	return client.New(p.URL, opts...)
+}
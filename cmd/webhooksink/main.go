@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+	"transaction-system/pkg/webhook"
+)
+
+/*
+	Локальный получатель вебхуков для проверки доставки без внешних сервисов
+	webhooksink -addr :8081 -secret whsec_...           - печатает вебхуки и проверяет подпись
+	webhooksink -secret whsec_... -fail 3               - отвечает 500 на первые 3 запроса, чтобы проверить повторы
+	webhooksink -secret whsec_... -fail-rate 0.5        - отвечает 500 на случайную половину запросов
+*/
+
+const signatureTolerance = 5 * time.Minute
+
+func main() {
+	addr := flag.String("addr", ":8081", "listen address")
+	secret := flag.String("secret", "", "webhook secret to verify X-Webhook-Signature, empty disables the check")
+	failFirst := flag.Int64("fail", 0, "respond 500 to the first N requests")
+	failRate := flag.Float64("fail-rate", 0, "probability of responding 500")
+	flag.Parse()
+
+	var received atomic.Int64
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		n := received.Add(1)
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		verdict := "signature not checked"
+		if *secret != "" {
+			err = webhook.Verify(*secret, r.Header.Get(webhook.HeaderTimestamp), r.Header.Get(webhook.HeaderSignature), body, signatureTolerance)
+			if err != nil {
+				log.Printf("#%d %s %s: %v", n, r.Header.Get(webhook.HeaderEvent), r.Header.Get(webhook.HeaderID), err)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			verdict = "signature ok"
+		}
+
+		if n <= *failFirst || rand.Float64() < *failRate {
+			log.Printf("#%d %s %s: simulated failure", n, r.Header.Get(webhook.HeaderEvent), r.Header.Get(webhook.HeaderID))
+			http.Error(w, "simulated failure", http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("#%d %s %s (%s)\n\t%s", n, r.Header.Get(webhook.HeaderEvent), r.Header.Get(webhook.HeaderID), verdict, strings.TrimSpace(string(body)))
+		fmt.Fprintln(w, "ok")
+	})
+
+	log.Printf("webhook sink listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
@@ -3,8 +3,21 @@ package config
 type Config struct {
 	PostgresDB PostgresDB `mapstructure:"POSTGRES_DB"`
 	Kafka      Kafka      `mapstructure:"KAFKA"`
+	NATS       NATS       `mapstructure:"NATS"`
+	EventBus   EventBus   `mapstructure:"EVENT_BUS"`
 	Logger     Logger     `mapstructure:"LOGGER"`
 	Scheduler  Scheduler  `mapstructure:"SCHEDULER"`
+	Limits     Limits     `mapstructure:"LIMITS"`
+	HTTP       HTTP       `mapstructure:"HTTP"`
+	GRPC       GRPC       `mapstructure:"GRPC"`
+	RateLimit  RateLimit  `mapstructure:"RATE_LIMIT"`
+	Batches    Batches    `mapstructure:"BATCHES"`
+	Async      Async      `mapstructure:"ASYNC"`
+	Stream     Stream     `mapstructure:"STREAM"`
+	Webhooks   Webhooks   `mapstructure:"WEBHOOKS"`
+	Auth       Auth       `mapstructure:"AUTH"`
+	Health     Health     `mapstructure:"HEALTH"`
+	Tracing    Tracing    `mapstructure:"TRACING"`
 	LocalURL   string     `mapstructure:"THIS_APP_URL"`
 }
 
@@ -16,9 +29,64 @@ type PostgresDB struct {
 }
 
 type Kafka struct {
-	Brokers []string `mapstructure:"BROKERS"`
-	Topic   string   `mapstructure:"TOPIC"`
-	GroupID string   `mapstructure:"GROUP_ID"`
+	Brokers  []string  `mapstructure:"BROKERS"`
+	Topic    string    `mapstructure:"TOPIC"`
+	GroupID  string    `mapstructure:"GROUP_ID"`
+	DLQTopic string    `mapstructure:"DLQ_TOPIC"`
+	SASL     KafkaSASL `mapstructure:"SASL"`
+	TLS      KafkaTLS  `mapstructure:"TLS"`
+
+	// Настройки продюсера: BATCH_SIZE - сообщений в батче, LINGER - мс ожидания наполнения батча,
+	// REQUIRED_ACKS - all, one или none, COMPRESSION - gzip, snappy, lz4 или zstd
+	BatchSize    int    `mapstructure:"BATCH_SIZE"`
+	Linger       int    `mapstructure:"LINGER"`
+	RequiredAcks string `mapstructure:"REQUIRED_ACKS"`
+	Compression  string `mapstructure:"COMPRESSION"`
+
+	// Создание недостающих топиков при старте
+	CreateTopics      bool `mapstructure:"CREATE_TOPICS"`
+	Partitions        int  `mapstructure:"PARTITIONS"`
+	ReplicationFactor int  `mapstructure:"REPLICATION_FACTOR"`
+}
+
+// KafkaSASL - MECHANISM: plain, scram-sha-256 или scram-sha-512. Пустое значение отключает SASL
+type KafkaSASL struct {
+	Mechanism string `mapstructure:"MECHANISM"`
+	Username  string `mapstructure:"USERNAME"`
+	Password  string `mapstructure:"PASSWORD"`
+}
+
+type KafkaTLS struct {
+	Enabled            bool   `mapstructure:"ENABLED"`
+	CAFile             string `mapstructure:"CA_FILE"`
+	CertFile           string `mapstructure:"CERT_FILE"`
+	KeyFile            string `mapstructure:"KEY_FILE"`
+	InsecureSkipVerify bool   `mapstructure:"INSECURE_SKIP_VERIFY"`
+}
+
+type NATS struct {
+	URL        string `mapstructure:"URL"`
+	Subject    string `mapstructure:"SUBJECT"`
+	Queue      string `mapstructure:"QUEUE"`
+	DLQSubject string `mapstructure:"DLQ_SUBJECT"`
+}
+
+// EventBus - выбор брокера событий: kafka, nats или memory
+type EventBus struct {
+	Backend string `mapstructure:"BACKEND"`
+	Retry   Retry  `mapstructure:"RETRY"`
+	// ProcessedRetention - сколько часов хранить ID обработанных событий для дедупликации
+	ProcessedRetention int `mapstructure:"PROCESSED_RETENTION"`
+	// RelayInterval - раз во сколько секунд отправлять события журнала, не опубликованные после коммита
+	RelayInterval int `mapstructure:"RELAY_INTERVAL"`
+}
+
+// Retry - политика повторной обработки событий консьюмером, интервалы в миллисекундах
+type Retry struct {
+	MaxAttempts    int     `mapstructure:"MAX_ATTEMPTS"`
+	InitialBackoff int     `mapstructure:"INITIAL_BACKOFF"`
+	MaxBackoff     int     `mapstructure:"MAX_BACKOFF"`
+	Multiplier     float64 `mapstructure:"MULTIPLIER"`
 }
 
 type Logger struct {
@@ -29,3 +97,133 @@ type Logger struct {
 type Scheduler struct {
 	Update int `mapstructure:"UPDATE"`
 }
+
+// Limits - MAX_AMOUNT задает максимальную сумму одной операции по ISO-коду валюты, например 840: 10000.
+// Валюта без лимита или с лимитом 0 не ограничена
+type Limits struct {
+	MaxAmount map[int]float64 `mapstructure:"MAX_AMOUNT"`
+}
+
+// HTTP - таймауты в секундах. WRITE_TIMEOUT не действует на потоки SSE и WebSocket,
+// SHUTDOWN_TIMEOUT - сколько ждать завершения запросов и фоновых обработчиков при остановке.
+// MAX_BODY_SIZE - предельный размер тела запроса в байтах, по умолчанию 1 МиБ
+type HTTP struct {
+	// ValidateOpenAPI включает сверку запросов и ответов с OpenAPI-спецификацией, только для dev-режима
+	ValidateOpenAPI bool  `mapstructure:"VALIDATE_OPENAPI"`
+	ReadTimeout     int   `mapstructure:"READ_TIMEOUT"`
+	WriteTimeout    int   `mapstructure:"WRITE_TIMEOUT"`
+	IdleTimeout     int   `mapstructure:"IDLE_TIMEOUT"`
+	ShutdownTimeout int   `mapstructure:"SHUTDOWN_TIMEOUT"`
+	MaxBodySize     int64 `mapstructure:"MAX_BODY_SIZE"`
+	// TrustedProxies - адреса и подсети прокси, чьим X-Forwarded-For верится при определении IP клиента.
+	// Пустой список - IP клиента берется из соединения
+	TrustedProxies []string `mapstructure:"TRUSTED_PROXIES"`
+}
+
+// Health - проверки /readyz. TIMEOUT в миллисекундах на каждую проверку, POOL_USAGE - доля занятых соединений пула БД,
+// начиная с которой сервис считается неготовым, например 0.9
+type Health struct {
+	Timeout   int     `mapstructure:"TIMEOUT"`
+	PoolUsage float64 `mapstructure:"POOL_USAGE"`
+}
+
+// Tracing - EXPORTER: otlp, file или none (по умолчанию). ENDPOINT - адрес коллектора OTLP/gRPC, например localhost:4317,
+// FILE - файл, в который экспортер file пишет спаны в JSON. SAMPLE_RATIO - доля трассируемых запросов от 0 до 1, 0 означает все
+type Tracing struct {
+	Exporter    string  `mapstructure:"EXPORTER"`
+	ServiceName string  `mapstructure:"SERVICE_NAME"`
+	Endpoint    string  `mapstructure:"ENDPOINT"`
+	Insecure    bool    `mapstructure:"INSECURE"`
+	File        string  `mapstructure:"FILE"`
+	SampleRatio float64 `mapstructure:"SAMPLE_RATIO"`
+}
+
+// GRPC - ADDR адрес gRPC-сервера, например :9090. TOKEN обязателен при ENABLED и требуется в метаданных authorization: Bearer <TOKEN>
+type GRPC struct {
+	Enabled bool   `mapstructure:"ENABLED"`
+	Addr    string `mapstructure:"ADDR"`
+	Token   string `mapstructure:"TOKEN"`
+}
+
+// Batches - MAX_ITEMS операций в одном пакете, CONCURRENCY операций пакета обрабатываются одновременно.
+// WORKERS пакетов обрабатываются в фоне параллельно, POLL_INTERVAL в миллисекундах
+type Batches struct {
+	MaxItems     int `mapstructure:"MAX_ITEMS"`
+	Concurrency  int `mapstructure:"CONCURRENCY"`
+	Workers      int `mapstructure:"WORKERS"`
+	PollInterval int `mapstructure:"POLL_INTERVAL"`
+}
+
+// Async - фоновое проведение операций. DEFAULT включает ответ 202 для /invoice и /withdraw без заголовка Prefer,
+// POLL_INTERVAL в миллисекундах, MAX_ATTEMPTS - сколько раз повторять операцию при временных ошибках БД или шины
+type Async struct {
+	Default      bool `mapstructure:"DEFAULT"`
+	Workers      int  `mapstructure:"WORKERS"`
+	PollInterval int  `mapstructure:"POLL_INTERVAL"`
+	MaxAttempts  int  `mapstructure:"MAX_ATTEMPTS"`
+}
+
+// Stream - поток событий кошелька по SSE и WebSocket. HEARTBEAT - интервал пустых сообщений в секундах,
+// BUFFER_SIZE - сколько событий ждет отправки медленному подписчику, прежде чем его соединение будет закрыто
+type Stream struct {
+	Heartbeat  int `mapstructure:"HEARTBEAT"`
+	BufferSize int `mapstructure:"BUFFER_SIZE"`
+	// AllowedOrigins - Origin страниц других хостов, которым разрешено открывать WebSocket, например https://app.example.com
+	AllowedOrigins []string `mapstructure:"ALLOWED_ORIGINS"`
+}
+
+// Webhooks - доставка вебхуков. POLL_INTERVAL, INITIAL_BACKOFF и MAX_BACKOFF в миллисекундах, TIMEOUT - в секундах.
+// После MAX_ATTEMPTS неудачных попыток доставка получает статус Failed и может быть отправлена повторно вручную.
+// ALLOW_PRIVATE_NETWORKS разрешает адреса в локальных и частных сетях, только для разработки
+type Webhooks struct {
+	Workers        int `mapstructure:"WORKERS"`
+	PollInterval   int `mapstructure:"POLL_INTERVAL"`
+	Timeout        int `mapstructure:"TIMEOUT"`
+	MaxAttempts    int `mapstructure:"MAX_ATTEMPTS"`
+	InitialBackoff int `mapstructure:"INITIAL_BACKOFF"`
+	MaxBackoff     int `mapstructure:"MAX_BACKOFF"`
+
+	AllowPrivateNetworks bool `mapstructure:"ALLOW_PRIVATE_NETWORKS"`
+}
+
+// RateLimit - лимиты запросов по API-ключу, кошельку и IP. ROUTES переопределяет DEFAULT для отдельных ручек,
+// PATH указывается без префикса версии, например /withdraw
+type RateLimit struct {
+	Enabled bool             `mapstructure:"ENABLED"`
+	Default RateLimitRule    `mapstructure:"DEFAULT"`
+	Routes  []RouteRateLimit `mapstructure:"ROUTES"`
+}
+
+// RateLimitRule - RPS запросов в секунду в среднем и BURST запросов подряд
+type RateLimitRule struct {
+	RPS   float64 `mapstructure:"RPS"`
+	Burst int     `mapstructure:"BURST"`
+}
+
+type RouteRateLimit struct {
+	Path          string `mapstructure:"PATH"`
+	RateLimitRule `mapstructure:",squash"`
+}
+
+type Auth struct {
+	APIKeys    APIKeysAuth `mapstructure:"API_KEYS"`
+	JWT        JWT         `mapstructure:"JWT"`
+	AdminToken string      `mapstructure:"ADMIN_TOKEN"`
+}
+
+// JWT - токены HS256 подписываются HS256_SECRET, токены RS256 проверяются ключами из JWKS_FILE
+type JWT struct {
+	Enabled     bool   `mapstructure:"ENABLED"`
+	HS256Secret string `mapstructure:"HS256_SECRET"`
+	JWKSFile    string `mapstructure:"JWKS_FILE"`
+	Issuer      string `mapstructure:"ISSUER"`
+	Audience    string `mapstructure:"AUDIENCE"`
+}
+
+// APIKeysAuth - REPLAY_WINDOW в секундах, на столько X-Timestamp может отличаться от времени сервера.
+// ENCRYPTION_KEY - 32 байта в base64, которыми шифруются ключи подписи в БД, обязателен при ENABLED
+type APIKeysAuth struct {
+	Enabled       bool   `mapstructure:"ENABLED"`
+	ReplayWindow  int    `mapstructure:"REPLAY_WINDOW"`
+	EncryptionKey string `mapstructure:"ENCRYPTION_KEY"`
+}
@@ -0,0 +1,162 @@
+package eventbus
+
+import (
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"time"
+	"transaction-system/config"
+	"transaction-system/initializers/kafka"
+	"transaction-system/initializers/nats"
+	"transaction-system/pkg/eventbus"
+)
+
+const (
+	memoryConsumerGroup = "transaction-system"
+	memoryStreamGroup   = "transaction-system-stream"
+)
+
+// Cleanup закрывает шину в два шага: Consumers - после того как обработка сообщений остановлена,
+// Producers - после того как все, кто публикует события, остановлены. Продюсеры при закрытии дописывают накопленные батчи
+type Cleanup struct {
+	Consumers func() error
+	Producers func() error
+}
+
+// Close закрывает консьюмеров, затем продюсеров
+func (c Cleanup) Close() error {
+	errConsumers := c.Consumers()
+	errProducers := c.Producers()
+
+	return errors.Join(errConsumers, errProducers)
+}
+
+// NewEventBus собирает publisher, subscriber и dead-letter канал для бэкенда из EVENT_BUS.BACKEND
+func NewEventBus(cfg *config.Config, logger *zap.Logger) (*eventbus.Bus, Cleanup, error) {
+	switch cfg.EventBus.Backend {
+	case eventbus.BackendKafka, "":
+		return newKafkaBus(cfg, logger)
+	case eventbus.BackendNATS:
+		return newNATSBus(cfg, logger)
+	case eventbus.BackendMemory:
+		return newMemoryBus(cfg, logger)
+	default:
+		return nil, Cleanup{}, fmt.Errorf("%w: %s", eventbus.ErrUnsupportedBackend, cfg.EventBus.Backend)
+	}
+}
+
+func NewRetryPolicy(cfg *config.Config) eventbus.RetryPolicy {
+	return eventbus.RetryPolicy{
+		MaxAttempts:    cfg.EventBus.Retry.MaxAttempts,
+		InitialBackoff: time.Duration(cfg.EventBus.Retry.InitialBackoff) * time.Millisecond,
+		MaxBackoff:     time.Duration(cfg.EventBus.Retry.MaxBackoff) * time.Millisecond,
+		Multiplier:     cfg.EventBus.Retry.Multiplier,
+	}.WithDefaults()
+}
+
+func newKafkaBus(cfg *config.Config, logger *zap.Logger) (*eventbus.Bus, Cleanup, error) {
+	if cfg.Kafka.CreateTopics {
+		err := kafka.EnsureTopics(cfg, logger)
+		if err != nil {
+			return nil, Cleanup{}, err
+		}
+	}
+
+	producer, producerCleanup, err := kafka.NewProducer(cfg, logger)
+	if err != nil {
+		return nil, Cleanup{}, err
+	}
+
+	consumer, consumerCleanup, err := kafka.NewConsumer(cfg, logger)
+	if err != nil {
+		_ = producerCleanup()
+		return nil, Cleanup{}, err
+	}
+
+	streamConsumer, streamConsumerCleanup, err := kafka.NewStreamConsumer(cfg, logger)
+	if err != nil {
+		_ = consumerCleanup()
+		_ = producerCleanup()
+		return nil, Cleanup{}, err
+	}
+
+	dlqProducer, dlqProducerCleanup, err := kafka.NewDLQProducer(cfg, logger)
+	if err != nil {
+		_ = streamConsumerCleanup()
+		_ = consumerCleanup()
+		_ = producerCleanup()
+		return nil, Cleanup{}, err
+	}
+
+	bus := &eventbus.Bus{
+		Publisher:  eventbus.NewKafkaPublisher(producer),
+		Subscriber: eventbus.NewKafkaSubscriber(consumer),
+		Stream:     eventbus.NewKafkaSubscriber(streamConsumer),
+		DLQ:        eventbus.NewKafkaPublisher(dlqProducer),
+	}
+
+	cleanup := Cleanup{
+		Consumers: func() error {
+			errConsumer := consumerCleanup()
+			errStream := streamConsumerCleanup()
+
+			return errors.Join(errConsumer, errStream)
+		},
+		Producers: func() error {
+			errProducer := producerCleanup()
+			errDLQ := dlqProducerCleanup()
+
+			return errors.Join(errProducer, errDLQ)
+		},
+	}
+
+	return bus, cleanup, nil
+}
+
+func newNATSBus(cfg *config.Config, logger *zap.Logger) (*eventbus.Bus, Cleanup, error) {
+	conn, connCleanup, err := nats.NewConn(cfg, logger)
+	if err != nil {
+		return nil, Cleanup{}, err
+	}
+
+	// Stream подписывается без очереди и поэтому получает все сообщения субъекта
+	bus := &eventbus.Bus{
+		Publisher:  eventbus.NewNATSPublisher(conn, cfg.NATS.Subject),
+		Subscriber: eventbus.NewNATSSubscriber(conn, cfg.NATS.Subject, cfg.NATS.Queue),
+		Stream:     eventbus.NewNATSSubscriber(conn, cfg.NATS.Subject, ""),
+		DLQ:        eventbus.NewNATSPublisher(conn, cfg.NATS.DLQSubject),
+	}
+
+	// Подписки снимаются при выходе из Subscribe, а Drain отправляет накопленные публикации и закрывает соединение
+	cleanup := Cleanup{
+		Consumers: func() error { return nil },
+		Producers: connCleanup,
+	}
+
+	return bus, cleanup, nil
+}
+
+func newMemoryBus(cfg *config.Config, logger *zap.Logger) (*eventbus.Bus, Cleanup, error) {
+	logger.Warn("Using in-memory event bus, events are not persisted")
+
+	memory := eventbus.NewMemoryBus(cfg.Kafka.Topic, 0)
+	dlq := eventbus.NewMemoryBus(cfg.Kafka.DLQTopic, 0)
+
+	bus := &eventbus.Bus{
+		Publisher:  memory,
+		Subscriber: memory.Subscriber(memoryConsumerGroup),
+		Stream:     memory.Subscriber(memoryStreamGroup),
+		DLQ:        dlq,
+	}
+
+	cleanup := Cleanup{
+		Consumers: func() error { return nil },
+		Producers: func() error {
+			logger.Info("Cleanup from in-memory event bus")
+			_ = dlq.Close()
+			return memory.Close()
+		},
+	}
+
+	return bus, cleanup, nil
+}
@@ -1,19 +1,33 @@
 package kafka
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
 	"go.uber.org/zap"
+	"os"
+	"time"
 	"transaction-system/config"
 )
 
+const (
+	dialTimeout              = 10 * time.Second
+	defaultPartitions        = 1
+	defaultReplicationFactor = 1
+	streamGroupSuffix        = "-stream-"
+)
+
 func NewProducer(cfg *config.Config, logger *zap.Logger) (*kafka.Writer, func() error, error) {
-	fmt.Println(cfg.Kafka.Brokers)
-	writer := kafka.NewWriter(kafka.WriterConfig{
-		Brokers:  cfg.Kafka.Brokers,
-		Topic:    cfg.Kafka.Topic,
-		Balancer: &kafka.LeastBytes{},
-	})
+	writer, err := NewWriter(cfg, cfg.Kafka.Topic)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	cleanup := func() error {
 		logger.Info("Cleanup from Kafka producer")
@@ -28,11 +42,39 @@ func NewProducer(cfg *config.Config, logger *zap.Logger) (*kafka.Writer, func()
 	return writer, cleanup, nil
 }
 
+func NewDLQProducer(cfg *config.Config, logger *zap.Logger) (*kafka.Writer, func() error, error) {
+	if cfg.Kafka.DLQTopic == "" {
+		return nil, nil, errors.New("kafka dlq topic is not configured")
+	}
+
+	writer, err := NewWriter(cfg, cfg.Kafka.DLQTopic)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cleanup := func() error {
+		logger.Info("Cleanup from Kafka DLQ producer")
+		err := writer.Close()
+		if err != nil {
+			return err
+		}
+		return nil
+	}
+
+	return writer, cleanup, nil
+}
+
 func NewConsumer(cfg *config.Config, logger *zap.Logger) (*kafka.Reader, func() error, error) {
+	dialer, err := NewDialer(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	reader := kafka.NewReader(kafka.ReaderConfig{
 		Brokers: cfg.Kafka.Brokers,
 		GroupID: cfg.Kafka.GroupID,
 		Topic:   cfg.Kafka.Topic,
+		Dialer:  dialer,
 	})
 
 	cleanup := func() error {
@@ -46,3 +88,235 @@ func NewConsumer(cfg *config.Config, logger *zap.Logger) (*kafka.Reader, func()
 
 	return reader, cleanup, nil
 }
+
+// NewStreamConsumer читает основной топик отдельной группой для каждого хоста, чтобы каждый экземпляр сервиса
+// получал все события для своих подписчиков. Новая группа начинает с конца топика
+func NewStreamConsumer(cfg *config.Config, logger *zap.Logger) (*kafka.Reader, func() error, error) {
+	dialer, err := NewDialer(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, nil, fmt.Errorf("resolve hostname for stream consumer group: %w", err)
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     cfg.Kafka.Brokers,
+		GroupID:     cfg.Kafka.GroupID + streamGroupSuffix + hostname,
+		Topic:       cfg.Kafka.Topic,
+		Dialer:      dialer,
+		StartOffset: kafka.LastOffset,
+	})
+
+	cleanup := func() error {
+		logger.Info("Cleanup from Kafka stream consumer")
+		return reader.Close()
+	}
+
+	return reader, cleanup, nil
+}
+
+// NewDialer возвращает dialer с настройками SASL и TLS для ридеров и прямых подключений к брокеру
+func NewDialer(cfg *config.Config) (*kafka.Dialer, error) {
+	mechanism, err := newSASLMechanism(cfg.Kafka.SASL)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := newTLSConfig(cfg.Kafka.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kafka.Dialer{
+		Timeout:       dialTimeout,
+		DualStack:     true,
+		SASLMechanism: mechanism,
+		TLS:           tlsConfig,
+	}, nil
+}
+
+// EnsureTopics создает основной и dead-letter топики, если их еще нет на брокере
+func EnsureTopics(cfg *config.Config, logger *zap.Logger) error {
+	transport, err := newTransport(cfg)
+	if err != nil {
+		return err
+	}
+
+	partitions := cfg.Kafka.Partitions
+	if partitions <= 0 {
+		partitions = defaultPartitions
+	}
+
+	replicationFactor := cfg.Kafka.ReplicationFactor
+	if replicationFactor <= 0 {
+		replicationFactor = defaultReplicationFactor
+	}
+
+	var topics []kafka.TopicConfig
+	for _, topic := range []string{cfg.Kafka.Topic, cfg.Kafka.DLQTopic} {
+		if topic == "" {
+			continue
+		}
+		topics = append(topics, kafka.TopicConfig{
+			Topic:             topic,
+			NumPartitions:     partitions,
+			ReplicationFactor: replicationFactor,
+		})
+	}
+
+	client := &kafka.Client{
+		Addr:      kafka.TCP(cfg.Kafka.Brokers...),
+		Timeout:   dialTimeout,
+		Transport: transport,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+
+	resp, err := client.CreateTopics(ctx, &kafka.CreateTopicsRequest{Topics: topics})
+	if err != nil {
+		return fmt.Errorf("create kafka topics: %w", err)
+	}
+
+	for topic, errTopic := range resp.Errors {
+		switch {
+		case errTopic == nil:
+			logger.Info("Kafka topic created", zap.String("topic", topic), zap.Int("partitions", partitions))
+		case errors.Is(errTopic, kafka.TopicAlreadyExists):
+			logger.Info("Kafka topic already exists", zap.String("topic", topic))
+		default:
+			return fmt.Errorf("create kafka topic %s: %w", topic, errTopic)
+		}
+	}
+
+	return nil
+}
+
+// NewWriter создает продюсер в указанный топик с настройками батчинга, подтверждений и сжатия из конфига
+func NewWriter(cfg *config.Config, topic string) (*kafka.Writer, error) {
+	transport, err := newTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	requiredAcks, err := parseRequiredAcks(cfg.Kafka.RequiredAcks)
+	if err != nil {
+		return nil, err
+	}
+
+	compression, err := parseCompression(cfg.Kafka.Compression)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kafka.Writer{
+		Addr:         kafka.TCP(cfg.Kafka.Brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		BatchSize:    cfg.Kafka.BatchSize,
+		BatchTimeout: time.Duration(cfg.Kafka.Linger) * time.Millisecond,
+		RequiredAcks: requiredAcks,
+		Compression:  compression,
+		Transport:    transport,
+	}, nil
+}
+
+func newTransport(cfg *config.Config) (*kafka.Transport, error) {
+	mechanism, err := newSASLMechanism(cfg.Kafka.SASL)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := newTLSConfig(cfg.Kafka.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	return &kafka.Transport{
+		DialTimeout: dialTimeout,
+		SASL:        mechanism,
+		TLS:         tlsConfig,
+	}, nil
+}
+
+func newSASLMechanism(cfg config.KafkaSASL) (sasl.Mechanism, error) {
+	switch cfg.Mechanism {
+	case "":
+		return nil, nil
+	case "plain":
+		return plain.Mechanism{Username: cfg.Username, Password: cfg.Password}, nil
+	case "scram-sha-256":
+		return scram.Mechanism(scram.SHA256, cfg.Username, cfg.Password)
+	case "scram-sha-512":
+		return scram.Mechanism(scram.SHA512, cfg.Username, cfg.Password)
+	default:
+		return nil, fmt.Errorf("unsupported kafka sasl mechanism: %s", cfg.Mechanism)
+	}
+}
+
+func newTLSConfig(cfg config.KafkaTLS) (*tls.Config, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CAFile != "" {
+		ca, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read kafka ca file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("kafka ca file contains no certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load kafka client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+func parseRequiredAcks(acks string) (kafka.RequiredAcks, error) {
+	switch acks {
+	case "", "all":
+		return kafka.RequireAll, nil
+	case "one":
+		return kafka.RequireOne, nil
+	case "none":
+		return kafka.RequireNone, nil
+	default:
+		return 0, fmt.Errorf("unsupported kafka required acks: %s", acks)
+	}
+}
+
+func parseCompression(compression string) (kafka.Compression, error) {
+	switch compression {
+	case "":
+		return 0, nil
+	case "gzip":
+		return kafka.Gzip, nil
+	case "snappy":
+		return kafka.Snappy, nil
+	case "lz4":
+		return kafka.Lz4, nil
+	case "zstd":
+		return kafka.Zstd, nil
+	default:
+		return 0, fmt.Errorf("unsupported kafka compression: %s", compression)
+	}
+}
@@ -0,0 +1,28 @@
+package nats
+
+import (
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+	"transaction-system/config"
+)
+
+func NewConn(cfg *config.Config, logger *zap.Logger) (*nats.Conn, func() error, error) {
+	conn, err := nats.Connect(cfg.NATS.URL)
+	if err != nil {
+		logger.Error("failed to connect to NATS", zap.Error(err))
+		return nil, nil, err
+	}
+
+	logger.Info("NATS connection successful")
+
+	cleanup := func() error {
+		logger.Info("Cleanup from NATS")
+		err := conn.Drain()
+		if err != nil {
+			return err
+		}
+		return nil
+	}
+
+	return conn, cleanup, nil
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/go-pg/pg/v10"
 	"go.uber.org/zap"
 	"transaction-system/config"
+	"transaction-system/pkg/postgres"
 )
 
 func NewDB(cfg *config.Config, logger *zap.Logger) (db *pg.DB, cleanup func() error, err error) {
@@ -15,6 +16,8 @@ func NewDB(cfg *config.Config, logger *zap.Logger) (db *pg.DB, cleanup func() er
 	}
 
 	db = pg.Connect(opts)
+	// Запросы с контекстом трассировки записываются спанами, без настроенного TRACING хук ничего не пишет
+	db.AddQueryHook(postgres.TracingHook{})
 
 	_, err = db.Exec("SELECT 1")
 	if err != nil {
@@ -0,0 +1,33 @@
+package migration
+
+import (
+	"github.com/go-pg/migrations/v8"
+)
+
+func init() {
+	migrations.MustRegister(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			ALTER TABLE clients
+			ADD COLUMN IF NOT EXISTS event_seq bigint NOT NULL DEFAULT 0;
+
+			ALTER TABLE transactions
+			ADD COLUMN IF NOT EXISTS sequence bigint;
+
+			CREATE UNIQUE INDEX IF NOT EXISTS transactions_client_id_sequence_idx
+			ON transactions (client_id, sequence)
+			WHERE sequence IS NOT NULL;
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			DROP INDEX IF EXISTS transactions_client_id_sequence_idx;
+
+			ALTER TABLE transactions
+			DROP COLUMN IF EXISTS sequence;
+
+			ALTER TABLE clients
+			DROP COLUMN IF EXISTS event_seq;
+		`)
+		return err
+	})
+}
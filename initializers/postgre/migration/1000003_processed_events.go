@@ -0,0 +1,32 @@
+package migration
+
+import (
+	"github.com/go-pg/migrations/v8"
+)
+
+func init() {
+	migrations.MustRegister(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			CREATE TABLE IF NOT EXISTS processed_events (
+				event_id text PRIMARY KEY,
+				processed_at timestamptz NOT NULL DEFAULT now()
+			);
+
+			CREATE INDEX IF NOT EXISTS processed_events_processed_at_idx
+			ON processed_events (processed_at);
+
+			CREATE TABLE IF NOT EXISTS client_event_offsets (
+				client_id bigint PRIMARY KEY REFERENCES clients(id) ON DELETE CASCADE,
+				last_sequence bigint NOT NULL,
+				updated_at timestamptz NOT NULL DEFAULT now()
+			);
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			DROP TABLE IF EXISTS client_event_offsets;
+			DROP TABLE IF EXISTS processed_events;
+		`)
+		return err
+	})
+}
@@ -0,0 +1,37 @@
+package migration
+
+import (
+	"github.com/go-pg/migrations/v8"
+)
+
+func init() {
+	migrations.MustRegister(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			CREATE TABLE IF NOT EXISTS api_keys (
+				id bigserial PRIMARY KEY,
+				key_id text NOT NULL UNIQUE,
+				secret_hash text NOT NULL,
+				name text NOT NULL,
+				created_at timestamptz NOT NULL DEFAULT now(),
+				revoked_at timestamptz
+			);
+
+			ALTER TABLE transactions
+			ADD COLUMN IF NOT EXISTS api_key_id bigint;
+
+			ALTER TABLE transactions
+			ADD CONSTRAINT fk_transactions_api_keys
+			FOREIGN KEY (api_key_id)
+			REFERENCES api_keys(id);
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			ALTER TABLE transactions
+			DROP COLUMN IF EXISTS api_key_id;
+
+			DROP TABLE IF EXISTS api_keys;
+		`)
+		return err
+	})
+}
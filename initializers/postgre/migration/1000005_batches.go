@@ -0,0 +1,51 @@
+package migration
+
+import (
+	"github.com/go-pg/migrations/v8"
+)
+
+func init() {
+	migrations.MustRegister(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			CREATE TABLE IF NOT EXISTS batches (
+				id bigserial PRIMARY KEY,
+				idempotency_key text NOT NULL,
+				request_hash text NOT NULL,
+				api_key_id bigint REFERENCES api_keys(id),
+				status text NOT NULL,
+				total integer NOT NULL,
+				succeeded integer NOT NULL DEFAULT 0,
+				failed integer NOT NULL DEFAULT 0,
+				created_at timestamptz NOT NULL DEFAULT now(),
+				updated_at timestamptz
+			);
+
+			CREATE UNIQUE INDEX IF NOT EXISTS batches_idempotency_key_idx
+			ON batches (COALESCE(api_key_id, 0), idempotency_key);
+
+			CREATE TABLE IF NOT EXISTS batch_items (
+				id bigserial PRIMARY KEY,
+				batch_id bigint NOT NULL REFERENCES batches(id) ON DELETE CASCADE,
+				position integer NOT NULL,
+				operation text NOT NULL,
+				currency_code integer NOT NULL,
+				amount double precision NOT NULL,
+				wallet_number bigint,
+				card_number bigint,
+				status text NOT NULL,
+				transaction_id bigint REFERENCES transactions(id),
+				error_code text,
+				error text,
+				updated_at timestamptz,
+				UNIQUE (batch_id, position)
+			);
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			DROP TABLE IF EXISTS batch_items;
+			DROP TABLE IF EXISTS batches;
+		`)
+		return err
+	})
+}
@@ -0,0 +1,53 @@
+package migration
+
+import (
+	"github.com/go-pg/migrations/v8"
+)
+
+func init() {
+	migrations.MustRegister(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			CREATE TABLE IF NOT EXISTS operations (
+				id bigserial PRIMARY KEY,
+				operation text NOT NULL,
+				currency_code integer NOT NULL,
+				amount double precision NOT NULL,
+				wallet_number bigint,
+				card_number bigint,
+				api_key_id bigint REFERENCES api_keys(id),
+				status text NOT NULL,
+				transaction_id bigint REFERENCES transactions(id),
+				error_code text,
+				error text,
+				attempts integer NOT NULL DEFAULT 0,
+				next_attempt_at timestamptz,
+				created_at timestamptz NOT NULL DEFAULT now(),
+				updated_at timestamptz
+			);
+
+			CREATE INDEX IF NOT EXISTS operations_unfinished_idx
+			ON operations (id)
+			WHERE status IN ('Pending', 'Processing');
+
+			ALTER TABLE transactions
+			ADD COLUMN IF NOT EXISTS operation_id bigint;
+
+			ALTER TABLE transactions
+			ADD CONSTRAINT transactions_operation_id_key UNIQUE (operation_id);
+
+			ALTER TABLE transactions
+			ADD CONSTRAINT fk_transactions_operations
+			FOREIGN KEY (operation_id)
+			REFERENCES operations(id);
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			ALTER TABLE transactions
+			DROP COLUMN IF EXISTS operation_id;
+
+			DROP TABLE IF EXISTS operations;
+		`)
+		return err
+	})
+}
@@ -0,0 +1,33 @@
+package migration
+
+import (
+	"github.com/go-pg/migrations/v8"
+)
+
+func init() {
+	migrations.MustRegister(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			CREATE TABLE IF NOT EXISTS client_events (
+				client_id bigint NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+				sequence bigint NOT NULL,
+				event_id text,
+				transaction_id bigint NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+				status text NOT NULL,
+				created_at timestamptz NOT NULL DEFAULT now(),
+				PRIMARY KEY (client_id, sequence)
+			);
+
+			INSERT INTO client_events (client_id, sequence, transaction_id, status, created_at)
+			SELECT client_id, sequence, id, 'Created', created_at
+			FROM transactions
+			WHERE sequence > 0
+			ON CONFLICT DO NOTHING;
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			DROP TABLE IF EXISTS client_events;
+		`)
+		return err
+	})
+}
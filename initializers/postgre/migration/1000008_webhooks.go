@@ -0,0 +1,66 @@
+package migration
+
+import (
+	"github.com/go-pg/migrations/v8"
+)
+
+func init() {
+	migrations.MustRegister(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			CREATE TABLE IF NOT EXISTS webhooks (
+				id bigserial PRIMARY KEY,
+				client_id bigint REFERENCES clients(id) ON DELETE CASCADE,
+				api_key_id bigint REFERENCES api_keys(id) ON DELETE CASCADE,
+				url text NOT NULL,
+				secret text NOT NULL,
+				events text[] NOT NULL DEFAULT '{}',
+				created_at timestamptz NOT NULL DEFAULT now(),
+				CHECK (client_id IS NOT NULL OR api_key_id IS NOT NULL)
+			);
+
+			CREATE INDEX IF NOT EXISTS webhooks_client_id_idx ON webhooks (client_id);
+			CREATE INDEX IF NOT EXISTS webhooks_api_key_id_idx ON webhooks (api_key_id);
+
+			CREATE TABLE IF NOT EXISTS webhook_deliveries (
+				id bigserial PRIMARY KEY,
+				webhook_id bigint NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+				event_id text NOT NULL,
+				event_type text NOT NULL,
+				payload text NOT NULL,
+				status text NOT NULL,
+				attempts integer NOT NULL DEFAULT 0,
+				next_attempt_at timestamptz,
+				last_status_code integer,
+				last_error text,
+				created_at timestamptz NOT NULL DEFAULT now(),
+				updated_at timestamptz,
+				delivered_at timestamptz,
+				UNIQUE (webhook_id, event_id)
+			);
+
+			CREATE INDEX IF NOT EXISTS webhook_deliveries_unfinished_idx
+			ON webhook_deliveries (id)
+			WHERE status IN ('Pending', 'Sending');
+
+			CREATE TABLE IF NOT EXISTS webhook_attempts (
+				id bigserial PRIMARY KEY,
+				delivery_id bigint NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+				attempt integer NOT NULL,
+				status_code integer,
+				error text,
+				duration bigint NOT NULL DEFAULT 0,
+				created_at timestamptz NOT NULL DEFAULT now()
+			);
+
+			CREATE INDEX IF NOT EXISTS webhook_attempts_delivery_id_idx ON webhook_attempts (delivery_id);
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			DROP TABLE IF EXISTS webhook_attempts;
+			DROP TABLE IF EXISTS webhook_deliveries;
+			DROP TABLE IF EXISTS webhooks;
+		`)
+		return err
+	})
+}
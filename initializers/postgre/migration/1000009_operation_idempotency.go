@@ -0,0 +1,29 @@
+package migration
+
+import (
+	"github.com/go-pg/migrations/v8"
+)
+
+func init() {
+	migrations.MustRegister(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			ALTER TABLE operations
+			ADD COLUMN IF NOT EXISTS idempotency_key text,
+			ADD COLUMN IF NOT EXISTS request_hash text;
+
+			CREATE UNIQUE INDEX IF NOT EXISTS operations_idempotency_key_idx
+			ON operations (COALESCE(api_key_id, 0), idempotency_key)
+			WHERE idempotency_key IS NOT NULL;
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			DROP INDEX IF EXISTS operations_idempotency_key_idx;
+
+			ALTER TABLE operations
+			DROP COLUMN IF EXISTS request_hash,
+			DROP COLUMN IF EXISTS idempotency_key;
+		`)
+		return err
+	})
+}
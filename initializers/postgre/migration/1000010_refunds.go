@@ -0,0 +1,29 @@
+package migration
+
+import (
+	"github.com/go-pg/migrations/v8"
+)
+
+func init() {
+	migrations.MustRegister(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			ALTER TABLE transactions
+			ADD COLUMN IF NOT EXISTS refund_of bigint;
+
+			ALTER TABLE transactions
+			ADD CONSTRAINT transactions_refund_of_key UNIQUE (refund_of);
+
+			ALTER TABLE transactions
+			ADD CONSTRAINT fk_transactions_refund_of
+			FOREIGN KEY (refund_of)
+			REFERENCES transactions(id);
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			ALTER TABLE transactions
+			DROP COLUMN IF EXISTS refund_of;
+		`)
+		return err
+	})
+}
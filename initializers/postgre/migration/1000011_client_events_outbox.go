@@ -0,0 +1,31 @@
+package migration
+
+import (
+	"github.com/go-pg/migrations/v8"
+)
+
+func init() {
+	migrations.MustRegister(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			ALTER TABLE client_events
+			ADD COLUMN IF NOT EXISTS published_at timestamptz;
+
+			UPDATE client_events
+			SET published_at = created_at
+			WHERE published_at IS NULL;
+
+			CREATE INDEX IF NOT EXISTS client_events_unpublished_idx
+			ON client_events (created_at)
+			WHERE published_at IS NULL;
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			DROP INDEX IF EXISTS client_events_unpublished_idx;
+
+			ALTER TABLE client_events
+			DROP COLUMN IF EXISTS published_at;
+		`)
+		return err
+	})
+}
@@ -0,0 +1,35 @@
+package migration
+
+import (
+	"github.com/go-pg/migrations/v8"
+)
+
+func init() {
+	migrations.MustRegister(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			ALTER TABLE api_keys
+			ADD COLUMN IF NOT EXISTS secret_encrypted text;
+
+			ALTER TABLE api_keys
+			ALTER COLUMN secret_hash DROP NOT NULL;
+
+			CREATE TABLE IF NOT EXISTS api_key_signatures (
+				signature text PRIMARY KEY,
+				api_key_id bigint NOT NULL REFERENCES api_keys(id),
+				expires_at timestamptz NOT NULL
+			);
+
+			CREATE INDEX IF NOT EXISTS api_key_signatures_expires_at_idx
+			ON api_key_signatures (expires_at);
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			DROP TABLE IF EXISTS api_key_signatures;
+
+			ALTER TABLE api_keys
+			DROP COLUMN IF EXISTS secret_encrypted;
+		`)
+		return err
+	})
+}
@@ -0,0 +1,35 @@
+package migration
+
+import (
+	"github.com/go-pg/migrations/v8"
+)
+
+func init() {
+	migrations.MustRegister(func(db migrations.DB) error {
+		_, err := db.Exec(`
+			CREATE INDEX IF NOT EXISTS batches_unfinished_idx
+			ON batches (id)
+			WHERE status IN ('Pending', 'Processing');
+
+			ALTER TABLE transactions
+			ADD COLUMN IF NOT EXISTS batch_item_id bigint;
+
+			ALTER TABLE transactions
+			ADD CONSTRAINT transactions_batch_item_id_key UNIQUE (batch_item_id);
+
+			ALTER TABLE transactions
+			ADD CONSTRAINT fk_transactions_batch_items
+			FOREIGN KEY (batch_item_id)
+			REFERENCES batch_items(id);
+		`)
+		return err
+	}, func(db migrations.DB) error {
+		_, err := db.Exec(`
+			ALTER TABLE transactions
+			DROP COLUMN IF EXISTS batch_item_id;
+
+			DROP INDEX IF EXISTS batches_unfinished_idx;
+		`)
+		return err
+	})
+}
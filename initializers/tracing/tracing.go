@@ -0,0 +1,116 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.uber.org/zap"
+	"os"
+	"transaction-system/config"
+)
+
+// Exporters
+const (
+	ExporterOTLP = "otlp"
+	ExporterFile = "file"
+	ExporterNone = "none"
+)
+
+const (
+	defaultServiceName = "transaction-system"
+	defaultTraceFile   = "traces.json"
+)
+
+var ErrUnsupportedExporter = errors.New("unsupported tracing exporter")
+
+// NewTracerProvider настраивает глобальные TracerProvider и пропагатор W3C Trace Context из TRACING.
+// С экспортером none спаны не записываются, но контекст трассировки из входящих запросов передается дальше в Kafka.
+// cleanup дописывает накопленные спаны и закрывает экспортер
+func NewTracerProvider(cfg *config.Config, logger *zap.Logger) (func(ctx context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Warn("Tracing error", zap.Error(err))
+	}))
+
+	var (
+		exporter  sdktrace.SpanExporter
+		closeFile = func() error { return nil }
+		err       error
+	)
+
+	switch cfg.Tracing.Exporter {
+	case ExporterNone, "":
+		logger.Info("Tracing disabled")
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		var opts []otlptracegrpc.Option
+		if cfg.Tracing.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Tracing.Endpoint))
+		}
+		if cfg.Tracing.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+
+		// Соединение с коллектором устанавливается в фоне, недоступный коллектор не мешает старту
+		exporter, err = otlptracegrpc.New(context.Background(), opts...)
+		if err != nil {
+			return nil, err
+		}
+	case ExporterFile:
+		path := cfg.Tracing.File
+		if path == "" {
+			path = defaultTraceFile
+		}
+
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		closeFile = file.Close
+
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedExporter, cfg.Tracing.Exporter)
+	}
+
+	serviceName := cfg.Tracing.ServiceName
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+
+	ratio := cfg.Tracing.SampleRatio
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+
+	// Решение о записи принимает начало трассы: запросы с уже трассируемым родителем записываются всегда
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	logger.Info("Tracing enabled", zap.String("exporter", cfg.Tracing.Exporter), zap.Float64("sample_ratio", ratio))
+
+	cleanup := func(ctx context.Context) error {
+		logger.Info("cleanup from tracing")
+		errProvider := provider.Shutdown(ctx)
+		errFile := closeFile()
+
+		return errors.Join(errProvider, errFile)
+	}
+
+	return cleanup, nil
+}
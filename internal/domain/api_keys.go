@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+// APIKeys - ключ мерчанта. Сам секрет не хранится, ключ подписи hex(SHA-256(secret)) хранится зашифрованным ключом приложения
+type APIKeys struct {
+	tableName struct{} `pg:"api_keys"`
+
+	ID              int
+	KeyID           string
+	SecretEncrypted string
+	Name            string
+	CreatedAt       time.Time
+	RevokedAt       *time.Time
+	// SigningKey - расшифрованный ключ подписи, заполняется при поиске ключа и в БД не сохраняется
+	SigningKey string `pg:"-"`
+}
+
+// APIKeySignatures - подписи принятых запросов. Хранятся, пока время запроса не выйдет за окно, и не дают повторить запрос
+// ни на этом, ни на другом экземпляре сервиса
+type APIKeySignatures struct {
+	tableName struct{} `pg:"api_key_signatures"`
+
+	Signature string `pg:",pk"`
+	APIKeyID  int
+	ExpiresAt time.Time
+}
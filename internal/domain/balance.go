@@ -0,0 +1,14 @@
+package domain
+
+// Balance - актуальный (Success) и замороженный (Created) баланс клиента по каждой валюте.
+// Общей суммы нет: суммы в разных валютах не складываются
+type Balance struct {
+	Currencies []CurrencyBalance
+}
+
+type CurrencyBalance struct {
+	CurrencyCode int
+	CurrencyName string
+	Available    float64
+	Frozen       float64
+}
@@ -0,0 +1,57 @@
+package domain
+
+import "time"
+
+// Статусы пакета операций
+const (
+	BatchPending    = "Pending"
+	BatchProcessing = "Processing"
+	BatchCompleted  = "Completed"
+	BatchPartial    = "PartiallyCompleted"
+	BatchFailed     = "Failed"
+)
+
+// Статусы элемента пакета
+const (
+	BatchItemPending = "Pending"
+	BatchItemSuccess = "Success"
+	BatchItemError   = "Error"
+)
+
+// Операции, которые можно передать в пакете
+const (
+	OperationInvoice  = "invoice"
+	OperationWithdraw = "withdraw"
+)
+
+// Batches - пакет операций, отправленный одним запросом. IdempotencyKey уникален в пределах API-ключа,
+// RequestHash позволяет отличить повтор того же пакета от другого пакета с тем же ключом
+type Batches struct {
+	ID             int
+	IdempotencyKey string
+	RequestHash    string
+	APIKeyID       int
+	Status         string
+	Total          int           `pg:",use_zero"`
+	Succeeded      int           `pg:",use_zero"`
+	Failed         int           `pg:",use_zero"`
+	Items          []*BatchItems `pg:"rel:has-many,join_fk:batch_id"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+type BatchItems struct {
+	ID            int
+	BatchID       int
+	Position      int `pg:",use_zero"`
+	Operation     string
+	CurrencyCode  int     `pg:",use_zero"`
+	Amount        float64 `pg:",use_zero"`
+	WalletNumber  int
+	CardNumber    int
+	Status        string
+	TransactionID int
+	ErrorCode     string
+	Error         string
+	UpdatedAt     time.Time
+}
@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+// ClientEvents - журнал событий клиента: создание транзакции и смена ее статуса.
+// Sequence совпадает с TransactionEvent.Sequence и позволяет дочитать пропущенные события.
+// Журнал служит исходящей очередью шины: PublishedAt пуст, пока событие не отправлено
+type ClientEvents struct {
+	ClientID      int   `pg:",pk"`
+	Sequence      int64 `pg:",pk"`
+	EventID       string
+	TransactionID int
+	Status        string
+	CreatedAt     time.Time
+	PublishedAt   *time.Time
+}
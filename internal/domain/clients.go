@@ -4,4 +4,5 @@ type Clients struct {
 	ID           int
 	WalletNumber int
 	CardNumber   int
+	EventSeq     int64 `pg:",notnull,use_zero"`
 }
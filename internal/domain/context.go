@@ -0,0 +1,32 @@
+package domain
+
+import "context"
+
+type contextKey int
+
+const (
+	apiKeyIDKey contextKey = iota
+	adminKey
+)
+
+// ContextWithAPIKeyID сохраняет в контексте ключ, которым подписан запрос
+func ContextWithAPIKeyID(ctx context.Context, apiKeyID int) context.Context {
+	return context.WithValue(ctx, apiKeyIDKey, apiKeyID)
+}
+
+// APIKeyIDFromContext возвращает 0, если запрос не подписан ключом
+func APIKeyIDFromContext(ctx context.Context) int {
+	apiKeyID, _ := ctx.Value(apiKeyIDKey).(int)
+	return apiKeyID
+}
+
+// ContextWithAdmin отмечает запрос, выполненный администратором
+func ContextWithAdmin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, adminKey, true)
+}
+
+// IsAdminFromContext сообщает, что запрос выполнен администратором
+func IsAdminFromContext(ctx context.Context) bool {
+	admin, _ := ctx.Value(adminKey).(bool)
+	return admin
+}
@@ -0,0 +1,11 @@
+package domain
+
+// TransactionEvent - событие о транзакции в шине. Sequence растет на единицу для каждого события клиента,
+// что позволяет консьюмеру заметить пропуски и повторы. EventID уникален для каждого события и используется
+// консьюмером для дедупликации повторных доставок.
+type TransactionEvent struct {
+	EventID     string
+	ClientID    int
+	Sequence    int64
+	Transaction *Transactions
+}
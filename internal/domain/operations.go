@@ -0,0 +1,34 @@
+package domain
+
+import "time"
+
+// Статусы асинхронной операции
+const (
+	OperationPending    = "Pending"
+	OperationProcessing = "Processing"
+	OperationCompleted  = "Completed"
+	OperationFailed     = "Failed"
+)
+
+// Operations - операция, принятая в асинхронном режиме и проводимая в фоне, или синхронная операция
+// с ключом идемпотентности. Транзакция, созданная по операции, хранит ее ID, поэтому повторное проведение
+// не создаст вторую транзакцию. IdempotencyKey уникален в пределах API-ключа
+type Operations struct {
+	ID             int
+	IdempotencyKey string
+	RequestHash    string
+	Operation      string
+	CurrencyCode   int     `pg:",use_zero"`
+	Amount         float64 `pg:",use_zero"`
+	WalletNumber   int
+	CardNumber     int
+	APIKeyID       int
+	Status         string
+	TransactionID  int
+	ErrorCode      string
+	Error          string
+	Attempts       int `pg:",use_zero"`
+	NextAttemptAt  *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
@@ -0,0 +1,17 @@
+package domain
+
+// Проверки сверки
+const (
+	CheckNegativeBalance      = "negative_balance"
+	CheckEventSequence        = "event_sequence"
+	CheckOperationTransaction = "operation_transaction"
+	CheckRefundMismatch       = "refund_mismatch"
+)
+
+// Discrepancy - нарушение инварианта, найденное сверкой. ID - транзакция или операция, к которой оно относится
+type Discrepancy struct {
+	Check    string
+	ClientID int
+	ID       int
+	Detail   string
+}
@@ -10,6 +10,24 @@ type Transactions struct {
 	Currency   *Currencies
 	Amount     float64
 	Status     string
-	CreatedAt  time.Time
-	UpdatedAt  time.Time
+	Sequence   int64
+	APIKeyID   int
+	// OperationID заполнен для транзакций, проведенных из асинхронной операции
+	OperationID int
+	// BatchItemID заполнен для транзакций, проведенных из элемента пакета, в ответах API не отдается
+	BatchItemID int `json:"-"`
+	// RefundOf заполнен для возврата и указывает на возвращенную транзакцию
+	RefundOf  int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TransactionFilter - выборка транзакций клиента, найденного по кошельку или карте, новые первыми.
+// BeforeID - курсор: транзакции с меньшим ID
+type TransactionFilter struct {
+	WalletNumber int
+	CardNumber   int
+	Status       string
+	BeforeID     int
+	Limit        int
 }
@@ -0,0 +1,93 @@
+package domain
+
+import "time"
+
+// Типы событий вебхуков
+const (
+	WebhookTransactionCreated = "transaction.created"
+	WebhookTransactionSettled = "transaction.settled"
+	WebhookTransactionFailed  = "transaction.failed"
+	WebhookOperationFailed    = "operation.failed"
+)
+
+// Статусы доставки вебхука
+const (
+	WebhookDeliveryPending   = "Pending"
+	WebhookDeliverySending   = "Sending"
+	WebhookDeliveryDelivered = "Delivered"
+	WebhookDeliveryFailed    = "Failed"
+)
+
+// Webhooks - подписка на события клиента (ClientID) или мерчанта (APIKeyID).
+// Секрет хранится открытым: им подписывается каждая доставка. Пустой Events - все события
+type Webhooks struct {
+	ID        int
+	ClientID  int
+	APIKeyID  int
+	URL       string
+	Secret    string
+	Events    []string `pg:",array"`
+	CreatedAt time.Time
+}
+
+// WebhookDeliveries - доставка одного события по одной подписке, Payload - тело запроса
+type WebhookDeliveries struct {
+	ID             int
+	WebhookID      int
+	Webhook        *Webhooks `pg:"rel:has-one"`
+	EventID        string
+	EventType      string
+	Payload        string
+	Status         string
+	Attempts       int `pg:",use_zero"`
+	NextAttemptAt  *time.Time
+	LastStatusCode int
+	LastError      string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeliveredAt    *time.Time
+	Log            []*WebhookAttempts `pg:"rel:has-many,join_fk:delivery_id"`
+}
+
+// WebhookAttempts - журнал попыток доставки
+type WebhookAttempts struct {
+	ID         int
+	DeliveryID int
+	Attempt    int
+	StatusCode int
+	Error      string
+	Duration   int64 // мс
+	CreatedAt  time.Time
+}
+
+// WebhookEvent - тело запроса вебхука
+type WebhookEvent struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// WebhookTransaction - данные событий transaction.*
+type WebhookTransaction struct {
+	TransactionID int       `json:"transaction_id"`
+	ClientID      int       `json:"client_id"`
+	Sequence      int64     `json:"sequence"`
+	Status        string    `json:"status"`
+	Amount        float64   `json:"amount"`
+	CurrencyID    int       `json:"currency_id"`
+	OperationID   int       `json:"operation_id,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// WebhookOperation - данные события operation.failed
+type WebhookOperation struct {
+	OperationID  int     `json:"operation_id"`
+	Operation    string  `json:"operation"`
+	CurrencyCode int     `json:"currency_code"`
+	Amount       float64 `json:"amount"`
+	WalletNumber int     `json:"wallet_number,omitempty"`
+	CardNumber   int     `json:"card_number,omitempty"`
+	ErrorCode    string  `json:"error_code"`
+	Error        string  `json:"error"`
+}
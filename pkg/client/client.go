@@ -0,0 +1,558 @@
+// Package client - Go-клиент API transaction-system: зачисление и списание, баланс, асинхронные операции и пакеты.
+// Запросы на запись отправляются с Idempotency-Key и повторяются с тем же ключом при сетевых ошибках, 429 и 5xx,
+// поэтому повтор не проведет операцию второй раз
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	APIKeyHeader             = "X-API-Key"
+	TimestampHeader          = "X-Timestamp"
+	SignatureHeader          = "X-Signature"
+	AdminTokenHeader         = "X-Admin-Token"
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	PreferHeader             = "Prefer"
+)
+
+const (
+	defaultTimeout        = 30 * time.Second
+	defaultMaxAttempts    = 3
+	defaultInitialBackoff = 200 * time.Millisecond
+	defaultMaxBackoff     = 5 * time.Second
+	// Сервис отклоняет повторную подпись API-ключа, а время в подписи - в секундах,
+	// поэтому подписанный повтор не может уйти раньше, чем через секунду
+	signedRetryMinBackoff = time.Second
+	userAgent             = "transaction-system-go-client"
+	adminPathPrefix       = "/v1/admin/"
+)
+
+// AcceptedError возвращают Invoice и Withdraw, если сервис принял операцию в очередь (ASYNC.DEFAULT: true)
+// вместо проведения. Результат можно дождаться через WaitOperation
+type AcceptedError struct {
+	Operation *Operation
+}
+
+func (e *AcceptedError) Error() string {
+	return fmt.Sprintf("operation %d accepted for asynchronous processing", e.Operation.ID)
+}
+
+type Client struct {
+	baseURL        string
+	httpClient     *http.Client
+	bearerToken    string
+	apiKeyID       string
+	signingKey     string
+	adminToken     string
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	userAgent      string
+}
+
+type Option func(*Client)
+
+// WithHTTPClient заменяет http.Client по умолчанию с таймаутом 30 секунд
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithBearerToken аутентифицирует запросы JWT
+func WithBearerToken(token string) Option {
+	return func(c *Client) {
+		c.bearerToken = token
+	}
+}
+
+// WithAPIKey подписывает запросы API-ключом мерчанта: keyID и secret выдаются при создании ключа
+func WithAPIKey(keyID string, secret string) Option {
+	return func(c *Client) {
+		c.apiKeyID = keyID
+		sum := sha256.Sum256([]byte(secret))
+		c.signingKey = hex.EncodeToString(sum[:])
+	}
+}
+
+// WithAdminToken передает статический токен администратора в административные ручки (/v1/admin/...)
+func WithAdminToken(token string) Option {
+	return func(c *Client) {
+		c.adminToken = token
+	}
+}
+
+// WithRetry задает число попыток запроса (1 - без повторов) и границы экспоненциальной задержки между ними
+func WithRetry(maxAttempts int, initialBackoff time.Duration, maxBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxAttempts = maxAttempts
+		c.initialBackoff = initialBackoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+func WithUserAgent(ua string) Option {
+	return func(c *Client) {
+		c.userAgent = ua
+	}
+}
+
+// New создает клиент сервиса по адресу baseURL, например http://localhost:8080
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse base url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("base url must be an absolute http or https url: %q", baseURL)
+	}
+
+	c := &Client{
+		baseURL:        strings.TrimRight(baseURL, "/"),
+		httpClient:     &http.Client{Timeout: defaultTimeout},
+		maxAttempts:    defaultMaxAttempts,
+		initialBackoff: defaultInitialBackoff,
+		maxBackoff:     defaultMaxBackoff,
+		userAgent:      userAgent,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.maxAttempts <= 0 {
+		c.maxAttempts = 1
+	}
+
+	return c, nil
+}
+
+// Invoice зачисляет средства и возвращает созданную транзакцию
+func (c *Client) Invoice(ctx context.Context, req OperationRequest) (*Transaction, error) {
+	return c.operation(ctx, "/v1/invoice", req)
+}
+
+// Withdraw списывает средства и возвращает созданную транзакцию
+func (c *Client) Withdraw(ctx context.Context, req OperationRequest) (*Transaction, error) {
+	return c.operation(ctx, "/v1/withdraw", req)
+}
+
+// InvoiceAsync принимает зачисление в очередь, не дожидаясь проведения
+func (c *Client) InvoiceAsync(ctx context.Context, req OperationRequest) (*Operation, error) {
+	return c.operationAsync(ctx, "/v1/invoice", req)
+}
+
+// WithdrawAsync принимает списание в очередь, не дожидаясь проведения
+func (c *Client) WithdrawAsync(ctx context.Context, req OperationRequest) (*Operation, error) {
+	return c.operationAsync(ctx, "/v1/withdraw", req)
+}
+
+func (c *Client) GetOperation(ctx context.Context, id int) (*Operation, error) {
+	op := &Operation{}
+	err := c.call(ctx, http.MethodGet, "/v1/operations/"+strconv.Itoa(id), nil, nil, op)
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
+// WaitOperation опрашивает операцию раз в interval, пока она не получит статус Completed или Failed
+func (c *Client) WaitOperation(ctx context.Context, id int, interval time.Duration) (*Operation, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		op, err := c.GetOperation(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if op.Status == OperationCompleted || op.Status == OperationFailed {
+			return op, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Client) WalletBalance(ctx context.Context, walletNumber int) (*Balance, error) {
+	balance := &Balance{}
+	err := c.call(ctx, http.MethodGet, "/v1/wallets/"+strconv.Itoa(walletNumber)+"/balance", nil, nil, balance)
+	if err != nil {
+		return nil, err
+	}
+
+	return balance, nil
+}
+
+func (c *Client) CardBalance(ctx context.Context, cardNumber int) (*Balance, error) {
+	balance := &Balance{}
+	err := c.call(ctx, http.MethodGet, "/v1/cards/"+strconv.Itoa(cardNumber)+"/balance", nil, nil, balance)
+	if err != nil {
+		return nil, err
+	}
+
+	return balance, nil
+}
+
+// SubmitBatch отправляет пакет операций в фоновую обработку. Результат можно дождаться через WaitBatch,
+// ошибки отдельных операций возвращаются в их результатах, а не ошибкой
+func (c *Client) SubmitBatch(ctx context.Context, req BatchRequest) (*Batch, error) {
+	batch := &Batch{}
+	err := c.call(ctx, http.MethodPost, "/v1/batches", req, idempotencyHeader(req.IdempotencyKey), batch)
+	if err != nil {
+		return nil, err
+	}
+
+	return batch, nil
+}
+
+func (c *Client) GetBatch(ctx context.Context, id int) (*Batch, error) {
+	batch := &Batch{}
+	err := c.call(ctx, http.MethodGet, "/v1/batches/"+strconv.Itoa(id), nil, nil, batch)
+	if err != nil {
+		return nil, err
+	}
+
+	return batch, nil
+}
+
+// WaitBatch опрашивает пакет раз в interval, пока не будут обработаны все его операции
+func (c *Client) WaitBatch(ctx context.Context, id int, interval time.Duration) (*Batch, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		batch, err := c.GetBatch(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if batch.Status != BatchPending && batch.Status != BatchProcessing {
+			return batch, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetTransaction возвращает транзакцию вместе с клиентом
+func (c *Client) GetTransaction(ctx context.Context, id int) (*Transaction, error) {
+	transaction := &Transaction{}
+	err := c.call(ctx, http.MethodGet, "/v1/transactions/"+strconv.Itoa(id), nil, nil, transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// ListTransactions возвращает транзакции клиента, новые первыми. Следующая страница - BeforeID с ID последней транзакции
+func (c *Client) ListTransactions(ctx context.Context, filter TransactionFilter) ([]Transaction, error) {
+	query := url.Values{}
+	for name, value := range map[string]int{
+		"wallet_number": filter.WalletNumber,
+		"card_number":   filter.CardNumber,
+		"before_id":     filter.BeforeID,
+		"limit":         filter.Limit,
+	} {
+		if value != 0 {
+			query.Set(name, strconv.Itoa(value))
+		}
+	}
+	if filter.Status != "" {
+		query.Set("status", filter.Status)
+	}
+
+	var transactions []Transaction
+	err := c.call(ctx, http.MethodGet, "/v1/transactions?"+query.Encode(), nil, nil, &transactions)
+	if err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
+// Refund возвращает транзакцию и отдает транзакцию возврата. Запрос не повторяется: повтор после потерянного ответа
+// получил бы ErrAlreadyRefunded
+func (c *Client) Refund(ctx context.Context, id int) (*Transaction, error) {
+	refund := &Transaction{}
+	err := c.call(ctx, http.MethodPost, "/v1/transactions/"+strconv.Itoa(id)+"/refund", nil, nil, refund)
+	if err != nil {
+		return nil, err
+	}
+
+	return refund, nil
+}
+
+// CreateClient заводит клиента. Нужен WithAdminToken или JWT роли admin
+func (c *Client) CreateClient(ctx context.Context, req CreateClientRequest) (*ClientInfo, error) {
+	client := &ClientInfo{}
+	err := c.call(ctx, http.MethodPost, adminPathPrefix+"clients", req, nil, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+func (c *Client) operation(ctx context.Context, path string, req OperationRequest) (*Transaction, error) {
+	resp, err := c.do(ctx, http.MethodPost, path, req, idempotencyHeader(req.IdempotencyKey))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.status == http.StatusAccepted {
+		op := &Operation{}
+		err = decode(resp, op)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &AcceptedError{Operation: op}
+	}
+
+	transaction := &Transaction{}
+	err = decode(resp, transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+func (c *Client) operationAsync(ctx context.Context, path string, req OperationRequest) (*Operation, error) {
+	header := idempotencyHeader(req.IdempotencyKey)
+	header.Set(PreferHeader, "respond-async")
+
+	op := &Operation{}
+	err := c.call(ctx, http.MethodPost, path, req, header, op)
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
+type response struct {
+	status int
+	body   []byte
+}
+
+// call выполняет запрос и разбирает тело ответа в out
+func (c *Client) call(ctx context.Context, method string, path string, body any, header http.Header, out any) error {
+	resp, err := c.do(ctx, method, path, body, header)
+	if err != nil {
+		return err
+	}
+
+	return decode(resp, out)
+}
+
+// do отправляет запрос и повторяет его, пока не кончатся попытки, если ошибка временная, а запрос можно
+// безопасно повторить: GET или запрос с Idempotency-Key
+func (c *Client) do(ctx context.Context, method string, path string, body any, header http.Header) (*response, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("encode request: %w", err)
+		}
+	}
+
+	retryable := method == http.MethodGet || header.Get(IdempotencyKeyHeader) != ""
+
+	for attempt := 1; ; attempt++ {
+		resp, err := c.send(ctx, method, path, payload, header)
+		if err == nil {
+			return resp, nil
+		}
+
+		if !retryable || attempt >= c.maxAttempts || !temporary(err) || ctx.Err() != nil {
+			return nil, err
+		}
+
+		timer := time.NewTimer(c.backoff(attempt, err))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// send выполняет одну попытку запроса. Ответ с кодом 4xx или 5xx возвращается как *APIError
+func (c *Client) send(ctx context.Context, method string, path string, payload []byte, header http.Header) (*response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	switch {
+	case c.adminToken != "" && strings.HasPrefix(path, adminPathPrefix):
+		req.Header.Set(AdminTokenHeader, c.adminToken)
+	case c.bearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+c.bearerToken)
+	case c.apiKeyID != "":
+		// Подпись считается заново для каждой попытки: у повтора другое время
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(APIKeyHeader, c.apiKeyID)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, sign(c.signingKey, method, req.URL.Path, req.URL.RawQuery, timestamp, payload))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, newAPIError(resp, data)
+	}
+
+	return &response{status: resp.StatusCode, body: data}, nil
+}
+
+// backoff - экспоненциальная задержка со случайной добавкой, не меньше Retry-After из ответа
+func (c *Client) backoff(attempt int, err error) time.Duration {
+	delay := c.initialBackoff << (attempt - 1)
+	if delay > c.maxBackoff || delay <= 0 {
+		delay = c.maxBackoff
+	}
+	if delay > 0 {
+		delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > delay {
+		delay = apiErr.RetryAfter
+	}
+
+	if c.apiKeyID != "" && c.bearerToken == "" && delay < signedRetryMinBackoff {
+		delay = signedRetryMinBackoff
+	}
+
+	return delay
+}
+
+// temporary - ошибка сети или ответ, после которого запрос имеет смысл повторить.
+// Отмена или истечение ctx временной ошибкой не считаются
+func temporary(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.temporary()
+	}
+
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+func newAPIError(resp *http.Response, data []byte) *APIError {
+	var body struct {
+		Error   string        `json:"error"`
+		Code    string        `json:"code"`
+		TraceID string        `json:"trace_id"`
+		Details []FieldDetail `json:"details"`
+	}
+	if json.Unmarshal(data, &body) != nil || body.Error == "" {
+		body.Error = http.StatusText(resp.StatusCode)
+	}
+
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Code:       body.Code,
+		Message:    body.Error,
+		TraceID:    body.TraceID,
+		Details:    body.Details,
+	}
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		apiErr.RetryAfter = time.Duration(seconds) * time.Second
+	}
+
+	return apiErr
+}
+
+func decode(resp *response, out any) error {
+	if out == nil || len(resp.body) == 0 {
+		return nil
+	}
+
+	err := json.Unmarshal(resp.body, out)
+	if err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+
+	return nil
+}
+
+func idempotencyHeader(key string) http.Header {
+	if key == "" {
+		key = uuid.NewString()
+	}
+
+	header := http.Header{}
+	header.Set(IdempotencyKeyHeader, key)
+
+	return header
+}
+
+// sign - подпись запроса API-ключом, как SignRequest в transport/http
+func sign(signingKey string, method string, path string, rawQuery string, timestamp string, body []byte) string {
+	// Параметры сортируются по имени, как CanonicalQuery в transport/http
+	query := rawQuery
+	if values, err := url.ParseQuery(rawQuery); err == nil {
+		query = values.Encode()
+	}
+
+	mac := hmac.New(sha256.New, []byte(signingKey))
+	mac.Write([]byte(method + "\n" + path + "\n" + query + "\n" + timestamp + "\n"))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
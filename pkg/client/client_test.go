@@ -0,0 +1,628 @@
+package client_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+	"transaction-system/config"
+	"transaction-system/internal/domain"
+	"transaction-system/pkg/client"
+	"transaction-system/storage"
+	transport "transaction-system/transport/http"
+)
+
+const (
+	testWallet = 101234567
+	testAPIKey = "key_test"
+	testSecret = "secret"
+	testAdmin  = "admin-token"
+)
+
+var errDatabaseDown = errors.New("database is down")
+
+// bank - сервисы за роутером: кошелек testWallet с валютой 840 и журнал операций с ключами идемпотентности
+type bank struct {
+	mu           sync.Mutex
+	balance      float64
+	transactions []*domain.Transactions
+	operations   map[string]*domain.Transactions
+	queued       []*domain.Operations
+	// failures - сколько следующих операций завершится ошибкой БД
+	failures int
+	calls    int
+	keys     []string
+	clients  []*domain.Clients
+}
+
+func newBank() *bank {
+	return &bank{operations: make(map[string]*domain.Transactions)}
+}
+
+func (b *bank) apply(currencyCode int, amount float64, walletNumber int) (*domain.Transactions, error) {
+	b.calls++
+	if b.failures > 0 {
+		b.failures--
+		return nil, errDatabaseDown
+	}
+	if walletNumber != testWallet {
+		return nil, storage.ErrClientNotFound
+	}
+	if currencyCode != 840 {
+		return nil, storage.ErrCurrencyNotFound
+	}
+	if b.balance+amount < 0 {
+		return nil, storage.ErrInsufficientFunds
+	}
+
+	b.balance += amount
+	transaction := &domain.Transactions{
+		ID:        len(b.transactions) + 1,
+		ClientID:  1,
+		Client:    &domain.Clients{ID: 1, WalletNumber: testWallet},
+		Amount:    amount,
+		Status:    storage.CreatedStat,
+		Sequence:  int64(len(b.transactions) + 1),
+		CreatedAt: time.Now(),
+	}
+	b.transactions = append(b.transactions, transaction)
+
+	return transaction, nil
+}
+
+func (b *bank) AddAmountController(_ context.Context, currencyCode int, amount float64, walletNumber int, _ int) (*domain.Transactions, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.apply(currencyCode, amount, walletNumber)
+}
+
+func (b *bank) WithdrawAmountController(_ context.Context, currencyCode int, amount float64, walletNumber int, _ int) (*domain.Transactions, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.apply(currencyCode, -amount, walletNumber)
+}
+
+func (b *bank) GetAvailableBalanceController(context.Context, int, int) (float64, error) {
+	return b.balance, nil
+}
+
+func (b *bank) GetFrozenBalanceController(context.Context, int, int) (float64, error) {
+	return 0, nil
+}
+
+func (b *bank) GetBalanceController(_ context.Context, walletNumber int, _ int) (*domain.Balance, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if walletNumber != testWallet {
+		return nil, storage.ErrClientNotFound
+	}
+
+	return &domain.Balance{
+		Currencies: []domain.CurrencyBalance{{CurrencyCode: 840, CurrencyName: "USD", Frozen: b.balance}},
+	}, nil
+}
+
+func (b *bank) EnqueueOperation(_ context.Context, _ int, _ string, operation string, currencyCode int, amount float64, walletNumber int, cardNumber int) (*domain.Operations, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	op := &domain.Operations{
+		ID:           len(b.queued) + 1,
+		Operation:    operation,
+		CurrencyCode: currencyCode,
+		Amount:       amount,
+		WalletNumber: walletNumber,
+		CardNumber:   cardNumber,
+		Status:       domain.OperationPending,
+		CreatedAt:    time.Now(),
+	}
+	b.queued = append(b.queued, op)
+
+	return op, nil
+}
+
+func (b *bank) PerformOperation(_ context.Context, _ int, idempotencyKey string, operation string, currencyCode int, amount float64, walletNumber int, _ int) (*domain.Transactions, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.keys = append(b.keys, idempotencyKey)
+	if transaction, ok := b.operations[idempotencyKey]; ok {
+		return transaction, false, nil
+	}
+
+	if operation == domain.OperationWithdraw {
+		amount = -amount
+	}
+
+	transaction, err := b.apply(currencyCode, amount, walletNumber)
+	if err != nil {
+		return nil, false, err
+	}
+	b.operations[idempotencyKey] = transaction
+
+	return transaction, true, nil
+}
+
+func (b *bank) GetOperation(_ context.Context, _ int, id int) (*domain.Operations, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if id < 1 || id > len(b.queued) {
+		return nil, storage.ErrOperationNotFound
+	}
+
+	return b.queued[id-1], nil
+}
+
+// settleQueued проводит операции из очереди, как это делает OperationWorker
+func (b *bank) settleQueued() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, op := range b.queued {
+		amount := op.Amount
+		if op.Operation == domain.OperationWithdraw {
+			amount = -amount
+		}
+
+		transaction, err := b.apply(op.CurrencyCode, amount, op.WalletNumber)
+		if err != nil {
+			op.Status, op.ErrorCode, op.Error = domain.OperationFailed, "internal_error", err.Error()
+			continue
+		}
+		op.Status, op.TransactionID = domain.OperationCompleted, transaction.ID
+	}
+}
+
+func (b *bank) GetTransactionController(_ context.Context, id int) (*domain.Transactions, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if id < 1 || id > len(b.transactions) {
+		return nil, storage.ErrTransactionNotFound
+	}
+
+	return b.transactions[id-1], nil
+}
+
+func (b *bank) ListTransactionsController(_ context.Context, filter domain.TransactionFilter) ([]domain.Transactions, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if filter.WalletNumber != testWallet {
+		return nil, storage.ErrClientNotFound
+	}
+
+	var transactions []domain.Transactions
+	for i := len(b.transactions) - 1; i >= 0 && len(transactions) < filter.Limit; i-- {
+		transaction := b.transactions[i]
+		if filter.BeforeID != 0 && transaction.ID >= filter.BeforeID {
+			continue
+		}
+		transactions = append(transactions, *transaction)
+	}
+
+	return transactions, nil
+}
+
+func (b *bank) RefundTransactionController(_ context.Context, id int) (*domain.Transactions, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if id < 1 || id > len(b.transactions) {
+		return nil, storage.ErrTransactionNotFound
+	}
+
+	original := b.transactions[id-1]
+	for _, transaction := range b.transactions {
+		if transaction.RefundOf == id {
+			return nil, storage.ErrAlreadyRefunded
+		}
+	}
+
+	refund, err := b.apply(840, -original.Amount, testWallet)
+	if err != nil {
+		return nil, err
+	}
+	refund.RefundOf = id
+
+	return refund, nil
+}
+
+func (b *bank) CreateClientController(_ context.Context, walletNumber int, cardNumber int) (*domain.Clients, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, client := range b.clients {
+		if client.WalletNumber == walletNumber {
+			return nil, storage.ErrClientExists
+		}
+	}
+
+	client := &domain.Clients{ID: len(b.clients) + 1, WalletNumber: walletNumber, CardNumber: cardNumber}
+	b.clients = append(b.clients, client)
+
+	return client, nil
+}
+
+// apiKeys - ключ testAPIKey и подписи принятых запросов
+type apiKeys struct {
+	mu         sync.Mutex
+	signatures map[string]bool
+}
+
+func (k *apiKeys) FindActiveAPIKey(_ context.Context, keyID string) (*domain.APIKeys, error) {
+	if keyID != testAPIKey {
+		return nil, storage.ErrAPIKeyNotFound
+	}
+
+	sum := sha256.Sum256([]byte(testSecret))
+	return &domain.APIKeys{ID: 1, KeyID: testAPIKey, SigningKey: hex.EncodeToString(sum[:])}, nil
+}
+
+func (k *apiKeys) RememberSignature(_ context.Context, _ int, signature string, _ time.Time) (bool, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.signatures[signature] {
+		return false, nil
+	}
+	k.signatures[signature] = true
+
+	return true, nil
+}
+
+// newServer поднимает настоящий роутер сервиса поверх bank
+func newServer(t *testing.T, b *bank, configure func(cfg *config.Config)) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{}
+	cfg.Auth.AdminToken = testAdmin
+	if configure != nil {
+		configure(cfg)
+	}
+
+	logger := zap.NewNop()
+	controller := transport.NewWatController(b, b, cfg.Async.Default, logger)
+	router := transport.NewRouter(cfg, logger, controller, transport.NewAdminController(nil, b, logger), &apiKeys{signatures: map[string]bool{}})
+	router.MountVersion(transport.CurrentVersion, transport.NewTransactionController(b, logger))
+	router.RegisterRoutes()
+
+	server := httptest.NewServer(router.Handler())
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func newClient(t *testing.T, server *httptest.Server, opts ...client.Option) *client.Client {
+	t.Helper()
+
+	opts = append([]client.Option{
+		client.WithHTTPClient(server.Client()),
+		client.WithRetry(3, time.Millisecond, 10*time.Millisecond),
+	}, opts...)
+
+	c, err := client.New(server.URL, opts...)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	return c
+}
+
+func TestInvoiceWithdrawAndBalance(t *testing.T) {
+	b := newBank()
+	c := newClient(t, newServer(t, b, nil))
+	ctx := context.Background()
+
+	transaction, err := c.Invoice(ctx, client.OperationRequest{CurrencyCode: 840, Amount: 100, WalletNumber: testWallet})
+	if err != nil {
+		t.Fatalf("invoice: %v", err)
+	}
+	if transaction.ID != 1 || transaction.Amount != 100 || transaction.Status != client.TransactionCreated {
+		t.Fatalf("unexpected invoice transaction: %+v", transaction)
+	}
+
+	transaction, err = c.Withdraw(ctx, client.OperationRequest{CurrencyCode: 840, Amount: 30, WalletNumber: testWallet})
+	if err != nil {
+		t.Fatalf("withdraw: %v", err)
+	}
+	if transaction.Amount != -30 {
+		t.Fatalf("unexpected withdraw amount: %v", transaction.Amount)
+	}
+
+	balance, err := c.WalletBalance(ctx, testWallet)
+	if err != nil {
+		t.Fatalf("balance: %v", err)
+	}
+	if len(balance.Currencies) != 1 || balance.Currencies[0].Frozen != 70 || balance.Currencies[0].CurrencyName != "USD" {
+		t.Fatalf("unexpected balance: %+v", balance)
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	b := newBank()
+	c := newClient(t, newServer(t, b, nil))
+	ctx := context.Background()
+
+	_, err := c.Withdraw(ctx, client.OperationRequest{CurrencyCode: 840, Amount: 10, WalletNumber: testWallet})
+	if !errors.Is(err, client.ErrInsufficientFunds) {
+		t.Fatalf("expected insufficient_funds, got %v", err)
+	}
+
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict || apiErr.TraceID == "" {
+		t.Fatalf("expected 409 with trace id, got %#v", err)
+	}
+
+	_, err = c.WalletBalance(ctx, 42)
+	if !errors.Is(err, client.ErrClientNotFound) {
+		t.Fatalf("expected client_not_found, got %v", err)
+	}
+
+	_, err = c.Invoice(ctx, client.OperationRequest{CurrencyCode: 840, Amount: -1, WalletNumber: testWallet})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnprocessableEntity || !apiErr.HasCode("invalid_amount") {
+		t.Fatalf("expected 422 invalid_amount, got %#v", err)
+	}
+
+	// Ошибки в содержимом запроса не повторяются
+	if b.calls != 1 {
+		t.Fatalf("expected 1 call, got %d", b.calls)
+	}
+}
+
+func TestRetryServerErrorWithSameIdempotencyKey(t *testing.T) {
+	b := newBank()
+	b.failures = 2
+	c := newClient(t, newServer(t, b, nil))
+
+	transaction, err := c.Invoice(context.Background(), client.OperationRequest{CurrencyCode: 840, Amount: 5, WalletNumber: testWallet})
+	if err != nil {
+		t.Fatalf("invoice: %v", err)
+	}
+	if transaction.ID != 1 {
+		t.Fatalf("unexpected transaction: %+v", transaction)
+	}
+
+	if len(b.keys) != 3 || b.keys[0] == "" || b.keys[0] != b.keys[1] || b.keys[1] != b.keys[2] {
+		t.Fatalf("expected 3 attempts with one idempotency key, got %q", b.keys)
+	}
+}
+
+func TestRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	b := newBank()
+	b.failures = 10
+	c := newClient(t, newServer(t, b, nil))
+
+	_, err := c.Invoice(context.Background(), client.OperationRequest{CurrencyCode: 840, Amount: 5, WalletNumber: testWallet})
+	if !errors.Is(err, client.ErrInternal) {
+		t.Fatalf("expected internal_error, got %v", err)
+	}
+	if b.calls != 3 {
+		t.Fatalf("expected 3 attempts, got %d", b.calls)
+	}
+}
+
+// lossyTransport доставляет первый запрос до сервиса, но теряет ответ на него
+type lossyTransport struct {
+	next http.RoundTripper
+	lost bool
+}
+
+func (t *lossyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || t.lost {
+		return resp, err
+	}
+
+	t.lost = true
+	resp.Body.Close()
+	return nil, errors.New("connection reset by peer")
+}
+
+func TestLostResponseIsNotAppliedTwice(t *testing.T) {
+	b := newBank()
+	server := newServer(t, b, nil)
+	c := newClient(t, server, client.WithHTTPClient(&http.Client{Transport: &lossyTransport{next: server.Client().Transport}}))
+
+	transaction, err := c.Invoice(context.Background(), client.OperationRequest{CurrencyCode: 840, Amount: 5, WalletNumber: testWallet, IdempotencyKey: "order-1"})
+	if err != nil {
+		t.Fatalf("invoice: %v", err)
+	}
+	if transaction.ID != 1 || len(b.transactions) != 1 || b.balance != 5 {
+		t.Fatalf("expected a single transaction, got %d with balance %v", len(b.transactions), b.balance)
+	}
+	if len(b.keys) != 2 || b.keys[0] != "order-1" || b.keys[1] != "order-1" {
+		t.Fatalf("expected the retry to reuse the key, got %q", b.keys)
+	}
+}
+
+func TestContextCancelStopsRetries(t *testing.T) {
+	b := newBank()
+	b.failures = 10
+	server := newServer(t, b, nil)
+	c := newClient(t, server, client.WithRetry(5, time.Hour, time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := c.Invoice(ctx, client.OperationRequest{CurrencyCode: 840, Amount: 5, WalletNumber: testWallet})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if b.calls != 1 {
+		t.Fatalf("expected 1 attempt, got %d", b.calls)
+	}
+}
+
+func TestAsyncOperation(t *testing.T) {
+	b := newBank()
+	c := newClient(t, newServer(t, b, nil))
+	ctx := context.Background()
+
+	op, err := c.InvoiceAsync(ctx, client.OperationRequest{CurrencyCode: 840, Amount: 7, WalletNumber: testWallet})
+	if err != nil {
+		t.Fatalf("invoice async: %v", err)
+	}
+	if op.Status != client.OperationPending {
+		t.Fatalf("unexpected operation status: %s", op.Status)
+	}
+
+	b.settleQueued()
+
+	op, err = c.WaitOperation(ctx, op.ID, time.Millisecond)
+	if err != nil {
+		t.Fatalf("wait operation: %v", err)
+	}
+	if op.Status != client.OperationCompleted || op.TransactionID != 1 {
+		t.Fatalf("unexpected operation: %+v", op)
+	}
+
+	_, err = c.GetOperation(ctx, 99)
+	if !errors.Is(err, client.ErrOperationNotFound) {
+		t.Fatalf("expected operation_not_found, got %v", err)
+	}
+}
+
+func TestAsyncByDefault(t *testing.T) {
+	b := newBank()
+	c := newClient(t, newServer(t, b, func(cfg *config.Config) { cfg.Async.Default = true }))
+
+	_, err := c.Invoice(context.Background(), client.OperationRequest{CurrencyCode: 840, Amount: 7, WalletNumber: testWallet})
+
+	var accepted *client.AcceptedError
+	if !errors.As(err, &accepted) || accepted.Operation.ID != 1 {
+		t.Fatalf("expected accepted operation, got %v", err)
+	}
+}
+
+func TestAPIKeySignature(t *testing.T) {
+	b := newBank()
+	server := newServer(t, b, func(cfg *config.Config) { cfg.Auth.APIKeys.Enabled = true })
+	ctx := context.Background()
+
+	c := newClient(t, server, client.WithAPIKey(testAPIKey, testSecret))
+	_, err := c.Invoice(ctx, client.OperationRequest{CurrencyCode: 840, Amount: 1, WalletNumber: testWallet})
+	if err != nil {
+		t.Fatalf("signed invoice: %v", err)
+	}
+
+	_, err = c.WalletBalance(ctx, testWallet)
+	if err != nil {
+		t.Fatalf("signed balance: %v", err)
+	}
+
+	// Строка запроса входит в подпись
+	_, err = c.ListTransactions(ctx, client.TransactionFilter{WalletNumber: testWallet, Status: "Created", Limit: 10})
+	if err != nil {
+		t.Fatalf("signed list with query: %v", err)
+	}
+
+	c = newClient(t, server, client.WithAPIKey(testAPIKey, "wrong"))
+	_, err = c.WalletBalance(ctx, testWallet)
+	if !errors.Is(err, client.ErrInvalidSignature) {
+		t.Fatalf("expected invalid_signature, got %v", err)
+	}
+
+	c = newClient(t, server)
+	_, err = c.WalletBalance(ctx, testWallet)
+	if !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("expected unauthorized, got %v", err)
+	}
+}
+
+func TestTransactionsAndRefund(t *testing.T) {
+	b := newBank()
+	c := newClient(t, newServer(t, b, nil))
+	ctx := context.Background()
+
+	for _, amount := range []float64{10, 20, 30} {
+		_, err := c.Invoice(ctx, client.OperationRequest{CurrencyCode: 840, Amount: amount, WalletNumber: testWallet})
+		if err != nil {
+			t.Fatalf("invoice: %v", err)
+		}
+	}
+
+	page, err := c.ListTransactions(ctx, client.TransactionFilter{WalletNumber: testWallet, Limit: 2})
+	if err != nil {
+		t.Fatalf("list transactions: %v", err)
+	}
+	if len(page) != 2 || page[0].ID != 3 || page[1].ID != 2 {
+		t.Fatalf("unexpected first page: %+v", page)
+	}
+
+	page, err = c.ListTransactions(ctx, client.TransactionFilter{WalletNumber: testWallet, BeforeID: page[1].ID})
+	if err != nil {
+		t.Fatalf("list transactions: %v", err)
+	}
+	if len(page) != 1 || page[0].ID != 1 {
+		t.Fatalf("unexpected second page: %+v", page)
+	}
+
+	refund, err := c.Refund(ctx, 2)
+	if err != nil {
+		t.Fatalf("refund: %v", err)
+	}
+	if refund.RefundOf != 2 || refund.Amount != -20 {
+		t.Fatalf("unexpected refund: %+v", refund)
+	}
+
+	_, err = c.Refund(ctx, 2)
+	if !errors.Is(err, client.ErrAlreadyRefunded) {
+		t.Fatalf("expected already_refunded, got %v", err)
+	}
+
+	transaction, err := c.GetTransaction(ctx, refund.ID)
+	if err != nil {
+		t.Fatalf("get transaction: %v", err)
+	}
+	if transaction.RefundOf != 2 {
+		t.Fatalf("unexpected transaction: %+v", transaction)
+	}
+
+	_, err = c.GetTransaction(ctx, 99)
+	if !errors.Is(err, client.ErrTransactionNotFound) {
+		t.Fatalf("expected transaction_not_found, got %v", err)
+	}
+}
+
+func TestCreateClientRequiresAdminToken(t *testing.T) {
+	b := newBank()
+	server := newServer(t, b, nil)
+	ctx := context.Background()
+
+	_, err := newClient(t, server).CreateClient(ctx, client.CreateClientRequest{WalletNumber: 555})
+	if !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("expected unauthorized, got %v", err)
+	}
+
+	c := newClient(t, server, client.WithAdminToken(testAdmin))
+	created, err := c.CreateClient(ctx, client.CreateClientRequest{WalletNumber: 555, CardNumber: 4111})
+	if err != nil {
+		t.Fatalf("create client: %v", err)
+	}
+	if created.ID != 1 || created.WalletNumber != 555 || created.CardNumber != 4111 {
+		t.Fatalf("unexpected client: %+v", created)
+	}
+
+	_, err = c.CreateClient(ctx, client.CreateClientRequest{WalletNumber: 555})
+	if !errors.Is(err, client.ErrClientExists) {
+		t.Fatalf("expected client_exists, got %v", err)
+	}
+}
+
+func TestNewRejectsRelativeURL(t *testing.T) {
+	_, err := client.New("localhost:8080")
+	if err == nil {
+		t.Fatal("expected an error for a url without scheme")
+	}
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+// Коды ошибок API. Сравнивать ошибку с ними удобно через errors.Is(err, client.ErrInsufficientFunds)
+var (
+	ErrInvalidRequest       = &APIError{Code: "invalid_request"}
+	ErrValidationFailed     = &APIError{Code: "validation_failed"}
+	ErrInvalidAmount        = &APIError{Code: "invalid_amount"}
+	ErrAmountLimit          = &APIError{Code: "amount_limit_exceeded"}
+	ErrMissingRequisites    = &APIError{Code: "missing_requisites"}
+	ErrRequired             = &APIError{Code: "required"}
+	ErrClientNotFound       = &APIError{Code: "client_not_found"}
+	ErrCurrencyNotFound     = &APIError{Code: "currency_not_found"}
+	ErrOperationNotFound    = &APIError{Code: "operation_not_found"}
+	ErrBatchNotFound        = &APIError{Code: "batch_not_found"}
+	ErrTransactionNotFound  = &APIError{Code: "transaction_not_found"}
+	ErrClientExists         = &APIError{Code: "client_exists"}
+	ErrAlreadyRefunded      = &APIError{Code: "already_refunded"}
+	ErrNotRefundable        = &APIError{Code: "not_refundable"}
+	ErrInsufficientFunds    = &APIError{Code: "insufficient_funds"}
+	ErrIdempotencyKeyReused = &APIError{Code: "idempotency_key_reused"}
+	ErrUnauthorized         = &APIError{Code: "unauthorized"}
+	ErrInvalidSignature     = &APIError{Code: "invalid_signature"}
+	ErrInvalidToken         = &APIError{Code: "invalid_token"}
+	ErrForbidden            = &APIError{Code: "forbidden"}
+	ErrRateLimited          = &APIError{Code: "rate_limited"}
+	ErrInternal             = &APIError{Code: "internal_error"}
+)
+
+// APIError - ошибка, которую вернул сервис, в формате {error, code, trace_id, details}
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	TraceID    string
+	Details    []FieldDetail
+	// RetryAfter - значение заголовка Retry-After, если сервис его передал
+	RetryAfter time.Duration
+}
+
+type FieldDetail struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	if e.TraceID != "" {
+		return fmt.Sprintf("%s (%d %s, trace_id %s)", e.Message, e.StatusCode, e.Code, e.TraceID)
+	}
+
+	return fmt.Sprintf("%s (%d %s)", e.Message, e.StatusCode, e.Code)
+}
+
+// Is сравнивает ошибки по коду, поэтому errors.Is находит ошибку сервиса по значению из списка выше
+func (e *APIError) Is(target error) bool {
+	t, ok := target.(*APIError)
+	return ok && t.Code == e.Code
+}
+
+// HasCode сообщает, что ошибка сервиса или одна из ошибок полей в details имеет код code
+func (e *APIError) HasCode(code string) bool {
+	if e.Code == code {
+		return true
+	}
+
+	for _, d := range e.Details {
+		if d.Code == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// temporary - запрос можно повторить: лимит запросов, сбой сервиса или прокси перед ним
+func (e *APIError) temporary() bool {
+	return e.StatusCode == 429 || e.StatusCode >= 500
+}
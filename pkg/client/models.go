@@ -0,0 +1,148 @@
+package client
+
+import "time"
+
+// Статусы транзакции
+const (
+	TransactionCreated = "Created"
+	TransactionSuccess = "Success"
+	TransactionError   = "Error"
+)
+
+// Статусы асинхронной операции
+const (
+	OperationPending    = "Pending"
+	OperationProcessing = "Processing"
+	OperationCompleted  = "Completed"
+	OperationFailed     = "Failed"
+)
+
+// Статусы пакета
+const (
+	BatchPending    = "Pending"
+	BatchProcessing = "Processing"
+	BatchCompleted  = "Completed"
+	BatchPartial    = "PartiallyCompleted"
+	BatchFailed     = "Failed"
+)
+
+// Операции пакета
+const (
+	OperationInvoice  = "invoice"
+	OperationWithdraw = "withdraw"
+)
+
+// OperationRequest - тело POST /v1/invoice и POST /v1/withdraw. Нужен WalletNumber или CardNumber.
+// Пустой IdempotencyKey заменяется случайным, повторы запроса отправляются с тем же ключом
+type OperationRequest struct {
+	CurrencyCode   int     `json:"currency_code"`
+	Amount         float64 `json:"amount"`
+	WalletNumber   int     `json:"wallet_number,omitempty"`
+	CardNumber     int     `json:"card_number,omitempty"`
+	IdempotencyKey string  `json:"-"`
+}
+
+type Transaction struct {
+	ID          int       `json:"ID"`
+	ClientID    int       `json:"ClientID"`
+	CurrencyID  int       `json:"CurrencyID"`
+	Amount      float64   `json:"Amount"`
+	Status      string    `json:"Status"`
+	Sequence    int64     `json:"Sequence"`
+	APIKeyID    int       `json:"APIKeyID"`
+	OperationID int       `json:"OperationID"`
+	RefundOf    int       `json:"RefundOf"`
+	CreatedAt   time.Time `json:"CreatedAt"`
+	UpdatedAt   time.Time `json:"UpdatedAt"`
+}
+
+// TransactionFilter - выборка транзакций клиента для ListTransactions. Нужен WalletNumber или CardNumber,
+// нулевые поля не передаются
+type TransactionFilter struct {
+	WalletNumber int
+	CardNumber   int
+	Status       string
+	BeforeID     int
+	Limit        int
+}
+
+// CreateClientRequest - тело POST /v1/admin/clients
+type CreateClientRequest struct {
+	WalletNumber int `json:"wallet_number"`
+	CardNumber   int `json:"card_number,omitempty"`
+}
+
+type ClientInfo struct {
+	ID           int `json:"id"`
+	WalletNumber int `json:"wallet_number"`
+	CardNumber   int `json:"card_number,omitempty"`
+}
+
+// Balance - баланс по каждой валюте, общей суммы по всем валютам нет
+type Balance struct {
+	Currencies []CurrencyBalance `json:"currencies"`
+}
+
+type CurrencyBalance struct {
+	CurrencyCode int     `json:"currency_code"`
+	CurrencyName string  `json:"currency_name"`
+	Available    float64 `json:"available"`
+	Frozen       float64 `json:"frozen"`
+}
+
+type Operation struct {
+	ID            int             `json:"id"`
+	Operation     string          `json:"operation"`
+	Status        string          `json:"status"`
+	CurrencyCode  int             `json:"currency_code"`
+	Amount        float64         `json:"amount"`
+	WalletNumber  int             `json:"wallet_number,omitempty"`
+	CardNumber    int             `json:"card_number,omitempty"`
+	TransactionID int             `json:"transaction_id,omitempty"`
+	Attempts      int             `json:"attempts"`
+	Error         *OperationError `json:"error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+type OperationError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// BatchRequest - тело POST /v1/batches. Пустой IdempotencyKey заменяется случайным
+type BatchRequest struct {
+	Items          []BatchItem `json:"items"`
+	IdempotencyKey string      `json:"-"`
+}
+
+type BatchItem struct {
+	Operation    string  `json:"operation"`
+	CurrencyCode int     `json:"currency_code"`
+	Amount       float64 `json:"amount"`
+	WalletNumber int     `json:"wallet_number,omitempty"`
+	CardNumber   int     `json:"card_number,omitempty"`
+}
+
+type Batch struct {
+	ID        int               `json:"id"`
+	Status    string            `json:"status"`
+	Total     int               `json:"total"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Items     []BatchItemResult `json:"items"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+type BatchItemResult struct {
+	Position      int             `json:"position"`
+	Operation     string          `json:"operation"`
+	CurrencyCode  int             `json:"currency_code"`
+	Amount        float64         `json:"amount"`
+	WalletNumber  int             `json:"wallet_number,omitempty"`
+	CardNumber    int             `json:"card_number,omitempty"`
+	Status        string          `json:"status"`
+	TransactionID int             `json:"transaction_id,omitempty"`
+	Error         *OperationError `json:"error,omitempty"`
+}
@@ -0,0 +1,104 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultRestartBackoff    = time.Second
+	defaultMaxRestartBackoff = time.Minute
+)
+
+var ErrSubscriptionEnded = errors.New("subscription ended without error")
+
+// Consumer держит подписку канала до отмены контекста: если Subscribe завершился ошибкой брокера,
+// подписка оформляется заново с растущей паузой. Пока подписки нет, Alive возвращает ошибку
+type Consumer struct {
+	name       string
+	subscriber EventSubscriber
+	handler    Handler
+	backoff    RetryPolicy
+	logger     *zap.Logger
+
+	mu       sync.Mutex
+	err      error
+	failures int
+}
+
+// NewConsumer - подписка handler на subscriber. В backoff используются только InitialBackoff, MaxBackoff и Multiplier,
+// незаданные поля - секунда, минута и 2
+func NewConsumer(name string, subscriber EventSubscriber, handler Handler, backoff RetryPolicy, logger *zap.Logger) *Consumer {
+	if backoff.InitialBackoff <= 0 {
+		backoff.InitialBackoff = defaultRestartBackoff
+	}
+	if backoff.MaxBackoff <= 0 {
+		backoff.MaxBackoff = defaultMaxRestartBackoff
+	}
+
+	return &Consumer{
+		name:       name,
+		subscriber: subscriber,
+		handler:    handler,
+		backoff:    backoff.WithDefaults(),
+		logger:     logger.With(zap.String("consumer", name)),
+	}
+}
+
+// Run блокируется до отмены ctx. Подписка, проработавшая дольше MaxBackoff, сбрасывает паузу до начальной
+func (c *Consumer) Run(ctx context.Context) {
+	for {
+		started := time.Now()
+		err := c.subscriber.Subscribe(ctx, c.handler)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			err = ErrSubscriptionEnded
+		}
+
+		backoff := c.fail(err, time.Since(started))
+		c.logger.Error("Event subscriber stopped, restarting", zap.Duration("backoff", backoff), zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		c.mu.Lock()
+		c.err = nil
+		c.mu.Unlock()
+	}
+}
+
+// fail запоминает ошибку подписки и возвращает паузу перед следующей
+func (c *Consumer) fail(err error, lasted time.Duration) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if lasted > c.backoff.MaxBackoff {
+		c.failures = 0
+	}
+	c.failures++
+	c.err = err
+
+	return c.backoff.Backoff(c.failures)
+}
+
+// Alive - проверка для readiness: подписка оформлена, а не ждет перезапуска после ошибки
+func (c *Consumer) Alive(context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return fmt.Errorf("%s subscriber stopped after %d failures: %w", c.name, c.failures, c.err)
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package eventbus_test
+
+import (
+	"context"
+	"errors"
+	"go.uber.org/zap"
+	"sync/atomic"
+	"testing"
+	"time"
+	"transaction-system/pkg/eventbus"
+)
+
+var errBrokerDown = errors.New("broker is down")
+
+// flakySubscriber - первые failures подписок завершаются ошибкой брокера, следующие ждут отмены контекста
+type flakySubscriber struct {
+	failures   int32
+	subscribed atomic.Int32
+}
+
+func (s *flakySubscriber) Subscribe(ctx context.Context, _ eventbus.Handler) error {
+	if s.subscribed.Add(1) <= s.failures {
+		return errBrokerDown
+	}
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (s *flakySubscriber) Close() error {
+	return nil
+}
+
+func TestConsumerResubscribesAfterError(t *testing.T) {
+	sub := &flakySubscriber{failures: 2}
+	backoff := eventbus.RetryPolicy{InitialBackoff: 200 * time.Millisecond, MaxBackoff: time.Second}
+	consumer := eventbus.NewConsumer("events", sub, nil, backoff, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		consumer.Run(ctx)
+	}()
+
+	// Пока подписка ждет перезапуска, readiness видит ошибку брокера
+	time.Sleep(100 * time.Millisecond)
+	if err := consumer.Alive(ctx); !errors.Is(err, errBrokerDown) {
+		t.Fatalf("alive during backoff = %v, want %v", err, errBrokerDown)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for sub.subscribed.Load() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("subscribed %d times, want 3", sub.subscribed.Load())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := consumer.Alive(ctx); err != nil {
+		t.Fatalf("alive after resubscribe = %v", err)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("consumer did not stop after cancel")
+	}
+}
@@ -0,0 +1,58 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+)
+
+// Backends
+const (
+	BackendKafka  = "kafka"
+	BackendNATS   = "nats"
+	BackendMemory = "memory"
+)
+
+var (
+	ErrUnsupportedBackend = errors.New("unsupported event bus backend")
+	ErrClosed             = errors.New("event bus is closed")
+)
+
+// Message - транспортно-независимое представление события.
+// Partition и Offset заполняются только брокерами, которые их поддерживают (Kafka).
+type Message struct {
+	Topic     string
+	Key       []byte
+	Value     []byte
+	Headers   map[string]string
+	Partition int
+	Offset    int64
+}
+
+type Handler func(ctx context.Context, m Message) error
+
+// EventPublisher публикует сообщения одним вызовом, сохраняя их порядок
+type EventPublisher interface {
+	Publish(ctx context.Context, messages ...Message) error
+	Close() error
+}
+
+// EventSubscriber блокируется в Subscribe до отмены контекста или ошибки брокера.
+// Сообщение считается обработанным, когда handler вернул nil.
+type EventSubscriber interface {
+	Subscribe(ctx context.Context, handler Handler) error
+	Close() error
+}
+
+// Bus объединяет основной канал событий и dead-letter канал одного бэкенда.
+// Subscriber делит события между экземплярами сервиса, Stream доставляет каждому экземпляру все события
+type Bus struct {
+	Publisher  EventPublisher
+	Subscriber EventSubscriber
+	Stream     EventSubscriber
+	DLQ        EventPublisher
+}
+
+// Pinger - проверка доступности брокера для readiness. Реализуют каналы брокеров, которым нужна сеть
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
@@ -0,0 +1,155 @@
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const kafkaWriteTimeout = 10 * time.Second
+
+type KafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+func NewKafkaPublisher(writer *kafka.Writer) *KafkaPublisher {
+	return &KafkaPublisher{writer: writer}
+}
+
+// Publish отправляет сообщения в одном спане и передает его контекст в заголовках, чтобы консьюмер продолжил трассу
+func (p *KafkaPublisher) Publish(ctx context.Context, messages ...Message) (err error) {
+	ctx, span := startPublishSpan(ctx, p.writer.Topic, len(messages))
+	defer func() { endSpan(span, err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, kafkaWriteTimeout)
+	defer cancel()
+
+	kafkaMessages := make([]kafka.Message, 0, len(messages))
+	for _, m := range messages {
+		kafkaMessages = append(kafkaMessages, kafka.Message{
+			Key:     m.Key,
+			Value:   m.Value,
+			Headers: ToKafkaHeaders(traceHeaders(ctx, m.Headers)),
+		})
+	}
+
+	return p.writer.WriteMessages(ctx, kafkaMessages...)
+}
+
+func (p *KafkaPublisher) Close() error {
+	return p.writer.Close()
+}
+
+// Stats - статистика писателя с прошлого вызова, см. kafka.Writer.Stats
+func (p *KafkaPublisher) Stats() kafka.WriterStats {
+	return p.writer.Stats()
+}
+
+// Ping запрашивает метаданные топика писателя: брокер доступен, а у всех партиций есть лидер
+func (p *KafkaPublisher) Ping(ctx context.Context) error {
+	client := &kafka.Client{Addr: p.writer.Addr, Transport: p.writer.Transport}
+	res, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{p.writer.Topic}})
+	if err != nil {
+		return err
+	}
+
+	for _, t := range res.Topics {
+		if t.Error != nil {
+			return fmt.Errorf("topic %s: %w", t.Name, t.Error)
+		}
+		for _, partition := range t.Partitions {
+			if partition.Error != nil {
+				return fmt.Errorf("topic %s partition %d: %w", t.Name, partition.ID, partition.Error)
+			}
+		}
+	}
+
+	return nil
+}
+
+type KafkaSubscriber struct {
+	reader *kafka.Reader
+}
+
+func NewKafkaSubscriber(reader *kafka.Reader) *KafkaSubscriber {
+	return &KafkaSubscriber{reader: reader}
+}
+
+func (s *KafkaSubscriber) Subscribe(ctx context.Context, handler Handler) error {
+	for {
+		m, err := s.reader.FetchMessage(ctx)
+		if err != nil {
+			return err
+		}
+
+		message := FromKafkaMessage(m)
+		handlerCtx, span := startReceiveSpan(ctx, s.reader.Config().GroupID, message)
+
+		// Оффсет коммитим только после успешной обработки сообщения
+		err = handler(handlerCtx, message)
+		endSpan(span, err)
+		if err != nil {
+			return err
+		}
+
+		err = s.reader.CommitMessages(ctx, m)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *KafkaSubscriber) Close() error {
+	return s.reader.Close()
+}
+
+// Stats - статистика ридера с прошлого вызова, см. kafka.Reader.Stats
+func (s *KafkaSubscriber) Stats() kafka.ReaderStats {
+	return s.reader.Stats()
+}
+
+// Ping ищет партиции топика ридера через первый доступный брокер
+func (s *KafkaSubscriber) Ping(ctx context.Context) error {
+	cfg := s.reader.Config()
+	dialer := cfg.Dialer
+	if dialer == nil {
+		dialer = kafka.DefaultDialer
+	}
+
+	var err error
+	for _, broker := range cfg.Brokers {
+		_, err = dialer.LookupPartitions(ctx, "tcp", broker, cfg.Topic)
+		if err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
+func FromKafkaMessage(m kafka.Message) Message {
+	headers := make(map[string]string, len(m.Headers))
+	for _, h := range m.Headers {
+		headers[h.Key] = string(h.Value)
+	}
+
+	return Message{
+		Topic:     m.Topic,
+		Key:       m.Key,
+		Value:     m.Value,
+		Headers:   headers,
+		Partition: m.Partition,
+		Offset:    m.Offset,
+	}
+}
+
+func ToKafkaHeaders(headers map[string]string) []kafka.Header {
+	result := make([]kafka.Header, 0, len(headers))
+	for k, v := range headers {
+		result = append(result, kafka.Header{Key: k, Value: []byte(v)})
+	}
+
+	return result
+}
@@ -0,0 +1,114 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+const defaultMemoryBuffer = 1024
+
+var ErrBufferFull = errors.New("memory bus buffer is full")
+
+// MemoryBus - брокер в памяти процесса для локальной разработки и тестов.
+// Каждая группа получает свою копию сообщения, внутри группы сообщение достается одному подписчику.
+// Publish не ждет подписчиков: если в буфере какой-либо группы нет места, ничего не публикуется и возвращается ErrBufferFull
+type MemoryBus struct {
+	mu     sync.Mutex
+	topic  string
+	buffer int
+	groups map[string]chan Message
+	closed bool
+}
+
+func NewMemoryBus(topic string, buffer int) *MemoryBus {
+	if buffer <= 0 {
+		buffer = defaultMemoryBuffer
+	}
+
+	return &MemoryBus{topic: topic, buffer: buffer, groups: make(map[string]chan Message)}
+}
+
+// Publish кладет сообщения в буферы всех групп целиком или не кладет ни одного. Отправка под блокировкой
+// не ждет: место проверено заранее, а подписчики буферы только освобождают
+func (b *MemoryBus) Publish(ctx context.Context, messages ...Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return ErrClosed
+	}
+
+	for _, ch := range b.groups {
+		if cap(ch)-len(ch) < len(messages) {
+			return ErrBufferFull
+		}
+	}
+
+	for _, m := range messages {
+		m.Topic = b.topic
+		for _, ch := range b.groups {
+			ch <- m
+		}
+	}
+
+	return nil
+}
+
+func (b *MemoryBus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.closed {
+		b.closed = true
+		for _, ch := range b.groups {
+			close(ch)
+		}
+	}
+
+	return nil
+}
+
+// Subscriber регистрирует группу сразу, чтобы сообщения, опубликованные до вызова Subscribe, не терялись
+func (b *MemoryBus) Subscriber(group string) EventSubscriber {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch, ok := b.groups[group]
+	if !ok {
+		ch = make(chan Message, b.buffer)
+		b.groups[group] = ch
+	}
+
+	return &memorySubscriber{messages: ch}
+}
+
+type memorySubscriber struct {
+	messages chan Message
+}
+
+func (s *memorySubscriber) Subscribe(ctx context.Context, handler Handler) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case m, ok := <-s.messages:
+			if !ok {
+				return ErrClosed
+			}
+
+			err := handler(ctx, m)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *memorySubscriber) Close() error {
+	return nil
+}
@@ -0,0 +1,183 @@
+package eventbus_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+	"transaction-system/pkg/eventbus"
+)
+
+const testTopic = "transactions"
+
+// receive забирает из подписки n сообщений или падает по таймауту
+func receive(t *testing.T, sub eventbus.EventSubscriber, n int) []eventbus.Message {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var received []eventbus.Message
+	errDone := errors.New("done")
+	err := sub.Subscribe(ctx, func(_ context.Context, m eventbus.Message) error {
+		received = append(received, m)
+		if len(received) == n {
+			return errDone
+		}
+		return nil
+	})
+	if !errors.Is(err, errDone) {
+		t.Fatalf("received %d of %d messages: %v", len(received), n, err)
+	}
+
+	return received
+}
+
+func TestMemoryBusFanOutToGroups(t *testing.T) {
+	bus := eventbus.NewMemoryBus(testTopic, 0)
+	defer bus.Close()
+
+	first := bus.Subscriber("first")
+	second := bus.Subscriber("second")
+
+	err := bus.Publish(context.Background(), eventbus.Message{Key: []byte("7"), Value: []byte("a")}, eventbus.Message{Value: []byte("b")})
+	if err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	for _, sub := range []eventbus.EventSubscriber{first, second} {
+		messages := receive(t, sub, 2)
+		if string(messages[0].Value) != "a" || string(messages[1].Value) != "b" {
+			t.Fatalf("messages out of order: %q, %q", messages[0].Value, messages[1].Value)
+		}
+		if messages[0].Topic != testTopic || string(messages[0].Key) != "7" {
+			t.Fatalf("unexpected message %+v", messages[0])
+		}
+	}
+}
+
+func TestMemoryBusGroupSharesMessages(t *testing.T) {
+	bus := eventbus.NewMemoryBus(testTopic, 0)
+	defer bus.Close()
+
+	first := bus.Subscriber("group")
+	second := bus.Subscriber("group")
+
+	err := bus.Publish(context.Background(), eventbus.Message{Value: []byte("a")}, eventbus.Message{Value: []byte("b")})
+	if err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	receive(t, first, 1)
+	receive(t, second, 1)
+}
+
+func TestMemoryBusFullBufferDoesNotBlock(t *testing.T) {
+	bus := eventbus.NewMemoryBus(testTopic, 2)
+	defer bus.Close()
+
+	idle := bus.Subscriber("idle")
+	active := bus.Subscriber("active")
+
+	err := bus.Publish(context.Background(), eventbus.Message{Value: []byte("a")}, eventbus.Message{Value: []byte("b")})
+	if err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	receive(t, active, 2)
+
+	// В буфере idle нет места: публикация сразу возвращает ошибку и не попадает даже в свободный буфер active
+	done := make(chan error, 1)
+	go func() {
+		done <- bus.Publish(context.WithoutCancel(context.Background()), eventbus.Message{Value: []byte("c")})
+	}()
+
+	select {
+	case err = <-done:
+	case <-time.After(time.Second):
+		t.Fatal("publish blocked on a full buffer")
+	}
+	if !errors.Is(err, eventbus.ErrBufferFull) {
+		t.Fatalf("expected ErrBufferFull, got %v", err)
+	}
+
+	receive(t, idle, 2)
+
+	err = bus.Publish(context.Background(), eventbus.Message{Value: []byte("d")})
+	if err != nil {
+		t.Fatalf("publish after drain: %v", err)
+	}
+	if messages := receive(t, active, 1); string(messages[0].Value) != "d" {
+		t.Fatalf("active received %q instead of the next published message", messages[0].Value)
+	}
+}
+
+func TestMemoryBusCloseWithFullBuffers(t *testing.T) {
+	bus := eventbus.NewMemoryBus(testTopic, 1)
+	sub := bus.Subscriber("group")
+
+	err := bus.Publish(context.Background(), eventbus.Message{Value: []byte("a")})
+	if err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = bus.Publish(context.WithoutCancel(context.Background()), eventbus.Message{Value: []byte("b")})
+		}()
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		wg.Wait()
+		_ = bus.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("close deadlocked with publishers waiting on a full buffer")
+	}
+
+	err = bus.Publish(context.Background(), eventbus.Message{Value: []byte("c")})
+	if !errors.Is(err, eventbus.ErrClosed) {
+		t.Fatalf("expected ErrClosed after close, got %v", err)
+	}
+
+	// Сообщения, принятые до закрытия, дочитываются, затем подписка завершается
+	var received int
+	err = sub.Subscribe(context.Background(), func(context.Context, eventbus.Message) error {
+		received++
+		return nil
+	})
+	if !errors.Is(err, eventbus.ErrClosed) || received != 1 {
+		t.Fatalf("expected 1 message and ErrClosed, got %d and %v", received, err)
+	}
+}
+
+func TestMemoryBusCanceledContext(t *testing.T) {
+	bus := eventbus.NewMemoryBus(testTopic, 0)
+	defer bus.Close()
+
+	sub := bus.Subscriber("group")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := bus.Publish(ctx, eventbus.Message{Value: []byte("a")})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled from publish, got %v", err)
+	}
+
+	err = sub.Subscribe(ctx, func(context.Context, eventbus.Message) error {
+		t.Fatal("handler called for a message that was not published")
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled from subscribe, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package eventbus
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+)
+
+type NATSPublisher struct {
+	conn    *nats.Conn
+	subject string
+}
+
+func NewNATSPublisher(conn *nats.Conn, subject string) *NATSPublisher {
+	return &NATSPublisher{conn: conn, subject: subject}
+}
+
+func (p *NATSPublisher) Publish(ctx context.Context, messages ...Message) error {
+	for _, m := range messages {
+		msg := nats.NewMsg(p.subject)
+		msg.Data = m.Value
+		for k, v := range m.Headers {
+			msg.Header.Set(k, v)
+		}
+		if len(m.Key) > 0 {
+			msg.Header.Set(natsKeyHeader, string(m.Key))
+		}
+
+		err := p.conn.PublishMsg(msg)
+		if err != nil {
+			return err
+		}
+	}
+
+	return p.conn.FlushWithContext(ctx)
+}
+
+// Соединение общее для всех каналов и закрывается инициализатором
+func (p *NATSPublisher) Close() error {
+	return nil
+}
+
+func (p *NATSPublisher) Ping(ctx context.Context) error {
+	return pingNATS(ctx, p.conn)
+}
+
+type NATSSubscriber struct {
+	conn    *nats.Conn
+	subject string
+	queue   string
+}
+
+func NewNATSSubscriber(conn *nats.Conn, subject string, queue string) *NATSSubscriber {
+	return &NATSSubscriber{conn: conn, subject: subject, queue: queue}
+}
+
+func (s *NATSSubscriber) Subscribe(ctx context.Context, handler Handler) error {
+	sub, err := s.conn.QueueSubscribeSync(s.subject, s.queue)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	for {
+		msg, err := sub.NextMsgWithContext(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = handler(ctx, fromNATSMessage(msg))
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *NATSSubscriber) Close() error {
+	return nil
+}
+
+func (s *NATSSubscriber) Ping(ctx context.Context) error {
+	return pingNATS(ctx, s.conn)
+}
+
+// pingNATS проверяет, что соединение установлено, и дожидается ответа сервера на PING
+func pingNATS(ctx context.Context, conn *nats.Conn) error {
+	if status := conn.Status(); status != nats.CONNECTED {
+		return fmt.Errorf("nats connection is %s", status)
+	}
+
+	return conn.FlushWithContext(ctx)
+}
+
+// NATS не знает о ключах сообщений, поэтому ключ передается заголовком
+const natsKeyHeader = "x-key"
+
+func fromNATSMessage(msg *nats.Msg) Message {
+	headers := make(map[string]string, len(msg.Header))
+	var key []byte
+	for k := range msg.Header {
+		if k == natsKeyHeader {
+			key = []byte(msg.Header.Get(k))
+			continue
+		}
+		headers[k] = msg.Header.Get(k)
+	}
+
+	return Message{
+		Topic:   msg.Subject,
+		Key:     key,
+		Value:   msg.Data,
+		Headers: headers,
+	}
+}
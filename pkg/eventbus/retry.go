@@ -0,0 +1,132 @@
+package eventbus
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Заголовки, которыми помечаются сообщения в dead-letter канале
+const (
+	HeaderError             = "x-error"
+	HeaderAttempts          = "x-attempts"
+	HeaderOriginalTopic     = "x-original-topic"
+	HeaderOriginalPartition = "x-original-partition"
+	HeaderOriginalOffset    = "x-original-offset"
+	HeaderFailedAt          = "x-failed-at"
+)
+
+const (
+	defaultMaxAttempts    = 3
+	defaultInitialBackoff = 100 * time.Millisecond
+	defaultMaxBackoff     = 5 * time.Second
+	defaultMultiplier     = 2
+)
+
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+}
+
+// WithDefaults подставляет значения по умолчанию вместо незаданных полей
+func (p RetryPolicy) WithDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = defaultMaxAttempts
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = defaultInitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = defaultMaxBackoff
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = defaultMultiplier
+	}
+
+	return p
+}
+
+// Backoff возвращает паузу перед повтором номер attempt (нумерация с 1)
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	backoff := float64(p.InitialBackoff)
+	for i := 1; i < attempt; i++ {
+		backoff *= p.Multiplier
+		if backoff >= float64(p.MaxBackoff) {
+			return p.MaxBackoff
+		}
+	}
+
+	return time.Duration(backoff)
+}
+
+// WithRetry оборачивает handler повторами с экспоненциальной задержкой.
+// После MaxAttempts неудачных попыток сообщение уходит в dlq, и handler считается отработавшим.
+func WithRetry(handler Handler, policy RetryPolicy, dlq EventPublisher, logger *zap.Logger) Handler {
+	policy = policy.WithDefaults()
+
+	return func(ctx context.Context, m Message) error {
+		var err error
+		for attempt := 1; attempt <= policy.MaxAttempts; attempt++ {
+			err = handler(ctx, m)
+			if err == nil {
+				return nil
+			}
+
+			logger.Warn("Failed to process event",
+				zap.Int("attempt", attempt), zap.String("key", string(m.Key)), zap.Error(err))
+
+			if attempt == policy.MaxAttempts {
+				break
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(policy.Backoff(attempt)):
+			}
+		}
+
+		errDLQ := dlq.Publish(ctx, deadLetter(m, policy.MaxAttempts, err))
+		if errDLQ != nil {
+			return errDLQ
+		}
+
+		logger.Error("Event moved to dead-letter channel", zap.String("key", string(m.Key)), zap.Error(err))
+
+		return nil
+	}
+}
+
+func deadLetter(m Message, attempts int, cause error) Message {
+	headers := make(map[string]string, len(m.Headers)+6)
+	for k, v := range m.Headers {
+		headers[k] = v
+	}
+
+	headers[HeaderError] = cause.Error()
+	headers[HeaderAttempts] = strconv.Itoa(attempts)
+	headers[HeaderOriginalTopic] = m.Topic
+	headers[HeaderOriginalPartition] = strconv.Itoa(m.Partition)
+	headers[HeaderOriginalOffset] = strconv.FormatInt(m.Offset, 10)
+	headers[HeaderFailedAt] = time.Now().UTC().Format(time.RFC3339)
+
+	return Message{Key: m.Key, Value: m.Value, Headers: headers}
+}
+
+// StripDeadLetterHeaders убирает служебные заголовки DLQ перед повторной отправкой сообщения
+func StripDeadLetterHeaders(headers map[string]string) map[string]string {
+	result := make(map[string]string, len(headers))
+	for k, v := range headers {
+		switch k {
+		case HeaderError, HeaderAttempts, HeaderOriginalTopic, HeaderOriginalPartition, HeaderOriginalOffset, HeaderFailedAt:
+			continue
+		}
+		result[k] = v
+	}
+
+	return result
+}
@@ -0,0 +1,66 @@
+package eventbus
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "transaction-system/pkg/eventbus"
+
+// startPublishSpan начинает спан отправки сообщений в топик
+func startPublishSpan(ctx context.Context, topic string, count int) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, topic+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingOperationPublish,
+			semconv.MessagingDestinationName(topic),
+			semconv.MessagingBatchMessageCount(count),
+		),
+	)
+}
+
+// startReceiveSpan начинает спан обработки сообщения. Родителем становится спан отправки
+// из заголовков сообщения, так трасса запроса продолжается в консьюмере
+func startReceiveSpan(ctx context.Context, group string, m Message) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(m.Headers))
+
+	return otel.Tracer(tracerName).Start(ctx, m.Topic+" receive",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemKafka,
+			semconv.MessagingOperationReceive,
+			semconv.MessagingDestinationName(m.Topic),
+			semconv.MessagingKafkaConsumerGroup(group),
+			semconv.MessagingKafkaDestinationPartition(m.Partition),
+			semconv.MessagingKafkaMessageOffset(int(m.Offset)),
+			semconv.MessagingKafkaMessageKey(string(m.Key)),
+		),
+	)
+}
+
+// traceHeaders - копия заголовков сообщения с контекстом трассировки из ctx.
+// Заголовки вызывающего не меняются: одно сообщение может отправляться повторно из разных спанов
+func traceHeaders(ctx context.Context, headers map[string]string) map[string]string {
+	result := make(map[string]string, len(headers)+2)
+	for k, v := range headers {
+		result[k] = v
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(result))
+
+	return result
+}
+
+// endSpan завершает спан, отмечая его ошибкой, если она есть
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,174 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/segmentio/kafka-go"
+)
+
+var kafkaLabels = []string{"channel", "topic"}
+
+var (
+	kafkaWriterWrites   = prometheus.NewDesc("kafka_writer_writes_total", "Write requests sent to Kafka.", kafkaLabels, nil)
+	kafkaWriterMessages = prometheus.NewDesc("kafka_writer_messages_total", "Messages written to Kafka.", kafkaLabels, nil)
+	kafkaWriterBytes    = prometheus.NewDesc("kafka_writer_message_bytes_total", "Message bytes written to Kafka.", kafkaLabels, nil)
+	kafkaWriterErrors   = prometheus.NewDesc("kafka_writer_errors_total", "Failed writes to Kafka.", kafkaLabels, nil)
+	kafkaWriterRetries  = prometheus.NewDesc("kafka_writer_retries_total", "Retried writes to Kafka.", kafkaLabels, nil)
+	kafkaWriterWrite    = prometheus.NewDesc("kafka_writer_write_seconds", "Time spent writing batches to Kafka.", kafkaLabels, nil)
+	kafkaWriterWait     = prometheus.NewDesc("kafka_writer_wait_seconds", "Time spent waiting for a batch to fill.", kafkaLabels, nil)
+	kafkaWriterBatch    = prometheus.NewDesc("kafka_writer_batch_size", "Messages per written batch.", kafkaLabels, nil)
+
+	kafkaReaderDials      = prometheus.NewDesc("kafka_reader_dials_total", "Connections opened to Kafka brokers.", kafkaLabels, nil)
+	kafkaReaderFetches    = prometheus.NewDesc("kafka_reader_fetches_total", "Fetch requests sent to Kafka.", kafkaLabels, nil)
+	kafkaReaderMessages   = prometheus.NewDesc("kafka_reader_messages_total", "Messages read from Kafka.", kafkaLabels, nil)
+	kafkaReaderBytes      = prometheus.NewDesc("kafka_reader_message_bytes_total", "Message bytes read from Kafka.", kafkaLabels, nil)
+	kafkaReaderRebalances = prometheus.NewDesc("kafka_reader_rebalances_total", "Consumer group rebalances.", kafkaLabels, nil)
+	kafkaReaderTimeouts   = prometheus.NewDesc("kafka_reader_timeouts_total", "Fetch requests that timed out.", kafkaLabels, nil)
+	kafkaReaderErrors     = prometheus.NewDesc("kafka_reader_errors_total", "Failed reads from Kafka.", kafkaLabels, nil)
+	kafkaReaderRead       = prometheus.NewDesc("kafka_reader_read_seconds", "Time spent reading fetched messages.", kafkaLabels, nil)
+	kafkaReaderWait       = prometheus.NewDesc("kafka_reader_wait_seconds", "Time spent waiting for fetch responses.", kafkaLabels, nil)
+	kafkaReaderLag        = prometheus.NewDesc("kafka_reader_lag", "Messages behind the end of the partition.", kafkaLabels, nil)
+	kafkaReaderOffset     = prometheus.NewDesc("kafka_reader_offset", "Current reader offset.", kafkaLabels, nil)
+	kafkaReaderQueue      = prometheus.NewDesc("kafka_reader_queue_length", "Fetched messages waiting to be consumed.", kafkaLabels, nil)
+	kafkaReaderCapacity   = prometheus.NewDesc("kafka_reader_queue_capacity", "Capacity of the fetched messages queue.", kafkaLabels, nil)
+)
+
+// summary накапливает сумму и число наблюдений: Stats() в kafka-go возвращает их приращения с прошлого вызова
+type summary struct {
+	count uint64
+	sum   float64
+}
+
+func (s *summary) addDuration(stats kafka.DurationStats) {
+	s.count += uint64(stats.Count)
+	s.sum += stats.Sum.Seconds()
+}
+
+func (s *summary) addSummary(stats kafka.SummaryStats) {
+	s.count += uint64(stats.Count)
+	s.sum += float64(stats.Sum)
+}
+
+func (s *summary) metric(desc *prometheus.Desc, labels ...string) prometheus.Metric {
+	return prometheus.MustNewConstSummary(desc, s.count, s.sum, nil, labels...)
+}
+
+func counter(desc *prometheus.Desc, value int64, labels ...string) prometheus.Metric {
+	return prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(value), labels...)
+}
+
+func gauge(desc *prometheus.Desc, value int64, labels ...string) prometheus.Metric {
+	return prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(value), labels...)
+}
+
+// KafkaCollector - метрики писателей и ридеров Kafka. channel различает их в шине: publisher, dlq, subscriber, stream
+type KafkaCollector struct {
+	mu      sync.Mutex
+	writers []*kafkaWriter
+	readers []*kafkaReader
+}
+
+type kafkaWriter struct {
+	channel string
+	stats   func() kafka.WriterStats
+
+	writes, messages, bytes, errors, retries int64
+	write, wait, batch                       summary
+}
+
+type kafkaReader struct {
+	channel string
+	stats   func() kafka.ReaderStats
+
+	dials, fetches, messages, bytes, rebalances, timeouts, errors int64
+	read, wait                                                    summary
+}
+
+func NewKafkaCollector() *KafkaCollector {
+	return &KafkaCollector{}
+}
+
+// AddWriter добавляет писателя, вызывать до регистрации коллектора
+func (c *KafkaCollector) AddWriter(channel string, stats func() kafka.WriterStats) {
+	c.writers = append(c.writers, &kafkaWriter{channel: channel, stats: stats})
+}
+
+// AddReader добавляет ридер, вызывать до регистрации коллектора
+func (c *KafkaCollector) AddReader(channel string, stats func() kafka.ReaderStats) {
+	c.readers = append(c.readers, &kafkaReader{channel: channel, stats: stats})
+}
+
+func (c *KafkaCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		kafkaWriterWrites, kafkaWriterMessages, kafkaWriterBytes, kafkaWriterErrors,
+		kafkaWriterRetries, kafkaWriterWrite, kafkaWriterWait, kafkaWriterBatch,
+		kafkaReaderDials, kafkaReaderFetches, kafkaReaderMessages, kafkaReaderBytes, kafkaReaderRebalances,
+		kafkaReaderTimeouts, kafkaReaderErrors, kafkaReaderRead, kafkaReaderWait, kafkaReaderLag,
+		kafkaReaderOffset, kafkaReaderQueue, kafkaReaderCapacity,
+	} {
+		ch <- desc
+	}
+}
+
+func (c *KafkaCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, w := range c.writers {
+		w.collect(ch)
+	}
+	for _, r := range c.readers {
+		r.collect(ch)
+	}
+}
+
+func (w *kafkaWriter) collect(ch chan<- prometheus.Metric) {
+	stats := w.stats()
+	w.writes += stats.Writes
+	w.messages += stats.Messages
+	w.bytes += stats.Bytes
+	w.errors += stats.Errors
+	w.retries += stats.Retries
+	w.write.addDuration(stats.WriteTime)
+	w.wait.addDuration(stats.WaitTime)
+	w.batch.addSummary(stats.BatchSize)
+
+	labels := []string{w.channel, stats.Topic}
+	ch <- counter(kafkaWriterWrites, w.writes, labels...)
+	ch <- counter(kafkaWriterMessages, w.messages, labels...)
+	ch <- counter(kafkaWriterBytes, w.bytes, labels...)
+	ch <- counter(kafkaWriterErrors, w.errors, labels...)
+	ch <- counter(kafkaWriterRetries, w.retries, labels...)
+	ch <- w.write.metric(kafkaWriterWrite, labels...)
+	ch <- w.wait.metric(kafkaWriterWait, labels...)
+	ch <- w.batch.metric(kafkaWriterBatch, labels...)
+}
+
+func (r *kafkaReader) collect(ch chan<- prometheus.Metric) {
+	stats := r.stats()
+	r.dials += stats.Dials
+	r.fetches += stats.Fetches
+	r.messages += stats.Messages
+	r.bytes += stats.Bytes
+	r.rebalances += stats.Rebalances
+	r.timeouts += stats.Timeouts
+	r.errors += stats.Errors
+	r.read.addDuration(stats.ReadTime)
+	r.wait.addDuration(stats.WaitTime)
+
+	labels := []string{r.channel, stats.Topic}
+	ch <- counter(kafkaReaderDials, r.dials, labels...)
+	ch <- counter(kafkaReaderFetches, r.fetches, labels...)
+	ch <- counter(kafkaReaderMessages, r.messages, labels...)
+	ch <- counter(kafkaReaderBytes, r.bytes, labels...)
+	ch <- counter(kafkaReaderRebalances, r.rebalances, labels...)
+	ch <- counter(kafkaReaderTimeouts, r.timeouts, labels...)
+	ch <- counter(kafkaReaderErrors, r.errors, labels...)
+	ch <- r.read.metric(kafkaReaderRead, labels...)
+	ch <- r.wait.metric(kafkaReaderWait, labels...)
+	ch <- gauge(kafkaReaderLag, stats.Lag, labels...)
+	ch <- gauge(kafkaReaderOffset, stats.Offset, labels...)
+	ch <- gauge(kafkaReaderQueue, stats.QueueLength, labels...)
+	ch <- gauge(kafkaReaderCapacity, stats.QueueCapacity, labels...)
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// Registry - реестр метрик сервиса, отдается на /metrics. Коллекторы БД и брокера регистрируются при старте
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Duration of HTTP requests by method, route template and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	TransactionsCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "transactions_created_total",
+		Help: "Transactions created by currency and initial status.",
+	}, []string{"currency", "status"})
+
+	TransactionsSettled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "transactions_settled_total",
+		Help: "Transactions settled by the scheduler by currency and resulting status.",
+	}, []string{"currency", "status"})
+
+	SchedulerJobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "scheduler_job_duration_seconds",
+		Help:    "Duration of scheduler job runs.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"job"})
+
+	SchedulerJobFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "scheduler_job_failures_total",
+		Help: "Scheduler job runs that returned an error.",
+	}, []string{"job"})
+
+	RateLimitAllowed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rate_limit_allowed_total",
+		Help: "Requests passed by the rate limiter.",
+	}, []string{"route"})
+
+	RateLimitRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rate_limit_rejected_total",
+		Help: "Requests rejected by the rate limiter.",
+	}, []string{"dimension", "route"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestDuration,
+		TransactionsCreated,
+		TransactionsSettled,
+		SchedulerJobDuration,
+		SchedulerJobFailures,
+		RateLimitAllowed,
+		RateLimitRejected,
+	)
+}
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const pendingQueryTimeout = 5 * time.Second
+
+var (
+	poolHits     = prometheus.NewDesc("pg_pool_hits_total", "Connections taken from the pool without dialing.", nil, nil)
+	poolMisses   = prometheus.NewDesc("pg_pool_misses_total", "Connections dialed because the pool had no idle one.", nil, nil)
+	poolTimeouts = prometheus.NewDesc("pg_pool_timeouts_total", "Waits for a free connection that timed out.", nil, nil)
+	poolStale    = prometheus.NewDesc("pg_pool_stale_connections_total", "Stale connections removed from the pool.", nil, nil)
+	poolTotal    = prometheus.NewDesc("pg_pool_connections", "Open connections in the pool.", nil, nil)
+	poolIdle     = prometheus.NewDesc("pg_pool_idle_connections", "Idle connections in the pool.", nil, nil)
+	poolSize     = prometheus.NewDesc("pg_pool_max_connections", "Configured pool size.", nil, nil)
+
+	pendingCount = prometheus.NewDesc("transactions_pending", "Transactions in Created status waiting for settlement.", []string{"currency"}, nil)
+	pendingAge   = prometheus.NewDesc("transactions_pending_oldest_age_seconds", "Age of the oldest transaction in Created status.", []string{"currency"}, nil)
+)
+
+// PoolCollector - статистика пула соединений go-pg. PoolStats() накопительная, поэтому значения отдаются как есть
+type PoolCollector struct {
+	db *pg.DB
+}
+
+func NewPoolCollector(db *pg.DB) *PoolCollector {
+	return &PoolCollector{db: db}
+}
+
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{poolHits, poolMisses, poolTimeouts, poolStale, poolTotal, poolIdle, poolSize} {
+		ch <- desc
+	}
+}
+
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.db.PoolStats()
+	ch <- counter(poolHits, int64(stats.Hits))
+	ch <- counter(poolMisses, int64(stats.Misses))
+	ch <- counter(poolTimeouts, int64(stats.Timeouts))
+	ch <- counter(poolStale, int64(stats.StaleConns))
+	ch <- gauge(poolTotal, int64(stats.TotalConns))
+	ch <- gauge(poolIdle, int64(stats.IdleConns))
+	ch <- gauge(poolSize, int64(c.db.Options().PoolSize))
+}
+
+// PendingTransactions - непроведенные транзакции одной валюты
+type PendingTransactions struct {
+	Currency  string
+	Count     int
+	OldestAge float64
+}
+
+// PendingCollector запрашивает непроведенные транзакции при каждом сборе метрик, чтобы после рестарта
+// и на всех экземплярах сервиса значения совпадали с БД
+type PendingCollector struct {
+	pending func(ctx context.Context) ([]PendingTransactions, error)
+	logger  *zap.Logger
+}
+
+func NewPendingCollector(pending func(ctx context.Context) ([]PendingTransactions, error), logger *zap.Logger) *PendingCollector {
+	return &PendingCollector{pending: pending, logger: logger}
+}
+
+func (c *PendingCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- pendingCount
+	ch <- pendingAge
+}
+
+func (c *PendingCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), pendingQueryTimeout)
+	defer cancel()
+
+	pending, err := c.pending(ctx)
+	if err != nil {
+		c.logger.Error("Failed to collect pending transactions", zap.Error(err))
+		ch <- prometheus.NewInvalidMetric(pendingCount, err)
+		return
+	}
+
+	for _, p := range pending {
+		ch <- prometheus.MustNewConstMetric(pendingCount, prometheus.GaugeValue, float64(p.Count), p.Currency)
+		ch <- prometheus.MustNewConstMetric(pendingAge, prometheus.GaugeValue, p.OldestAge, p.Currency)
+	}
+}
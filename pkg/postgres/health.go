@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-pg/pg/v10"
+)
+
+// Ping checks db connectivity and returns an error when more than maxUsage of the pool is in use
+func Ping(ctx context.Context, db *pg.DB, maxUsage float64) error {
+	err := db.Ping(ctx)
+	if err != nil {
+		return err
+	}
+
+	poolSize := db.Options().PoolSize
+	stats := db.PoolStats()
+	inUse := int(stats.TotalConns) - int(stats.IdleConns)
+	if maxUsage > 0 && poolSize > 0 && float64(inUse) >= maxUsage*float64(poolSize) {
+		return fmt.Errorf("connection pool is saturated: %d of %d connections in use, %d timeouts", inUse, poolSize, stats.Timeouts)
+	}
+
+	return nil
+}
+
+// CheckVersion returns an error when db version is behind the latest registered migration
+func CheckVersion(db *pg.DB) error {
+	current, latest, err := Status(db)
+	if err != nil {
+		return err
+	}
+
+	if current < latest {
+		return fmt.Errorf("schema version %d is behind %d", current, latest)
+	}
+
+	return nil
+}
@@ -1,12 +1,18 @@
 package postgres
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"math"
+	"strconv"
 
 	"github.com/go-pg/migrations/v8"
 	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
 )
 
 // Warning: init performs automatically
@@ -25,10 +31,12 @@ var (
 )
 
 const (
-	ActionUp    = "up"
-	ActionReset = "reset"
-	ActionInit  = "init"
-	ActionDown  = "down"
+	ActionUp         = "up"
+	ActionReset      = "reset"
+	ActionInit       = "init"
+	ActionDown       = "down"
+	ActionVersion    = "version"
+	ActionSetVersion = "set_version"
 )
 
 func Migrate(db *pg.DB, actions ...string) (oldVersion, newVersion int64, err error) {
@@ -61,3 +69,269 @@ func Migrate(db *pg.DB, actions ...string) (oldVersion, newVersion int64, err er
 
 	return oldVersion, newVersion, nil
 }
+
+// Status returns current db version and the latest registered migration version
+func Status(db *pg.DB) (current, latest int64, err error) {
+	var exists bool
+	_, err = db.QueryOne(pg.Scan(&exists), `SELECT to_regclass('gopg_migrations') IS NOT NULL`)
+	if err != nil {
+		return 0, 0, fmt.Errorf("fail migration status: %w", err)
+	}
+
+	if exists {
+		current, err = migrations.Version(db)
+		if err != nil {
+			return 0, 0, fmt.Errorf("fail migration status: %w", err)
+		}
+	}
+
+	registered := migrations.RegisteredMigrations()
+	if len(registered) > 0 {
+		latest = registered[len(registered)-1].Version
+	}
+
+	return current, latest, nil
+}
+
+// DryRun prints SQL of the actions to w without changing db: migrations run against a recorder that prints
+// every query instead of executing it. Only the current version is read from db.
+// Queries return no rows, so a migration that reads data prints what it would run on empty tables
+func DryRun(db *pg.DB, w io.Writer, actions ...string) (oldVersion, newVersion int64, err error) {
+	action := ActionUp
+	if len(actions) >= 1 {
+		action = actions[0]
+	}
+
+	var exists bool
+	_, err = db.QueryOne(pg.Scan(&exists), `SELECT to_regclass('gopg_migrations') IS NOT NULL`)
+	if err != nil {
+		return 0, 0, fmt.Errorf("fail dry run: %w", err)
+	}
+
+	if exists {
+		oldVersion, err = migrations.Version(db)
+		if err != nil {
+			return 0, 0, fmt.Errorf("fail dry run: %w", err)
+		}
+	}
+
+	rec := &sqlRecorder{ctx: context.Background(), fmter: db.Formatter(), w: w}
+	if !exists {
+		fmt.Fprintln(w, "-- init")
+		_, err = rec.Exec(`CREATE TABLE IF NOT EXISTS gopg_migrations (id serial, version bigint, created_at timestamptz)`)
+		if err != nil {
+			return 0, 0, fmt.Errorf("fail dry run: %w", err)
+		}
+	}
+
+	registered := migrations.RegisteredMigrations()
+	newVersion = oldVersion
+
+	switch action {
+	case ActionUp:
+		target := int64(math.MaxInt64)
+		if len(actions) >= 2 {
+			target, err = strconv.ParseInt(actions[1], 10, 64)
+			if err != nil {
+				return 0, 0, fmt.Errorf("fail dry run: %w", err)
+			}
+		}
+
+		for _, m := range registered {
+			if m.Version <= newVersion || m.Version > target {
+				continue
+			}
+
+			fmt.Fprintf(w, "-- up %d\n", m.Version)
+			err = m.Up(rec)
+			if err != nil {
+				return 0, 0, fmt.Errorf("fail dry run %d: %w", m.Version, err)
+			}
+
+			newVersion = m.Version
+			err = migrations.SetVersion(rec, newVersion)
+			if err != nil {
+				return 0, 0, fmt.Errorf("fail dry run: %w", err)
+			}
+		}
+	case ActionDown:
+		newVersion, err = dryRunDown(rec, w, registered, oldVersion)
+	case ActionReset:
+		for {
+			version := newVersion
+			newVersion, err = dryRunDown(rec, w, registered, version)
+			if err != nil || newVersion == version {
+				break
+			}
+		}
+	case ActionVersion:
+	case ActionSetVersion:
+		if len(actions) < 2 {
+			return 0, 0, errors.New("fail dry run: set_version requires version")
+		}
+
+		newVersion, err = strconv.ParseInt(actions[1], 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("fail dry run: %w", err)
+		}
+		err = migrations.SetVersion(rec, newVersion)
+	default:
+		return 0, 0, fmt.Errorf("fail dry run: unsupported command %q", action)
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("fail dry run: %w", err)
+	}
+
+	return oldVersion, newVersion, nil
+}
+
+// dryRunDown reverts the last applied migration the same way as go-pg migrations do
+func dryRunDown(rec *sqlRecorder, w io.Writer, registered []*migrations.Migration, version int64) (int64, error) {
+	for i := len(registered) - 1; i >= 0; i-- {
+		m := registered[i]
+		if m.Version > version {
+			continue
+		}
+
+		fmt.Fprintf(w, "-- down %d\n", m.Version)
+		if m.Down != nil {
+			err := m.Down(rec)
+			if err != nil {
+				return version, fmt.Errorf("%d: %w", m.Version, err)
+			}
+		}
+
+		return m.Version - 1, migrations.SetVersion(rec, m.Version-1)
+	}
+
+	return version, nil
+}
+
+var errDryRunTransaction = errors.New("dry run does not support migrations in their own transaction")
+
+// sqlRecorder is a migrations.DB that prints formatted queries to w instead of executing them
+type sqlRecorder struct {
+	ctx   context.Context
+	fmter orm.QueryFormatter
+	w     io.Writer
+}
+
+var (
+	_ orm.DB        = (*sqlRecorder)(nil)
+	_ migrations.DB = (*sqlRecorder)(nil)
+)
+
+func (r *sqlRecorder) Model(model ...interface{}) *orm.Query {
+	return orm.NewQueryContext(r.ctx, r, model...)
+}
+
+func (r *sqlRecorder) ModelContext(c context.Context, model ...interface{}) *orm.Query {
+	return orm.NewQueryContext(c, r, model...)
+}
+
+func (r *sqlRecorder) Exec(query interface{}, params ...interface{}) (orm.Result, error) {
+	return r.record(query, params...)
+}
+
+func (r *sqlRecorder) ExecContext(_ context.Context, query interface{}, params ...interface{}) (orm.Result, error) {
+	return r.record(query, params...)
+}
+
+func (r *sqlRecorder) ExecOne(query interface{}, params ...interface{}) (orm.Result, error) {
+	return r.record(query, params...)
+}
+
+func (r *sqlRecorder) ExecOneContext(_ context.Context, query interface{}, params ...interface{}) (orm.Result, error) {
+	return r.record(query, params...)
+}
+
+func (r *sqlRecorder) Query(_, query interface{}, params ...interface{}) (orm.Result, error) {
+	return r.record(query, params...)
+}
+
+func (r *sqlRecorder) QueryContext(_ context.Context, _, query interface{}, params ...interface{}) (orm.Result, error) {
+	return r.record(query, params...)
+}
+
+func (r *sqlRecorder) QueryOne(_, query interface{}, params ...interface{}) (orm.Result, error) {
+	return r.record(query, params...)
+}
+
+func (r *sqlRecorder) QueryOneContext(_ context.Context, _, query interface{}, params ...interface{}) (orm.Result, error) {
+	return r.record(query, params...)
+}
+
+func (r *sqlRecorder) CopyFrom(_ io.Reader, query interface{}, params ...interface{}) (orm.Result, error) {
+	return r.record(query, params...)
+}
+
+func (r *sqlRecorder) CopyTo(_ io.Writer, query interface{}, params ...interface{}) (orm.Result, error) {
+	return r.record(query, params...)
+}
+
+func (r *sqlRecorder) Begin() (*pg.Tx, error) {
+	return nil, errDryRunTransaction
+}
+
+func (r *sqlRecorder) Context() context.Context {
+	return r.ctx
+}
+
+func (r *sqlRecorder) Formatter() orm.QueryFormatter {
+	return r.fmter
+}
+
+// record formats the query the same way go-pg does before sending it and prints it
+func (r *sqlRecorder) record(query interface{}, params ...interface{}) (orm.Result, error) {
+	var (
+		formatted []byte
+		err       error
+	)
+
+	fmter := r.fmter
+	switch query := query.(type) {
+	case orm.QueryAppender:
+		if f, ok := fmter.(*orm.Formatter); ok {
+			fmter = f.WithModel(query)
+		}
+		formatted, err = query.AppendQuery(fmter, nil)
+	case string:
+		if len(params) > 0 {
+			if model, ok := params[len(params)-1].(orm.TableModel); ok {
+				if f, ok := fmter.(*orm.Formatter); ok {
+					fmter = f.WithTableModel(model)
+					params = params[:len(params)-1]
+				}
+			}
+		}
+		formatted = fmter.FormatQuery(nil, query, params...)
+	default:
+		err = fmt.Errorf("can't format %T", query)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	formatted = bytes.TrimRight(bytes.TrimSpace(formatted), ";")
+	_, err = fmt.Fprintf(r.w, "%s;\n\n", formatted)
+	if err != nil {
+		return nil, err
+	}
+
+	return dryResult{}, nil
+}
+
+// dryResult is the result of a query that was not executed
+type dryResult struct{}
+
+func (dryResult) Model() orm.Model {
+	return nil
+}
+
+func (dryResult) RowsAffected() int {
+	return 0
+}
+
+func (dryResult) RowsReturned() int {
+	return 0
+}
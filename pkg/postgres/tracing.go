@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName = "transaction-system/pkg/postgres"
+	// maxStatementLength keeps spans small when a query carries a large batch of values
+	maxStatementLength = 4096
+)
+
+// TracingHook is a go-pg query hook that records every query as a client span.
+// Queries only join the caller's trace when they run with its context (ModelContext, QueryContext, RunInTransaction)
+type TracingHook struct{}
+
+var _ pg.QueryHook = TracingHook{}
+
+// BeforeQuery starts the query span. The name is set in AfterQuery from the query template
+func (TracingHook) BeforeQuery(ctx context.Context, _ *pg.QueryEvent) (context.Context, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	ctx, _ = otel.Tracer(tracerName).Start(ctx, "postgres", trace.WithSpanKind(trace.SpanKindClient))
+	return ctx, nil
+}
+
+// AfterQuery names the span after the SQL operation and the model table, records the statement and the error and ends it
+func (TracingHook) AfterQuery(ctx context.Context, evt *pg.QueryEvent) error {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		span.End()
+		return nil
+	}
+
+	// The statement is recorded as a template with ? placeholders: formatted queries carry key hashes,
+	// webhook secrets and card numbers that must not leave the service
+	statement, err := evt.UnformattedQuery()
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	query := string(statement)
+	operation := "QUERY"
+	if fields := strings.Fields(query); len(fields) > 0 {
+		operation = strings.ToUpper(fields[0])
+	}
+	name := operation
+	if table := tableName(evt); table != "" {
+		name += " " + table
+	}
+	span.SetName(name)
+
+	if len(query) > maxStatementLength {
+		query = query[:maxStatementLength]
+	}
+	span.SetAttributes(semconv.DBSystemPostgreSQL, semconv.DBOperation(operation), semconv.DBStatement(query))
+
+	if evt.Result != nil {
+		span.SetAttributes(attribute.Int("db.rows_affected", evt.Result.RowsAffected()))
+	}
+
+	// A missing row is an expected answer for lookups, not a failed query
+	if evt.Err != nil && !errors.Is(evt.Err, pg.ErrNoRows) {
+		span.RecordError(evt.Err)
+		span.SetStatus(codes.Error, evt.Err.Error())
+	}
+
+	span.End()
+	return nil
+}
+
+// tableName returns the table of an ORM query. ORM queries pass their table model either as the event model
+// or as the last parameter, raw SQL queries have none
+func tableName(evt *pg.QueryEvent) string {
+	candidates := append([]interface{}{evt.Model}, evt.Params...)
+	for _, candidate := range candidates {
+		if model, ok := candidate.(orm.TableModel); ok && model != nil && model.Table() != nil {
+			return strings.Trim(string(model.Table().SQLName), `"`)
+		}
+	}
+
+	return ""
+}
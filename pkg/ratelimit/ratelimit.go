@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Как часто из памяти удаляются полностью восстановленные корзины
+const sweepInterval = time.Minute
+
+// Rule - скорость пополнения корзины в запросах в секунду и ее емкость
+type Rule struct {
+	RPS   float64
+	Burst int
+}
+
+func (r Rule) capacity() float64 {
+	if r.Burst < 1 {
+		return 1
+	}
+
+	return float64(r.Burst)
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	rule   Rule
+}
+
+// Limiter - набор token bucket, по одной корзине на ключ
+type Limiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewLimiter() *Limiter {
+	return &Limiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// AllowAll списывает по токену из корзины каждого ключа, только если токены есть во всех корзинах.
+// Иначе ничего не списывается и возвращается индекс исчерпанной корзины и время до появления в ней токена.
+func (l *Limiter) AllowAll(keys []string, rule Rule) (bool, int, time.Duration) {
+	if rule.RPS <= 0 {
+		return true, -1, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	buckets := make([]*bucket, len(keys))
+	for i, key := range keys {
+		b := l.refill(key, rule, now)
+		if b.tokens < 1 {
+			wait := time.Duration((1 - b.tokens) / rule.RPS * float64(time.Second))
+			return false, i, wait
+		}
+		buckets[i] = b
+	}
+
+	for _, b := range buckets {
+		b.tokens--
+	}
+
+	return true, -1, 0
+}
+
+func (l *Limiter) refill(key string, rule Rule, now time.Time) *bucket {
+	burst := rule.capacity()
+
+	b, ok := l.buckets[key]
+	if !ok || b.rule != rule {
+		b = &bucket{tokens: burst, last: now, rule: rule}
+		l.buckets[key] = b
+		return b
+	}
+
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rule.RPS)
+	b.last = now
+
+	return b
+}
+
+// sweep удаляет корзины, которые успели наполниться: их состояние не отличается от новой корзины
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		full := b.tokens + now.Sub(b.last).Seconds()*b.rule.RPS
+		if full >= b.rule.capacity() {
+			delete(l.buckets, key)
+		}
+	}
+}
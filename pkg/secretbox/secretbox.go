@@ -0,0 +1,76 @@
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// Префикс версии шифротекста, чтобы формат можно было сменить, не теряя уже сохраненные значения
+const versionPrefix = "v1:"
+
+var (
+	ErrInvalidKey        = errors.New("encryption key must be 32 bytes encoded in base64")
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+)
+
+// Box шифрует секреты для хранения в БД AES-256-GCM ключом приложения
+type Box struct {
+	aead cipher.AEAD
+}
+
+// New принимает ключ из 32 байт в base64, например из `openssl rand -base64 32`
+func New(key string) (*Box, error) {
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(raw) != 32 {
+		return nil, ErrInvalidKey
+	}
+
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Box{aead: aead}, nil
+}
+
+// Seal возвращает "v1:" + base64(nonce + шифротекст)
+func (b *Box) Seal(plaintext string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+
+	sealed := b.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return versionPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open расшифровывает значение Seal. Значение, зашифрованное другим ключом или измененное, отклоняется
+func (b *Box) Open(ciphertext string) (string, error) {
+	encoded, ok := strings.CutPrefix(ciphertext, versionPrefix)
+	if !ok {
+		return "", ErrInvalidCiphertext
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < b.aead.NonceSize() {
+		return "", ErrInvalidCiphertext
+	}
+
+	nonce, data := sealed[:b.aead.NonceSize()], sealed[b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, data, nil)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+
+	return string(plaintext), nil
+}
@@ -0,0 +1,100 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+	"transaction-system/pkg/webhook"
+)
+
+func TestIsPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr   string
+		public bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"fd00::1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"0.0.0.0", false},
+		{"100.64.0.1", false},
+		{"198.18.0.1", false},
+		{"224.0.0.1", false},
+		{"64:ff9b::7f00:1", false},
+	}
+
+	for _, tt := range tests {
+		if got := webhook.IsPublicAddr(netip.MustParseAddr(tt.addr)); got != tt.public {
+			t.Errorf("IsPublicAddr(%s) = %v, want %v", tt.addr, got, tt.public)
+		}
+	}
+}
+
+func TestCheckHostLiteral(t *testing.T) {
+	ctx := context.Background()
+
+	if err := webhook.CheckHost(ctx, "93.184.216.34"); err != nil {
+		t.Fatalf("CheckHost(public) = %v", err)
+	}
+
+	for _, host := range []string{"127.0.0.1", "169.254.169.254", "::1"} {
+		if err := webhook.CheckHost(ctx, host); !errors.Is(err, webhook.ErrPrivateAddress) {
+			t.Fatalf("CheckHost(%s) = %v, want %v", host, err, webhook.ErrPrivateAddress)
+		}
+	}
+}
+
+func TestDialControl(t *testing.T) {
+	if err := webhook.DialControl("tcp4", "93.184.216.34:443", nil); err != nil {
+		t.Fatalf("DialControl(public) = %v", err)
+	}
+
+	for _, address := range []string{"127.0.0.1:80", "[::1]:443", "10.0.0.1:8080", "169.254.169.254:80"} {
+		if err := webhook.DialControl("tcp", address, nil); !errors.Is(err, webhook.ErrPrivateAddress) {
+			t.Fatalf("DialControl(%s) = %v, want %v", address, err, webhook.ErrPrivateAddress)
+		}
+	}
+}
+
+// Имя проверяется при создании подписки, но к моменту доставки DNS может вернуть loopback (DNS rebinding).
+// Dialer с DialControl проверяет адрес, к которому подключается, после разрешения имени
+func TestDialControlRefusesResolvedLoopback(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	accepted := make(chan struct{}, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			accepted <- struct{}{}
+			conn.Close()
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	dialer := &net.Dialer{Timeout: time.Second, Control: webhook.DialControl}
+
+	_, err = dialer.DialContext(context.Background(), "tcp4", net.JoinHostPort("localhost", port))
+	if !errors.Is(err, webhook.ErrPrivateAddress) {
+		t.Fatalf("dial localhost = %v, want %v", err, webhook.ErrPrivateAddress)
+	}
+
+	select {
+	case <-accepted:
+		t.Fatal("connection reached the loopback listener")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,60 @@
+package webhook_test
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+	"transaction-system/pkg/webhook"
+)
+
+const testSecret = "whsec_test"
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"id":"evt_1","type":"transaction.created"}`)
+	now := time.Now().Unix()
+	signature := webhook.Sign(testSecret, now, body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		signature string
+		body      []byte
+		want      error
+	}{
+		{"valid", testSecret, strconv.FormatInt(now, 10), signature, body, nil},
+		{"other secret", "whsec_other", strconv.FormatInt(now, 10), signature, body, webhook.ErrInvalidSignature},
+		{"changed body", testSecret, strconv.FormatInt(now, 10), signature, []byte(`{"id":"evt_2"}`), webhook.ErrInvalidSignature},
+		{"changed timestamp", testSecret, strconv.FormatInt(now+1, 10), signature, body, webhook.ErrInvalidSignature},
+		{"no prefix", testSecret, strconv.FormatInt(now, 10), signature[len("sha256="):], body, webhook.ErrInvalidSignature},
+		{"bad timestamp", testSecret, "yesterday", signature, body, webhook.ErrInvalidSignature},
+		{"expired", testSecret, strconv.FormatInt(now-600, 10), webhook.Sign(testSecret, now-600, body), body, webhook.ErrExpiredTimestamp},
+		{"from the future", testSecret, strconv.FormatInt(now+600, 10), webhook.Sign(testSecret, now+600, body), body, webhook.ErrExpiredTimestamp},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := webhook.Verify(tt.secret, tt.timestamp, tt.signature, tt.body, 5*time.Minute)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Verify() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// Нулевой tolerance отключает проверку времени, но не подписи
+func TestVerifyWithoutTolerance(t *testing.T) {
+	body := []byte(`{}`)
+	old := time.Now().Add(-24 * time.Hour).Unix()
+
+	err := webhook.Verify(testSecret, strconv.FormatInt(old, 10), webhook.Sign(testSecret, old, body), body, 0)
+	if err != nil {
+		t.Fatalf("Verify() = %v", err)
+	}
+
+	err = webhook.Verify(testSecret, strconv.FormatInt(old, 10), webhook.Sign("whsec_other", old, body), body, 0)
+	if !errors.Is(err, webhook.ErrInvalidSignature) {
+		t.Fatalf("Verify() = %v, want %v", err, webhook.ErrInvalidSignature)
+	}
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+	"transaction-system/internal/domain"
+	"transaction-system/pkg/secretbox"
+)
+
+const (
+	apiKeyIDPrefix  = "ak_"
+	apiKeyIDBytes   = 8
+	apiSecretBytes  = 32
+	apiKeyMaxLength = 100
+)
+
+var (
+	ErrInvalidAPIKeyName     = errors.New("api key name is required")
+	ErrEncryptionKeyRequired = errors.New("api keys require AUTH.API_KEYS.ENCRYPTION_KEY")
+	ErrAPIKeyNotEncrypted    = errors.New("api key signing key is stored in plaintext, run txadmin encrypt api-keys")
+)
+
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *domain.APIKeys) error
+	FindActiveAPIKey(ctx context.Context, keyID string) (*domain.APIKeys, error)
+	ListAPIKeys(ctx context.Context) ([]domain.APIKeys, error)
+	RevokeAPIKey(ctx context.Context, id int) error
+	UpdateAPIKeySecret(ctx context.Context, id int, secretEncrypted string) (*domain.APIKeys, error)
+	PlaintextAPIKeys(ctx context.Context) (map[int]string, error)
+	EncryptAPIKey(ctx context.Context, id int, secretEncrypted string) error
+	RememberAPIKeySignature(ctx context.Context, apiKeyID int, signature string, expiresAt time.Time) (bool, error)
+}
+
+type APIKeyWorker struct {
+	repo APIKeyRepository
+	box  *secretbox.Box
+}
+
+// NewAPIKeyWorker принимает ключ шифрования из AUTH.API_KEYS.ENCRYPTION_KEY. Без него список и отзыв ключей работают,
+// а выпуск и проверка подписи возвращают ErrEncryptionKeyRequired
+func NewAPIKeyWorker(repo APIKeyRepository, encryptionKey string) (*APIKeyWorker, error) {
+	aw := &APIKeyWorker{repo: repo}
+	if encryptionKey == "" {
+		return aw, nil
+	}
+
+	box, err := secretbox.New(encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	aw.box = box
+
+	return aw, nil
+}
+
+// IssueAPIKey создает ключ и возвращает секрет. Секрет показывается один раз, в БД остается только зашифрованный ключ подписи
+func (aw *APIKeyWorker) IssueAPIKey(ctx context.Context, name string) (*domain.APIKeys, string, error) {
+	if name == "" || len(name) > apiKeyMaxLength {
+		return nil, "", ErrInvalidAPIKeyName
+	}
+
+	keyID, err := randomHex(apiKeyIDBytes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret, encrypted, err := aw.newSecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := &domain.APIKeys{
+		KeyID:           apiKeyIDPrefix + keyID,
+		SecretEncrypted: encrypted,
+		Name:            name,
+		CreatedAt:       time.Now(),
+	}
+
+	err = aw.repo.CreateAPIKey(ctx, key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return key, secret, nil
+}
+
+// FindActiveAPIKey ищет неотозванный ключ и расшифровывает его ключ подписи в SigningKey
+func (aw *APIKeyWorker) FindActiveAPIKey(ctx context.Context, keyID string) (*domain.APIKeys, error) {
+	if aw.box == nil {
+		return nil, ErrEncryptionKeyRequired
+	}
+
+	key, err := aw.repo.FindActiveAPIKey(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.SecretEncrypted == "" {
+		return nil, ErrAPIKeyNotEncrypted
+	}
+
+	key.SigningKey, err = aw.box.Open(key.SecretEncrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// RememberSignature отмечает подпись запроса использованной до expiresAt. false - запрос с такой подписью уже был,
+// в том числе на другом экземпляре сервиса
+func (aw *APIKeyWorker) RememberSignature(ctx context.Context, apiKeyID int, signature string, expiresAt time.Time) (bool, error) {
+	return aw.repo.RememberAPIKeySignature(ctx, apiKeyID, signature, expiresAt)
+}
+
+func (aw *APIKeyWorker) ListAPIKeys(ctx context.Context) ([]domain.APIKeys, error) {
+	return aw.repo.ListAPIKeys(ctx)
+}
+
+func (aw *APIKeyWorker) RevokeAPIKey(ctx context.Context, id int) error {
+	return aw.repo.RevokeAPIKey(ctx, id)
+}
+
+// RotateAPIKey выпускает новый секрет для ключа, старый перестает действовать сразу.
+// Секрет показывается один раз, как и при выпуске ключа
+func (aw *APIKeyWorker) RotateAPIKey(ctx context.Context, id int) (*domain.APIKeys, string, error) {
+	secret, encrypted, err := aw.newSecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	key, err := aw.repo.UpdateAPIKeySecret(ctx, id, encrypted)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return key, secret, nil
+}
+
+// EncryptPlaintextAPIKeys шифрует ключи подписи, сохраненные открытыми до появления ENCRYPTION_KEY, и возвращает их число.
+// Секреты мерчантов при этом не меняются
+func (aw *APIKeyWorker) EncryptPlaintextAPIKeys(ctx context.Context) (int, error) {
+	if aw.box == nil {
+		return 0, ErrEncryptionKeyRequired
+	}
+
+	keys, err := aw.repo.PlaintextAPIKeys(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for id, signingKey := range keys {
+		encrypted, err := aw.box.Seal(signingKey)
+		if err != nil {
+			return 0, err
+		}
+
+		err = aw.repo.EncryptAPIKey(ctx, id, encrypted)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(keys), nil
+}
+
+// newSecret выпускает секрет для мерчанта и возвращает его вместе с зашифрованным ключом подписи для БД
+func (aw *APIKeyWorker) newSecret() (string, string, error) {
+	if aw.box == nil {
+		return "", "", ErrEncryptionKeyRequired
+	}
+
+	secret, err := randomHex(apiSecretBytes)
+	if err != nil {
+		return "", "", err
+	}
+
+	encrypted, err := aw.box.Seal(HashAPISecret(secret))
+	if err != nil {
+		return "", "", err
+	}
+
+	return secret, encrypted, nil
+}
+
+// HashAPISecret - hex(SHA-256(secret)), ключ HMAC-подписи запросов. Выданный мерчанту секрет нигде не сохраняется,
+// а ключ подписи хранится в БД только зашифрованным
+func HashAPISecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,322 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+	"transaction-system/config"
+	"transaction-system/internal/domain"
+)
+
+const (
+	defaultBatchMaxItems     = 100
+	defaultBatchConcurrency  = 8
+	defaultBatchWorkers      = 2
+	defaultBatchPollInterval = time.Second
+	// Пакет в Processing без обновлений дольше этого срока считается брошенным упавшим обработчиком
+	batchStaleAfter = 5 * time.Minute
+)
+
+var (
+	ErrIdempotencyKeyRequired = errors.New("idempotency key is required")
+	ErrIdempotencyKeyReused   = errors.New("idempotency key was used for another request")
+	ErrEmptyBatch             = errors.New("batch has no items")
+	ErrBatchTooLarge          = errors.New("batch has too many items")
+	ErrUnknownOperation       = errors.New("unknown batch operation")
+	ErrBatchNotOwned          = errors.New("batch belongs to another api key")
+)
+
+type BatchRepository interface {
+	CreateBatch(ctx context.Context, batch *domain.Batches) (*domain.Batches, bool, error)
+	GetBatch(ctx context.Context, id int) (*domain.Batches, error)
+	ClaimPendingBatch(ctx context.Context, staleAfter time.Duration) (*domain.Batches, error)
+	SettleBatchItem(ctx context.Context, batch *domain.Batches, item *domain.BatchItems) (*domain.Transactions, error)
+	UpdateBatchItem(ctx context.Context, item *domain.BatchItems) error
+	FinishBatch(ctx context.Context, batch *domain.Batches) error
+}
+
+// BatchWorker принимает пакеты и обрабатывает их в фоне, результат получают опросом GetBatch
+type BatchWorker struct {
+	repo         BatchRepository
+	validator    *DataBaseWorker
+	maxItems     int
+	concurrency  int
+	workers      int
+	pollInterval time.Duration
+	wake         chan struct{}
+	logger       *zap.Logger
+}
+
+func NewBatchWorker(repo BatchRepository, validator *DataBaseWorker, cfg *config.Config, logger *zap.Logger) *BatchWorker {
+	maxItems := cfg.Batches.MaxItems
+	if maxItems <= 0 {
+		maxItems = defaultBatchMaxItems
+	}
+
+	concurrency := cfg.Batches.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+
+	workers := cfg.Batches.Workers
+	if workers <= 0 {
+		workers = defaultBatchWorkers
+	}
+
+	pollInterval := time.Duration(cfg.Batches.PollInterval) * time.Millisecond
+	if pollInterval <= 0 {
+		pollInterval = defaultBatchPollInterval
+	}
+
+	return &BatchWorker{
+		repo:         repo,
+		validator:    validator,
+		maxItems:     maxItems,
+		concurrency:  concurrency,
+		workers:      workers,
+		pollInterval: pollInterval,
+		wake:         make(chan struct{}, 1),
+		logger:       logger,
+	}
+}
+
+// SubmitBatch сохраняет пакет в очередь, элементы обрабатывает Run.
+// Повтор с тем же ключом идемпотентности возвращает уже сохраненный пакет без повторного проведения операций,
+// второе значение в этом случае равно false. apiKeyID - ключ запроса, 0 без ключа
+func (bw *BatchWorker) SubmitBatch(ctx context.Context, apiKeyID int, idempotencyKey string, items []*domain.BatchItems) (*domain.Batches, bool, error) {
+	if idempotencyKey == "" {
+		return nil, false, ErrIdempotencyKeyRequired
+	}
+
+	if len(items) == 0 {
+		return nil, false, ErrEmptyBatch
+	}
+
+	if len(items) > bw.maxItems {
+		return nil, false, fmt.Errorf("%w: %d > %d", ErrBatchTooLarge, len(items), bw.maxItems)
+	}
+
+	for i, item := range items {
+		if item.Operation != domain.OperationInvoice && item.Operation != domain.OperationWithdraw {
+			return nil, false, fmt.Errorf("%w: %q", ErrUnknownOperation, item.Operation)
+		}
+
+		item.Position = i
+		item.Status = domain.BatchItemPending
+	}
+
+	hash, err := batchHash(items)
+	if err != nil {
+		return nil, false, err
+	}
+
+	batch := &domain.Batches{
+		IdempotencyKey: idempotencyKey,
+		RequestHash:    hash,
+		APIKeyID:       apiKeyID,
+		Status:         domain.BatchPending,
+		Total:          len(items),
+		Items:          items,
+	}
+
+	saved, created, err := bw.repo.CreateBatch(ctx, batch)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !created {
+		if saved.RequestHash != hash {
+			return nil, false, ErrIdempotencyKeyReused
+		}
+		return saved, false, nil
+	}
+
+	select {
+	case bw.wake <- struct{}{}:
+	default:
+	}
+
+	return saved, true, nil
+}
+
+// GetBatch возвращает пакет, только если он создан тем же API-ключом apiKeyID, что и запрос.
+// Запросу без ключа доступны все пакеты
+func (bw *BatchWorker) GetBatch(ctx context.Context, apiKeyID int, id int) (*domain.Batches, error) {
+	batch, err := bw.repo.GetBatch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if apiKeyID != 0 && batch.APIKeyID != apiKeyID {
+		return nil, ErrBatchNotOwned
+	}
+
+	return batch, nil
+}
+
+// Run обрабатывает пакеты из очереди, пока не отменен ctx
+func (bw *BatchWorker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < bw.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			bw.loop(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (bw *BatchWorker) loop(ctx context.Context) {
+	ticker := time.NewTicker(bw.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		batch, err := bw.repo.ClaimPendingBatch(ctx, batchStaleAfter)
+		if err != nil {
+			bw.logger.Error("Failed to claim batch", zap.Error(err))
+		}
+
+		if batch != nil {
+			err = bw.process(ctx, batch)
+			if err != nil {
+				// Пакет остается в Processing и будет забран повторно через batchStaleAfter
+				bw.logger.Error("Failed to process batch", zap.Int("batch_id", batch.ID), zap.Error(err))
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-bw.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// process проводит необработанные элементы пакета не более чем concurrency одновременно. Элементы, обработанные
+// до повторного захвата пакета, пропускаются. Начатый пакет доводится до конца и при отмене ctx
+func (bw *BatchWorker) process(ctx context.Context, batch *domain.Batches) error {
+	ctx = context.WithoutCancel(ctx)
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	sem := make(chan struct{}, bw.concurrency)
+
+	for _, item := range batch.Items {
+		if item.Status != domain.BatchItemPending {
+			continue
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+
+		go func(item *domain.BatchItems) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			err := bw.processItem(ctx, batch, item)
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(item)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	batch.Succeeded, batch.Failed = 0, 0
+	for _, item := range batch.Items {
+		if item.Status == domain.BatchItemSuccess {
+			batch.Succeeded++
+		} else {
+			batch.Failed++
+		}
+	}
+
+	switch {
+	case batch.Failed == 0:
+		batch.Status = domain.BatchCompleted
+	case batch.Succeeded == 0:
+		batch.Status = domain.BatchFailed
+	default:
+		batch.Status = domain.BatchPartial
+	}
+
+	return bw.repo.FinishBatch(ctx, batch)
+}
+
+// processItem проводит операцию элемента. Ошибка запроса, например нехватка средств, сохраняется в элементе как
+// окончательный результат. Временная ошибка, например недоступность БД, возвращается наружу: элемент остается
+// Pending, а пакет - Processing, и его повторно заберут через batchStaleAfter
+func (bw *BatchWorker) processItem(ctx context.Context, batch *domain.Batches, item *domain.BatchItems) error {
+	var transaction *domain.Transactions
+
+	err := bw.validator.ValidateOperation(item.CurrencyCode, item.Amount, item.WalletNumber, item.CardNumber)
+	if err == nil {
+		transaction, err = bw.repo.SettleBatchItem(ctx, batch, item)
+	}
+
+	if err != nil {
+		classified := ClassifyError(err)
+		if classified.Temporary() {
+			return fmt.Errorf("settle batch item %d: %w", item.ID, err)
+		}
+
+		item.Status = domain.BatchItemError
+		item.ErrorCode, item.Error = classified.Code, classified.Message
+	} else {
+		item.Status = domain.BatchItemSuccess
+		item.TransactionID = transaction.ID
+	}
+
+	return bw.repo.UpdateBatchItem(ctx, item)
+}
+
+// batchHash - отпечаток содержимого пакета для сравнения повторов с тем же ключом идемпотентности
+func batchHash(items []*domain.BatchItems) (string, error) {
+	type operation struct {
+		Operation    string  `json:"operation"`
+		CurrencyCode int     `json:"currency_code"`
+		Amount       float64 `json:"amount"`
+		WalletNumber int     `json:"wallet_number"`
+		CardNumber   int     `json:"card_number"`
+	}
+
+	operations := make([]operation, 0, len(items))
+	for _, item := range items {
+		operations = append(operations, operation{
+			Operation:    item.Operation,
+			CurrencyCode: item.CurrencyCode,
+			Amount:       item.Amount,
+			WalletNumber: item.WalletNumber,
+			CardNumber:   item.CardNumber,
+		})
+	}
+
+	data, err := json.Marshal(operations)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -1,29 +1,42 @@
 package service
 
 import (
-	"github.com/gin-gonic/gin"
+	"context"
+	"transaction-system/config"
 	"transaction-system/internal/domain"
 )
 
 type DataBaseRepository interface {
-	AddAmount(c *gin.Context, currencyCode int, amount float64, walletNumber int, cardNumber int) (*domain.Transactions, error)
-	WithdrawAmount(c *gin.Context, currencyCode int, amount float64, walletNumber int, cardNumber int) (*domain.Transactions, error)
-	GetAvailableBalance(c *gin.Context, walletNumber int, cardNumber int) (float64, error)
-	GetFrozenBalance(c *gin.Context, walletNumber int, cardNumber int) (float64, error)
+	AddAmount(ctx context.Context, currencyCode int, amount float64, walletNumber int, cardNumber int) (*domain.Transactions, error)
+	WithdrawAmount(ctx context.Context, currencyCode int, amount float64, walletNumber int, cardNumber int) (*domain.Transactions, error)
+	GetAvailableBalance(ctx context.Context, walletNumber int, cardNumber int) (float64, error)
+	GetFrozenBalance(ctx context.Context, walletNumber int, cardNumber int) (float64, error)
+	GetBalance(ctx context.Context, walletNumber int, cardNumber int) (*domain.Balance, error)
+	GetTransaction(ctx context.Context, id int) (*domain.Transactions, error)
+	ListTransactions(ctx context.Context, filter domain.TransactionFilter) ([]domain.Transactions, error)
+	RefundTransaction(ctx context.Context, id int) (*domain.Transactions, error)
+	CreateClient(ctx context.Context, client *domain.Clients) error
 }
 
 type DataBaseWorker struct {
-	repo DataBaseRepository
+	repo       DataBaseRepository
+	maxAmounts map[int]float64
 }
 
-func NewDataBaseWorker(repo DataBaseRepository) *DataBaseWorker {
+func NewDataBaseWorker(repo DataBaseRepository, cfg *config.Config) *DataBaseWorker {
 	return &DataBaseWorker{
-		repo: repo,
+		repo:       repo,
+		maxAmounts: cfg.Limits.MaxAmount,
 	}
 }
 
-func (dw *DataBaseWorker) AddAmountController(c *gin.Context, currencyCode int, amount float64, walletNumber int, cardNumber int) (*domain.Transactions, error) {
-	response, err := dw.repo.AddAmount(c, currencyCode, amount, walletNumber, cardNumber)
+func (dw *DataBaseWorker) AddAmountController(ctx context.Context, currencyCode int, amount float64, walletNumber int, cardNumber int) (*domain.Transactions, error) {
+	err := dw.ValidateOperation(currencyCode, amount, walletNumber, cardNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := dw.repo.AddAmount(ctx, currencyCode, amount, walletNumber, cardNumber)
 	if err != nil {
 		return nil, err
 	}
@@ -31,8 +44,13 @@ func (dw *DataBaseWorker) AddAmountController(c *gin.Context, currencyCode int,
 	return response, nil
 }
 
-func (dw *DataBaseWorker) WithdrawAmountController(c *gin.Context, currencyCode int, amount float64, walletNumber int, cardNumber int) (*domain.Transactions, error) {
-	response, err := dw.repo.WithdrawAmount(c, currencyCode, amount, walletNumber, cardNumber)
+func (dw *DataBaseWorker) WithdrawAmountController(ctx context.Context, currencyCode int, amount float64, walletNumber int, cardNumber int) (*domain.Transactions, error) {
+	err := dw.ValidateOperation(currencyCode, amount, walletNumber, cardNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := dw.repo.WithdrawAmount(ctx, currencyCode, amount, walletNumber, cardNumber)
 	if err != nil {
 		return nil, err
 	}
@@ -40,8 +58,8 @@ func (dw *DataBaseWorker) WithdrawAmountController(c *gin.Context, currencyCode
 	return response, nil
 }
 
-func (dw *DataBaseWorker) GetAvailableBalanceController(c *gin.Context, walletNumber int, cardNumber int) (float64, error) {
-	response, err := dw.repo.GetAvailableBalance(c, walletNumber, cardNumber)
+func (dw *DataBaseWorker) GetAvailableBalanceController(ctx context.Context, walletNumber int, cardNumber int) (float64, error) {
+	response, err := dw.repo.GetAvailableBalance(ctx, walletNumber, cardNumber)
 	if err != nil {
 		return 0, err
 	}
@@ -49,11 +67,60 @@ func (dw *DataBaseWorker) GetAvailableBalanceController(c *gin.Context, walletNu
 	return response, nil
 }
 
-func (dw *DataBaseWorker) GetFrozenBalanceController(c *gin.Context, walletNumber int, cardNumber int) (float64, error) {
-	response, err := dw.repo.GetFrozenBalance(c, walletNumber, cardNumber)
+func (dw *DataBaseWorker) GetFrozenBalanceController(ctx context.Context, walletNumber int, cardNumber int) (float64, error) {
+	response, err := dw.repo.GetFrozenBalance(ctx, walletNumber, cardNumber)
 	if err != nil {
 		return 0, err
 	}
 
 	return response, nil
 }
+
+func (dw *DataBaseWorker) GetBalanceController(ctx context.Context, walletNumber int, cardNumber int) (*domain.Balance, error) {
+	response, err := dw.repo.GetBalance(ctx, walletNumber, cardNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (dw *DataBaseWorker) GetTransactionController(ctx context.Context, id int) (*domain.Transactions, error) {
+	response, err := dw.repo.GetTransaction(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (dw *DataBaseWorker) ListTransactionsController(ctx context.Context, filter domain.TransactionFilter) ([]domain.Transactions, error) {
+	response, err := dw.repo.ListTransactions(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// RefundTransactionController возвращает транзакцию целиком. Возврат зачисления уменьшает баланс клиента,
+// поэтому он не пройдет, если средств уже не хватает
+func (dw *DataBaseWorker) RefundTransactionController(ctx context.Context, id int) (*domain.Transactions, error) {
+	response, err := dw.repo.RefundTransaction(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (dw *DataBaseWorker) CreateClientController(ctx context.Context, walletNumber int, cardNumber int) (*domain.Clients, error) {
+	client := &domain.Clients{WalletNumber: walletNumber, CardNumber: cardNumber}
+
+	err := dw.repo.CreateClient(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
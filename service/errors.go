@@ -0,0 +1,131 @@
+package service
+
+import (
+	"errors"
+	"transaction-system/storage"
+)
+
+var ErrInvalidAmount = errors.New("amount must be greater than zero")
+
+// Коды ошибок. Их отдают HTTP и gRPC API и сохраняют результаты фоновых операций, клиенты должны опираться на них,
+// а не на текст сообщения
+const (
+	CodeInvalidAmount       = "invalid_amount"
+	CodeClientNotFound      = "client_not_found"
+	CodeCurrencyNotFound    = "currency_not_found"
+	CodeInsufficientFunds   = "insufficient_funds"
+	CodeValidationFailed    = "validation_failed"
+	CodeRequired            = "required"
+	CodeMissingRequisites   = "missing_requisites"
+	CodeAmountLimit         = "amount_limit_exceeded"
+	CodeAPIKeyNotFound      = "api_key_not_found"
+	CodeBatchNotFound       = "batch_not_found"
+	CodeOperationNotFound   = "operation_not_found"
+	CodeBatchTooLarge       = "batch_too_large"
+	CodeInvalidOperation    = "invalid_operation"
+	CodeIdempotencyKey      = "idempotency_key_required"
+	CodeIdempotencyReused   = "idempotency_key_reused"
+	CodeWebhookNotFound     = "webhook_not_found"
+	CodeDeliveryNotFound    = "webhook_delivery_not_found"
+	CodeInvalidURL          = "invalid_url"
+	CodeInvalidEvent        = "invalid_event"
+	CodeTransactionNotFound = "transaction_not_found"
+	CodeClientExists        = "client_exists"
+	CodeAlreadyRefunded     = "already_refunded"
+	CodeNotRefundable       = "not_refundable"
+	CodeMultipleCurrencies  = "multiple_currencies"
+	CodeForbidden           = "forbidden"
+	CodeInternal            = "internal_error"
+)
+
+// ErrorKind - вид ошибки, по нему транспорт выбирает HTTP-статус или код gRPC
+type ErrorKind int
+
+const (
+	// KindInternal - ошибка не связана с содержимым запроса, операцию имеет смысл повторить
+	KindInternal ErrorKind = iota
+	KindInvalidArgument
+	// KindMissingArgument - в запросе нет обязательного параметра, например ключа идемпотентности
+	KindMissingArgument
+	KindNotFound
+	KindConflict
+	KindPermissionDenied
+)
+
+// ClassifiedError - стабильный код и текст ошибки для ответа API и результата фоновой операции
+type ClassifiedError struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+}
+
+// Temporary сообщает, что операцию с этой ошибкой можно повторить
+func (e ClassifiedError) Temporary() bool {
+	return e.Kind == KindInternal
+}
+
+var errorClasses = []struct {
+	err error
+	ClassifiedError
+}{
+	{storage.ErrClientNotFound, ClassifiedError{KindNotFound, CodeClientNotFound, "Client not found"}},
+	{storage.ErrCurrencyNotFound, ClassifiedError{KindNotFound, CodeCurrencyNotFound, "Currency not found"}},
+	{storage.ErrTransactionNotFound, ClassifiedError{KindNotFound, CodeTransactionNotFound, "Transaction not found"}},
+	{storage.ErrClientExists, ClassifiedError{KindConflict, CodeClientExists, "Wallet or card already belongs to a client"}},
+	{storage.ErrAlreadyRefunded, ClassifiedError{KindConflict, CodeAlreadyRefunded, "Transaction has already been refunded"}},
+	{storage.ErrNotRefundable, ClassifiedError{KindInvalidArgument, CodeNotRefundable, "Only settled transactions that are not refunds can be refunded"}},
+	{storage.ErrMultipleCurrencies, ClassifiedError{KindConflict, CodeMultipleCurrencies, "Client has balances in several currencies, use GET /v1/wallets/{wallet_number}/balance"}},
+	{storage.ErrInsufficientFunds, ClassifiedError{KindConflict, CodeInsufficientFunds, "Insufficient funds"}},
+	{storage.ErrAPIKeyNotFound, ClassifiedError{KindNotFound, CodeAPIKeyNotFound, "API key not found"}},
+	{ErrInvalidAPIKeyName, ClassifiedError{KindInvalidArgument, CodeRequired, "API key name is required"}},
+	{storage.ErrBatchNotFound, ClassifiedError{KindNotFound, CodeBatchNotFound, "Batch not found"}},
+	{ErrBatchNotOwned, ClassifiedError{KindNotFound, CodeBatchNotFound, "Batch not found"}},
+	{storage.ErrOperationNotFound, ClassifiedError{KindNotFound, CodeOperationNotFound, "Operation not found"}},
+	{ErrOperationNotOwned, ClassifiedError{KindNotFound, CodeOperationNotFound, "Operation not found"}},
+	{ErrBatchTooLarge, ClassifiedError{KindInvalidArgument, CodeBatchTooLarge, "Batch has too many items"}},
+	{ErrEmptyBatch, ClassifiedError{KindInvalidArgument, CodeRequired, "Batch has no items"}},
+	{ErrUnknownOperation, ClassifiedError{KindInvalidArgument, CodeInvalidOperation, "Operation must be invoice or withdraw"}},
+	{ErrIdempotencyKeyRequired, ClassifiedError{KindMissingArgument, CodeIdempotencyKey, "Idempotency-Key header is required"}},
+	{storage.ErrWebhookNotFound, ClassifiedError{KindNotFound, CodeWebhookNotFound, "Webhook not found"}},
+	{ErrWebhookNotOwned, ClassifiedError{KindNotFound, CodeWebhookNotFound, "Webhook not found"}},
+	{storage.ErrWebhookDeliveryNotFound, ClassifiedError{KindNotFound, CodeDeliveryNotFound, "Webhook delivery not found or is being sent"}},
+	{ErrInvalidWebhookURL, ClassifiedError{KindInvalidArgument, CodeInvalidURL, "Webhook url must be an absolute http or https url"}},
+	{ErrPrivateWebhookURL, ClassifiedError{KindInvalidArgument, CodeInvalidURL, "Webhook url must resolve to a public address"}},
+	{ErrUnknownWebhookEvent, ClassifiedError{KindInvalidArgument, CodeInvalidEvent, "Unknown webhook event"}},
+	{ErrWalletWebhookDenied, ClassifiedError{KindPermissionDenied, CodeForbidden, "Wallet webhooks require the wallet's client token or an admin"}},
+	{ErrWebhookOwnerRequired, ClassifiedError{KindInvalidArgument, CodeMissingRequisites, "wallet_number is required without an api key"}},
+	{ErrIdempotencyKeyReused, ClassifiedError{KindConflict, CodeIdempotencyReused, "Idempotency-Key was already used for another request"}},
+}
+
+var fieldErrorCodes = map[error]string{
+	ErrCurrencyRequired:    CodeRequired,
+	ErrInvalidAmount:       CodeInvalidAmount,
+	ErrAmountLimitExceeded: CodeAmountLimit,
+	ErrMissingRequisites:   CodeMissingRequisites,
+}
+
+// Code возвращает код нарушения поля
+func (f FieldError) Code() string {
+	return fieldErrorCodes[f.Err]
+}
+
+// ClassifyError возвращает вид, код и текст ошибки. Ошибка проверки с одним нарушением получает код этого поля.
+// Неизвестные ошибки относятся к KindInternal
+func ClassifyError(err error) ClassifiedError {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		if len(validationErr.Fields) == 1 {
+			f := validationErr.Fields[0]
+			return ClassifiedError{Kind: KindInvalidArgument, Code: f.Code(), Message: f.Field + ": " + f.Message}
+		}
+		return ClassifiedError{Kind: KindInvalidArgument, Code: CodeValidationFailed, Message: err.Error()}
+	}
+
+	for _, c := range errorClasses {
+		if errors.Is(err, c.err) {
+			return c.ClassifiedError
+		}
+	}
+
+	return ClassifiedError{Kind: KindInternal, Code: CodeInternal, Message: "Operation failed"}
+}
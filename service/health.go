@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+	"transaction-system/config"
+)
+
+const defaultHealthTimeout = 2 * time.Second
+
+// Статусы компонента в отчете о готовности
+const (
+	HealthUp   = "up"
+	HealthDown = "down"
+)
+
+// HealthCheck - проверка одной зависимости сервиса, nil - зависимость готова
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+type ComponentHealth struct {
+	Name    string
+	Status  string
+	Latency time.Duration
+	Error   string
+}
+
+// HealthReport - результат всех проверок в порядке их регистрации. Ready равно true, если все компоненты up
+type HealthReport struct {
+	Ready      bool
+	Components []ComponentHealth
+}
+
+// HealthChecker выполняет проверки готовности параллельно, каждую не дольше HEALTH.TIMEOUT
+type HealthChecker struct {
+	checks  []HealthCheck
+	timeout time.Duration
+}
+
+func NewHealthChecker(cfg *config.Config, checks ...HealthCheck) *HealthChecker {
+	timeout := time.Duration(cfg.Health.Timeout) * time.Millisecond
+	if timeout <= 0 {
+		timeout = defaultHealthTimeout
+	}
+
+	return &HealthChecker{checks: checks, timeout: timeout}
+}
+
+func (hc *HealthChecker) Ready(ctx context.Context) HealthReport {
+	report := HealthReport{Ready: true, Components: make([]ComponentHealth, len(hc.checks))}
+
+	var wg sync.WaitGroup
+	for i, check := range hc.checks {
+		wg.Add(1)
+		go func(i int, check HealthCheck) {
+			defer wg.Done()
+			report.Components[i] = hc.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	for _, component := range report.Components {
+		if component.Status != HealthUp {
+			report.Ready = false
+		}
+	}
+
+	return report
+}
+
+func (hc *HealthChecker) run(ctx context.Context, check HealthCheck) ComponentHealth {
+	ctx, cancel := context.WithTimeout(ctx, hc.timeout)
+	defer cancel()
+
+	// Проверка, которая не уважает ctx, не должна задерживать ответ дольше таймаута
+	done := make(chan error, 1)
+	start := time.Now()
+	go func() {
+		done <- check.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	component := ComponentHealth{Name: check.Name, Status: HealthUp, Latency: time.Since(start)}
+	if err != nil {
+		component.Status = HealthDown
+		component.Error = err.Error()
+	}
+
+	return component
+}
@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"go.uber.org/zap"
+	"math"
+	"sync"
+	"time"
+	"transaction-system/config"
+	"transaction-system/internal/domain"
+)
+
+const (
+	defaultOperationWorkers      = 4
+	defaultOperationPollInterval = time.Second
+	defaultOperationMaxAttempts  = 5
+	operationRetryBackoff        = time.Second
+	operationMaxBackoff          = time.Minute
+	// Операция в Processing дольше этого срока считается брошенной упавшим обработчиком
+	operationStaleAfter = 5 * time.Minute
+)
+
+var ErrOperationNotOwned = errors.New("operation belongs to another api key")
+
+type OperationRepository interface {
+	CreateOperation(ctx context.Context, operation *domain.Operations) (*domain.Operations, bool, error)
+	DeleteOperation(ctx context.Context, id int) error
+	GetOperation(ctx context.Context, id int) (*domain.Operations, error)
+	GetTransaction(ctx context.Context, id int) (*domain.Transactions, error)
+	ClaimPendingOperation(ctx context.Context, staleAfter time.Duration) (*domain.Operations, error)
+	SettleOperation(ctx context.Context, operation *domain.Operations) (*domain.Transactions, error)
+	FinishOperation(ctx context.Context, operation *domain.Operations) error
+}
+
+// OperationWorker принимает операции в асинхронном режиме и проводит их в фоне,
+// чтобы задержки БД и брокера не превращались в задержку ответа API
+type OperationWorker struct {
+	repo         OperationRepository
+	validator    *DataBaseWorker
+	workers      int
+	pollInterval time.Duration
+	maxAttempts  int
+	wake         chan struct{}
+	logger       *zap.Logger
+}
+
+func NewOperationWorker(repo OperationRepository, validator *DataBaseWorker, cfg *config.Config, logger *zap.Logger) *OperationWorker {
+	workers := cfg.Async.Workers
+	if workers <= 0 {
+		workers = defaultOperationWorkers
+	}
+
+	pollInterval := time.Duration(cfg.Async.PollInterval) * time.Millisecond
+	if pollInterval <= 0 {
+		pollInterval = defaultOperationPollInterval
+	}
+
+	maxAttempts := cfg.Async.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultOperationMaxAttempts
+	}
+
+	return &OperationWorker{
+		repo:         repo,
+		validator:    validator,
+		workers:      workers,
+		pollInterval: pollInterval,
+		maxAttempts:  maxAttempts,
+		wake:         make(chan struct{}, 1),
+		logger:       logger,
+	}
+}
+
+// EnqueueOperation проверяет операцию и сохраняет ее в очередь. Проведение выполняет Run.
+// Повтор с тем же ключом идемпотентности возвращает уже принятую операцию. apiKeyID - ключ запроса, 0 без ключа
+func (ow *OperationWorker) EnqueueOperation(ctx context.Context, apiKeyID int, idempotencyKey string, operation string, currencyCode int, amount float64, walletNumber int, cardNumber int) (*domain.Operations, error) {
+	op, created, err := ow.createOperation(ctx, apiKeyID, idempotencyKey, domain.OperationPending, operation, currencyCode, amount, walletNumber, cardNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	if created {
+		select {
+		case ow.wake <- struct{}{}:
+		default:
+		}
+	}
+
+	return op, nil
+}
+
+// PerformOperation проводит операцию с ключом идемпотентности синхронно. Повтор с тем же ключом возвращает
+// транзакцию первого запроса, второе значение в этом случае равно false. Если операция не проведена,
+// она удаляется, и повтор с тем же ключом проведет ее заново
+func (ow *OperationWorker) PerformOperation(ctx context.Context, apiKeyID int, idempotencyKey string, operation string, currencyCode int, amount float64, walletNumber int, cardNumber int) (*domain.Transactions, bool, error) {
+	// Операция сразу создается в Processing, чтобы ее не забрали обработчики очереди. Если сервис упадет
+	// посреди запроса, они доведут ее, как зависшую асинхронную операцию
+	op, created, err := ow.createOperation(ctx, apiKeyID, idempotencyKey, domain.OperationProcessing, operation, currencyCode, amount, walletNumber, cardNumber)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if op.Status == domain.OperationCompleted {
+		transaction, err := ow.repo.GetTransaction(ctx, op.TransactionID)
+		if err != nil {
+			return nil, false, err
+		}
+		return transaction, false, nil
+	}
+
+	// Незавершенный повтор проводится еще раз: уникальный operation_id не даст создать вторую транзакцию
+	op.Attempts++
+	transaction, err := ow.repo.SettleOperation(ctx, op)
+	if err != nil {
+		deleteErr := ow.repo.DeleteOperation(context.WithoutCancel(ctx), op.ID)
+		if deleteErr != nil {
+			ow.logger.Error("Failed to release operation", zap.Int("operation_id", op.ID), zap.Error(deleteErr))
+		}
+		return nil, false, err
+	}
+
+	op.Status = domain.OperationCompleted
+	op.TransactionID = transaction.ID
+	op.ErrorCode, op.Error = "", ""
+	op.NextAttemptAt = nil
+
+	err = ow.repo.FinishOperation(context.WithoutCancel(ctx), op)
+	if err != nil {
+		ow.logger.Error("Failed to save operation result", zap.Int("operation_id", op.ID), zap.Error(err))
+	}
+
+	return transaction, created, nil
+}
+
+// createOperation проверяет и сохраняет операцию. Если операция с этим ключом уже есть, она возвращается,
+// а второе значение равно false. Другое содержимое с тем же ключом отклоняется
+func (ow *OperationWorker) createOperation(ctx context.Context, apiKeyID int, idempotencyKey string, status string, operation string, currencyCode int, amount float64, walletNumber int, cardNumber int) (*domain.Operations, bool, error) {
+	err := ow.validator.ValidateOperation(currencyCode, amount, walletNumber, cardNumber)
+	if err != nil {
+		return nil, false, err
+	}
+
+	op := &domain.Operations{
+		IdempotencyKey: idempotencyKey,
+		Operation:      operation,
+		CurrencyCode:   currencyCode,
+		Amount:         amount,
+		WalletNumber:   walletNumber,
+		CardNumber:     cardNumber,
+		APIKeyID:       apiKeyID,
+		Status:         status,
+		CreatedAt:      time.Now(),
+	}
+
+	if idempotencyKey != "" {
+		op.RequestHash, err = operationHash(op)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	saved, created, err := ow.repo.CreateOperation(ctx, op)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !created && saved.RequestHash != op.RequestHash {
+		return nil, false, ErrIdempotencyKeyReused
+	}
+
+	return saved, created, nil
+}
+
+// GetOperation возвращает операцию, только если она принята с тем же API-ключом apiKeyID, что и запрос.
+// Запросу без ключа доступны все операции
+func (ow *OperationWorker) GetOperation(ctx context.Context, apiKeyID int, id int) (*domain.Operations, error) {
+	op, err := ow.repo.GetOperation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if apiKeyID != 0 && op.APIKeyID != apiKeyID {
+		return nil, ErrOperationNotOwned
+	}
+
+	return op, nil
+}
+
+// Run проводит операции из очереди, пока не отменен ctx
+func (ow *OperationWorker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < ow.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ow.loop(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (ow *OperationWorker) loop(ctx context.Context) {
+	ticker := time.NewTicker(ow.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		op, err := ow.repo.ClaimPendingOperation(ctx, operationStaleAfter)
+		if err != nil {
+			ow.logger.Error("Failed to claim operation", zap.Error(err))
+		}
+
+		if op != nil {
+			ow.settle(ctx, op)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ow.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// settle проводит операцию. Ошибки в содержимом операции завершают ее со статусом Failed,
+// временные ошибки возвращают ее в очередь с экспоненциальной задержкой, пока не кончатся попытки
+func (ow *OperationWorker) settle(ctx context.Context, op *domain.Operations) {
+	op.Attempts++
+
+	transaction, err := ow.repo.SettleOperation(ctx, op)
+	switch {
+	case err == nil:
+		op.Status = domain.OperationCompleted
+		op.TransactionID = transaction.ID
+		op.ErrorCode, op.Error = "", ""
+		op.NextAttemptAt = nil
+	default:
+		classified := ClassifyError(err)
+		op.ErrorCode, op.Error = classified.Code, classified.Message
+		if classified.Temporary() && op.Attempts < ow.maxAttempts {
+			next := time.Now().Add(operationBackoff(op.Attempts))
+			op.Status = domain.OperationPending
+			op.NextAttemptAt = &next
+		} else {
+			op.Status = domain.OperationFailed
+			op.NextAttemptAt = nil
+		}
+		ow.logger.Warn("Failed to settle operation", zap.Int("operation_id", op.ID), zap.Int("attempt", op.Attempts), zap.Error(err))
+	}
+
+	err = ow.repo.FinishOperation(context.WithoutCancel(ctx), op)
+	if err != nil {
+		ow.logger.Error("Failed to save operation result", zap.Int("operation_id", op.ID), zap.Error(err))
+	}
+}
+
+// operationHash - отпечаток содержимого операции для сравнения повторов с тем же ключом идемпотентности
+func operationHash(op *domain.Operations) (string, error) {
+	data, err := json.Marshal(struct {
+		Operation    string  `json:"operation"`
+		CurrencyCode int     `json:"currency_code"`
+		Amount       float64 `json:"amount"`
+		WalletNumber int     `json:"wallet_number"`
+		CardNumber   int     `json:"card_number"`
+	}{op.Operation, op.CurrencyCode, op.Amount, op.WalletNumber, op.CardNumber})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func operationBackoff(attempt int) time.Duration {
+	backoff := time.Duration(float64(operationRetryBackoff) * math.Pow(2, float64(attempt-1)))
+	if backoff > operationMaxBackoff {
+		return operationMaxBackoff
+	}
+
+	return backoff
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"sync"
+	"transaction-system/config"
+	"transaction-system/internal/domain"
+	"transaction-system/pkg/eventbus"
+)
+
+const (
+	defaultStreamBufferSize = 64
+	// Сколько событий читается из журнала за один запрос при возобновлении потока
+	streamReplayPage = 500
+)
+
+type StreamRepository interface {
+	FindClient(ctx context.Context, walletNumber int, cardNumber int) (*domain.Clients, error)
+	ListClientEvents(ctx context.Context, clientID int, after int64, limit int) ([]domain.TransactionEvent, error)
+}
+
+// Subscription - подписка на события одного клиента. Events закрывается, когда подписка отменена
+// или подписчик не успевает забирать события
+type Subscription struct {
+	ClientID int
+	Events   <-chan domain.TransactionEvent
+
+	events chan domain.TransactionEvent
+	hub    *StreamHub
+	once   sync.Once
+}
+
+// Close отменяет подписку, повторный вызов ничего не делает
+func (s *Subscription) Close() {
+	s.hub.unsubscribe(s)
+}
+
+// StreamHub раздает события о транзакциях из шины подписчикам потока кошелька
+type StreamHub struct {
+	repo        StreamRepository
+	balances    *DataBaseWorker
+	bufferSize  int
+	mu          sync.RWMutex
+	subscribers map[int]map[*Subscription]struct{}
+	logger      *zap.Logger
+}
+
+func NewStreamHub(repo StreamRepository, balances *DataBaseWorker, cfg *config.Config, logger *zap.Logger) *StreamHub {
+	bufferSize := cfg.Stream.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultStreamBufferSize
+	}
+
+	return &StreamHub{
+		repo:        repo,
+		balances:    balances,
+		bufferSize:  bufferSize,
+		subscribers: make(map[int]map[*Subscription]struct{}),
+		logger:      logger,
+	}
+}
+
+// Subscribe подписывает на события владельца кошелька. Подписку нужно закрыть через Close
+func (h *StreamHub) Subscribe(ctx context.Context, walletNumber int) (*Subscription, error) {
+	client, err := h.repo.FindClient(ctx, walletNumber, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan domain.TransactionEvent, h.bufferSize)
+	sub := &Subscription{
+		ClientID: client.ID,
+		Events:   events,
+		events:   events,
+		hub:      h,
+	}
+
+	h.mu.Lock()
+	if h.subscribers[client.ID] == nil {
+		h.subscribers[client.ID] = make(map[*Subscription]struct{})
+	}
+	h.subscribers[client.ID][sub] = struct{}{}
+	h.mu.Unlock()
+
+	return sub, nil
+}
+
+// Replay вызывает send для каждого события клиента с номером больше after в порядке номеров
+// и возвращает номер последнего отправленного события
+func (h *StreamHub) Replay(ctx context.Context, sub *Subscription, after int64, send func(domain.TransactionEvent) error) (int64, error) {
+	for {
+		events, err := h.repo.ListClientEvents(ctx, sub.ClientID, after, streamReplayPage)
+		if err != nil {
+			return after, err
+		}
+
+		for _, event := range events {
+			err = send(event)
+			if err != nil {
+				return after, err
+			}
+			after = event.Sequence
+		}
+
+		if len(events) < streamReplayPage {
+			return after, nil
+		}
+	}
+}
+
+func (h *StreamHub) Balance(ctx context.Context, walletNumber int) (*domain.Balance, error) {
+	return h.balances.GetBalanceController(ctx, walletNumber, 0)
+}
+
+// HandleEvent - обработчик шины. Событие отправляется всем подписчикам клиента, подписчик с заполненным
+// буфером отключается: он переподключится с Last-Event-ID и дочитает пропущенное из журнала
+func (h *StreamHub) HandleEvent(_ context.Context, m eventbus.Message) error {
+	event := domain.TransactionEvent{}
+	err := json.Unmarshal(m.Value, &event)
+	if err != nil {
+		return fmt.Errorf("decode transaction event: %w", err)
+	}
+
+	if event.Transaction == nil {
+		return nil
+	}
+
+	var slow []*Subscription
+
+	h.mu.RLock()
+	for sub := range h.subscribers[event.ClientID] {
+		select {
+		case sub.events <- event:
+		default:
+			slow = append(slow, sub)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, sub := range slow {
+		h.logger.Warn("Dropping slow stream subscriber", zap.Int("client_id", sub.ClientID))
+		h.unsubscribe(sub)
+	}
+
+	return nil
+}
+
+func (h *StreamHub) unsubscribe(sub *Subscription) {
+	sub.once.Do(func() {
+		h.mu.Lock()
+		delete(h.subscribers[sub.ClientID], sub)
+		if len(h.subscribers[sub.ClientID]) == 0 {
+			delete(h.subscribers, sub.ClientID)
+		}
+		h.mu.Unlock()
+
+		close(sub.events)
+	})
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+var (
+	ErrValidation          = errors.New("validation failed")
+	ErrCurrencyRequired    = errors.New("currency code is required")
+	ErrMissingRequisites   = errors.New("wallet number or card number is required")
+	ErrAmountLimitExceeded = errors.New("amount exceeds the limit for currency")
+)
+
+// FieldError описывает нарушение правила для конкретного поля запроса
+type FieldError struct {
+	Field   string
+	Message string
+	Err     error
+}
+
+// ValidationError содержит все нарушения, найденные в операции.
+// errors.Is срабатывает как для ErrValidation, так и для причины каждого поля.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		messages = append(messages, f.Field+": "+f.Message)
+	}
+
+	return ErrValidation.Error() + ": " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Unwrap() []error {
+	errs := []error{ErrValidation}
+	for _, f := range e.Fields {
+		errs = append(errs, f.Err)
+	}
+
+	return errs
+}
+
+// ValidateOperation проверяет зачисление или списание по тем же правилам, что и HTTP-слой,
+// чтобы их нельзя было обойти при вызове сервиса напрямую
+func (dw *DataBaseWorker) ValidateOperation(currencyCode int, amount float64, walletNumber int, cardNumber int) error {
+	var fields []FieldError
+
+	if currencyCode == 0 {
+		fields = append(fields, FieldError{Field: "currency_code", Message: "is required", Err: ErrCurrencyRequired})
+	}
+
+	// NaN не меньше нуля и не больше лимита, поэтому проверяем его и бесконечность отдельно
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		fields = append(fields, FieldError{Field: "amount", Message: "must be a finite number", Err: ErrInvalidAmount})
+	} else if amount <= 0 {
+		fields = append(fields, FieldError{Field: "amount", Message: "must be greater than zero", Err: ErrInvalidAmount})
+	} else if limit := dw.maxAmounts[currencyCode]; limit > 0 && amount > limit {
+		fields = append(fields, FieldError{Field: "amount", Message: fmt.Sprintf("must not exceed %v", limit), Err: ErrAmountLimitExceeded})
+	}
+
+	if walletNumber == 0 && cardNumber == 0 {
+		fields = append(fields, FieldError{Field: "wallet_number", Message: "wallet_number or card_number is required", Err: ErrMissingRequisites})
+	}
+
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+
+	return nil
+}
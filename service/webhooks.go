@@ -0,0 +1,430 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+	"transaction-system/config"
+	"transaction-system/internal/domain"
+	"transaction-system/pkg/webhook"
+)
+
+const (
+	webhookSecretPrefix = "whsec_"
+	webhookSecretBytes  = 32
+
+	defaultWebhookWorkers        = 2
+	defaultWebhookPollInterval   = time.Second
+	defaultWebhookTimeout        = 10 * time.Second
+	defaultWebhookMaxAttempts    = 8
+	defaultWebhookInitialBackoff = 10 * time.Second
+	defaultWebhookMaxBackoff     = time.Hour
+	// Доставка в Sending дольше этого срока считается брошенной упавшим обработчиком
+	webhookStaleAfter = 5 * time.Minute
+	// Сколько байт ответа получателя вычитывается, чтобы переиспользовать соединение. В журнал ответ не попадает
+	webhookDrainLimit = 64 << 10
+)
+
+var (
+	ErrInvalidWebhookURL    = errors.New("webhook url must be an absolute http or https url")
+	ErrPrivateWebhookURL    = errors.New("webhook url must resolve to a public address")
+	ErrUnknownWebhookEvent  = errors.New("unknown webhook event")
+	ErrWebhookOwnerRequired = errors.New("webhook requires a wallet number or an api key")
+	ErrWebhookNotOwned      = errors.New("webhook belongs to another owner")
+	ErrWalletWebhookDenied  = errors.New("wallet webhooks are managed by the wallet's client token or an admin")
+)
+
+var webhookEvents = []string{
+	domain.WebhookTransactionCreated,
+	domain.WebhookTransactionSettled,
+	domain.WebhookTransactionFailed,
+	domain.WebhookOperationFailed,
+}
+
+type WebhookRepository interface {
+	CreateWebhook(ctx context.Context, hook *domain.Webhooks) error
+	ListWebhooks(ctx context.Context, apiKeyID int, clientID int) ([]domain.Webhooks, error)
+	GetWebhook(ctx context.Context, id int) (*domain.Webhooks, error)
+	DeleteWebhook(ctx context.Context, id int) error
+	UpdateWebhookSecret(ctx context.Context, id int, secret string) (*domain.Webhooks, error)
+	ListWebhookDeliveries(ctx context.Context, webhookID int, status string, limit int) ([]domain.WebhookDeliveries, error)
+	ClaimWebhookDelivery(ctx context.Context, staleAfter time.Duration) (*domain.WebhookDeliveries, error)
+	FinishWebhookDelivery(ctx context.Context, delivery *domain.WebhookDeliveries, attempt *domain.WebhookAttempts) error
+	ResendWebhookDelivery(ctx context.Context, webhookID int, deliveryID int) (*domain.WebhookDeliveries, error)
+	FindClient(ctx context.Context, walletNumber int, cardNumber int) (*domain.Clients, error)
+}
+
+// WebhookWorker управляет подписками на вебхуки и доставляет их в фоне
+type WebhookWorker struct {
+	repo           WebhookRepository
+	client         *http.Client
+	allowPrivate   bool
+	workers        int
+	pollInterval   time.Duration
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	wake           chan struct{}
+	logger         *zap.Logger
+}
+
+func NewWebhookWorker(repo WebhookRepository, cfg *config.Config, logger *zap.Logger) *WebhookWorker {
+	workers := cfg.Webhooks.Workers
+	if workers <= 0 {
+		workers = defaultWebhookWorkers
+	}
+
+	pollInterval := time.Duration(cfg.Webhooks.PollInterval) * time.Millisecond
+	if pollInterval <= 0 {
+		pollInterval = defaultWebhookPollInterval
+	}
+
+	timeout := time.Duration(cfg.Webhooks.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+
+	maxAttempts := cfg.Webhooks.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultWebhookMaxAttempts
+	}
+
+	initialBackoff := time.Duration(cfg.Webhooks.InitialBackoff) * time.Millisecond
+	if initialBackoff <= 0 {
+		initialBackoff = defaultWebhookInitialBackoff
+	}
+
+	maxBackoff := time.Duration(cfg.Webhooks.MaxBackoff) * time.Millisecond
+	if maxBackoff <= 0 {
+		maxBackoff = defaultWebhookMaxBackoff
+	}
+
+	// Адрес получателя проверяется при каждом подключении, прокси из окружения не используется: иначе проверялся бы прокси
+	dialer := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}
+	if !cfg.Webhooks.AllowPrivateNetworks {
+		dialer.Control = webhook.DialControl
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &WebhookWorker{
+		repo: repo,
+		// Редиректы не выполняются: подпись относится к адресу подписки, а не к тому, куда отправит получатель
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		allowPrivate:   cfg.Webhooks.AllowPrivateNetworks,
+		workers:        workers,
+		pollInterval:   pollInterval,
+		maxAttempts:    maxAttempts,
+		initialBackoff: initialBackoff,
+		maxBackoff:     maxBackoff,
+		wake:           make(chan struct{}, 1),
+		logger:         logger,
+	}
+}
+
+// Caller - кто обращается к подпискам: API-ключ, кошелек клиентского токена или администратор.
+// Нулевые поля означают, что этого признака у запроса нет
+type Caller struct {
+	APIKeyID int
+	Wallet   int
+	Admin    bool
+}
+
+// canManageWallet - подписками кошелька управляет его клиентский токен или администратор, но не API-ключи
+func (c Caller) canManageWallet(walletNumber int) bool {
+	return c.Admin || (c.Wallet != 0 && walletNumber == c.Wallet)
+}
+
+// CreateWebhook подписывает на события клиента с кошельком walletNumber или, если кошелек не указан,
+// на события транзакций API-ключа запроса. На кошелек подписывает только его клиентский токен
+// или администратор. Адрес должен разрешаться только в публичные IP. Секрет подписи возвращается только здесь
+func (ww *WebhookWorker) CreateWebhook(ctx context.Context, caller Caller, rawURL string, events []string, walletNumber int) (*domain.Webhooks, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return nil, ErrInvalidWebhookURL
+	}
+
+	if !ww.allowPrivate {
+		err = webhook.CheckHost(ctx, parsed.Hostname())
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrPrivateWebhookURL, err)
+		}
+	}
+
+	for _, event := range events {
+		if !slices.Contains(webhookEvents, event) {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownWebhookEvent, event)
+		}
+	}
+
+	hook := &domain.Webhooks{
+		URL:       rawURL,
+		Events:    append([]string{}, events...),
+		CreatedAt: time.Now(),
+	}
+
+	if walletNumber != 0 {
+		if !caller.canManageWallet(walletNumber) {
+			return nil, ErrWalletWebhookDenied
+		}
+
+		client, err := ww.repo.FindClient(ctx, walletNumber, 0)
+		if err != nil {
+			return nil, err
+		}
+		hook.ClientID = client.ID
+	} else {
+		hook.APIKeyID = caller.APIKeyID
+		if hook.APIKeyID == 0 {
+			return nil, ErrWebhookOwnerRequired
+		}
+	}
+
+	hook.Secret, err = newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	err = ww.repo.CreateWebhook(ctx, hook)
+	if err != nil {
+		return nil, err
+	}
+
+	return hook, nil
+}
+
+// RotateWebhookSecret выпускает новый секрет подписи вебхуков. Получатель должен принять его до следующей доставки
+func (ww *WebhookWorker) RotateWebhookSecret(ctx context.Context, id int) (*domain.Webhooks, error) {
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	return ww.repo.UpdateWebhookSecret(ctx, id, secret)
+}
+
+// ListWebhooks возвращает подписки клиента с кошельком walletNumber или, если кошелек не указан, API-ключа запроса
+func (ww *WebhookWorker) ListWebhooks(ctx context.Context, caller Caller, walletNumber int) ([]domain.Webhooks, error) {
+	if walletNumber == 0 {
+		return ww.repo.ListWebhooks(ctx, caller.APIKeyID, 0)
+	}
+
+	if !caller.canManageWallet(walletNumber) {
+		return nil, ErrWalletWebhookDenied
+	}
+
+	client, err := ww.repo.FindClient(ctx, walletNumber, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return ww.repo.ListWebhooks(ctx, 0, client.ID)
+}
+
+// GetWebhook возвращает подписку, если она доступна запросу. Подписка кошелька доступна только его клиенту
+// и администратору, подписка API-ключа - только этому ключу и администратору
+func (ww *WebhookWorker) GetWebhook(ctx context.Context, caller Caller, id int) (*domain.Webhooks, error) {
+	hook, err := ww.repo.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case caller.Admin:
+	case caller.Wallet != 0:
+		if hook.ClientID == 0 {
+			return nil, ErrWebhookNotOwned
+		}
+		client, err := ww.repo.FindClient(ctx, caller.Wallet, 0)
+		if err != nil || hook.ClientID != client.ID {
+			return nil, ErrWebhookNotOwned
+		}
+	case caller.APIKeyID != 0:
+		if hook.APIKeyID != caller.APIKeyID {
+			return nil, ErrWebhookNotOwned
+		}
+	default:
+		return nil, ErrWebhookNotOwned
+	}
+
+	return hook, nil
+}
+
+func (ww *WebhookWorker) DeleteWebhook(ctx context.Context, caller Caller, id int) error {
+	_, err := ww.GetWebhook(ctx, caller, id)
+	if err != nil {
+		return err
+	}
+
+	return ww.repo.DeleteWebhook(ctx, id)
+}
+
+func (ww *WebhookWorker) ListDeliveries(ctx context.Context, caller Caller, id int, status string, limit int) ([]domain.WebhookDeliveries, error) {
+	_, err := ww.GetWebhook(ctx, caller, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return ww.repo.ListWebhookDeliveries(ctx, id, status, limit)
+}
+
+// ResendDelivery возвращает доставку в очередь, например после того как получатель исправил ошибку
+func (ww *WebhookWorker) ResendDelivery(ctx context.Context, caller Caller, id int, deliveryID int) (*domain.WebhookDeliveries, error) {
+	_, err := ww.GetWebhook(ctx, caller, id)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery, err := ww.repo.ResendWebhookDelivery(ctx, id, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case ww.wake <- struct{}{}:
+	default:
+	}
+
+	return delivery, nil
+}
+
+// Run доставляет вебхуки из очереди, пока не отменен ctx
+func (ww *WebhookWorker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < ww.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ww.loop(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (ww *WebhookWorker) loop(ctx context.Context) {
+	ticker := time.NewTicker(ww.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		delivery, err := ww.repo.ClaimWebhookDelivery(ctx, webhookStaleAfter)
+		if err != nil {
+			ww.logger.Error("Failed to claim webhook delivery", zap.Error(err))
+		}
+
+		if delivery != nil {
+			ww.deliver(ctx, delivery)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ww.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliver отправляет вебхук. Ответ 2xx завершает доставку, иначе она повторяется с экспоненциальной задержкой,
+// пока не кончатся попытки
+func (ww *WebhookWorker) deliver(ctx context.Context, delivery *domain.WebhookDeliveries) {
+	delivery.Attempts++
+	attempt := &domain.WebhookAttempts{Attempt: delivery.Attempts, CreatedAt: time.Now()}
+
+	statusCode, err := ww.send(ctx, delivery)
+	attempt.Duration = time.Since(attempt.CreatedAt).Milliseconds()
+	attempt.StatusCode = statusCode
+	delivery.LastStatusCode = statusCode
+
+	if err == nil {
+		now := time.Now()
+		delivery.Status = domain.WebhookDeliveryDelivered
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+		delivery.LastError = ""
+	} else {
+		attempt.Error = err.Error()
+		delivery.LastError = err.Error()
+		if delivery.Attempts < ww.maxAttempts {
+			next := time.Now().Add(ww.backoff(delivery.Attempts))
+			delivery.Status = domain.WebhookDeliveryPending
+			delivery.NextAttemptAt = &next
+		} else {
+			delivery.Status = domain.WebhookDeliveryFailed
+			delivery.NextAttemptAt = nil
+		}
+		ww.logger.Warn("Failed to deliver webhook",
+			zap.Int("delivery_id", delivery.ID), zap.Int("attempt", delivery.Attempts), zap.Error(err))
+	}
+
+	err = ww.repo.FinishWebhookDelivery(context.WithoutCancel(ctx), delivery, attempt)
+	if err != nil {
+		ww.logger.Error("Failed to save webhook delivery result", zap.Int("delivery_id", delivery.ID), zap.Error(err))
+	}
+}
+
+func (ww *WebhookWorker) send(ctx context.Context, delivery *domain.WebhookDeliveries) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "transaction-system-webhooks")
+	req.Header.Set(webhook.HeaderID, delivery.EventID)
+	req.Header.Set(webhook.HeaderEvent, delivery.EventType)
+	req.Header.Set(webhook.HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(webhook.HeaderSignature, webhook.Sign(delivery.Webhook.Secret, timestamp, body))
+
+	resp, err := ww.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// Тело ответа не сохраняется: журнал доставок доступен владельцу подписки и не должен пересказывать ответы получателя
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, webhookDrainLimit))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, nil
+	}
+
+	return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+}
+
+func (ww *WebhookWorker) backoff(attempt int) time.Duration {
+	backoff := time.Duration(float64(ww.initialBackoff) * math.Pow(2, float64(attempt-1)))
+	if backoff > ww.maxBackoff {
+		return ww.maxBackoff
+	}
+
+	return backoff
+}
+
+func newWebhookSecret() (string, error) {
+	secret, err := randomHex(webhookSecretBytes)
+	if err != nil {
+		return "", err
+	}
+
+	return webhookSecretPrefix + secret, nil
+}
@@ -0,0 +1,204 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"go.uber.org/zap"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"transaction-system/config"
+	"transaction-system/internal/domain"
+	"transaction-system/pkg/webhook"
+	"transaction-system/service"
+)
+
+const testWebhookSecret = "whsec_test"
+
+// result - доставка и попытка, сохраненные обработчиком
+type result struct {
+	delivery domain.WebhookDeliveries
+	attempt  domain.WebhookAttempts
+}
+
+// deliveries - очередь доставок вместо БД: обработчик забирает их из queue и сохраняет результат в results
+type deliveries struct {
+	service.WebhookRepository
+	queue   chan *domain.WebhookDeliveries
+	results chan result
+}
+
+func newDeliveries() *deliveries {
+	return &deliveries{queue: make(chan *domain.WebhookDeliveries, 1), results: make(chan result, 1)}
+}
+
+func (d *deliveries) ClaimWebhookDelivery(_ context.Context, _ time.Duration) (*domain.WebhookDeliveries, error) {
+	select {
+	case delivery := <-d.queue:
+		return delivery, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (d *deliveries) FinishWebhookDelivery(_ context.Context, delivery *domain.WebhookDeliveries, attempt *domain.WebhookAttempts) error {
+	d.results <- result{delivery: *delivery, attempt: *attempt}
+	return nil
+}
+
+// deliver ставит доставку в очередь и ждет результата попытки
+func (d *deliveries) deliver(t *testing.T, delivery *domain.WebhookDeliveries) result {
+	t.Helper()
+
+	d.queue <- delivery
+	select {
+	case r := <-d.results:
+		return r
+	case <-time.After(5 * time.Second):
+		t.Fatal("delivery was not attempted")
+		return result{}
+	}
+}
+
+func startWebhookWorker(t *testing.T, repo *deliveries, cfg *config.Config) {
+	t.Helper()
+
+	cfg.Webhooks.PollInterval = 10
+	worker := service.NewWebhookWorker(repo, cfg, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		worker.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func newDelivery(url string) *domain.WebhookDeliveries {
+	return &domain.WebhookDeliveries{
+		ID:        1,
+		WebhookID: 1,
+		Webhook:   &domain.Webhooks{ID: 1, URL: url, Secret: testWebhookSecret},
+		EventID:   "evt_1",
+		EventType: domain.WebhookTransactionCreated,
+		Payload:   `{"id":"evt_1","type":"transaction.created"}`,
+		Status:    domain.WebhookDeliveryPending,
+	}
+}
+
+func TestWebhookDeliveryIsSigned(t *testing.T) {
+	received := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		err := webhook.Verify(testWebhookSecret, r.Header.Get(webhook.HeaderTimestamp), r.Header.Get(webhook.HeaderSignature), body, time.Minute)
+		if err == nil && (r.Header.Get(webhook.HeaderID) != "evt_1" || r.Header.Get(webhook.HeaderEvent) != domain.WebhookTransactionCreated) {
+			err = errors.New("missing webhook headers")
+		}
+		received <- err
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	repo := newDeliveries()
+	startWebhookWorker(t, repo, &config.Config{Webhooks: config.Webhooks{AllowPrivateNetworks: true}})
+
+	r := repo.deliver(t, newDelivery(server.URL))
+	if err := <-received; err != nil {
+		t.Fatalf("receiver rejected the request: %v", err)
+	}
+	if r.delivery.Status != domain.WebhookDeliveryDelivered || r.delivery.DeliveredAt == nil || r.attempt.StatusCode != http.StatusNoContent {
+		t.Fatalf("delivery = %+v, attempt = %+v", r.delivery, r.attempt)
+	}
+}
+
+// Неудачная доставка повторяется с удваивающейся паузой не длиннее MAX_BACKOFF, после MAX_ATTEMPTS она Failed
+func TestWebhookDeliveryRetriesWithBackoff(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	repo := newDeliveries()
+	startWebhookWorker(t, repo, &config.Config{Webhooks: config.Webhooks{
+		AllowPrivateNetworks: true,
+		MaxAttempts:          4,
+		InitialBackoff:       1000,
+		MaxBackoff:           3000,
+	}})
+
+	delivery := newDelivery(server.URL)
+	for attempt, backoff := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+		started := time.Now()
+		r := repo.deliver(t, delivery)
+
+		if r.delivery.Status != domain.WebhookDeliveryPending || r.delivery.Attempts != attempt+1 {
+			t.Fatalf("attempt %d: status = %s, attempts = %d", attempt+1, r.delivery.Status, r.delivery.Attempts)
+		}
+		if r.attempt.StatusCode != http.StatusServiceUnavailable || r.delivery.LastError == "" {
+			t.Fatalf("attempt %d: status code = %d, error = %q", attempt+1, r.attempt.StatusCode, r.delivery.LastError)
+		}
+
+		wait := r.delivery.NextAttemptAt.Sub(started)
+		if wait < backoff || wait > backoff+time.Second {
+			t.Fatalf("attempt %d: next attempt in %s, want %s", attempt+1, wait, backoff)
+		}
+
+		delivery = &r.delivery
+	}
+
+	r := repo.deliver(t, delivery)
+	if r.delivery.Status != domain.WebhookDeliveryFailed || r.delivery.NextAttemptAt != nil || r.delivery.Attempts != 4 {
+		t.Fatalf("last attempt: status = %s, attempts = %d, next = %v", r.delivery.Status, r.delivery.Attempts, r.delivery.NextAttemptAt)
+	}
+	if calls.Load() != 4 {
+		t.Fatalf("receiver got %d requests, want 4", calls.Load())
+	}
+}
+
+// Адрес подписки прошел проверку при создании, но к доставке имя разрешается в loopback (DNS rebinding):
+// подключение отклоняется до отправки запроса
+func TestWebhookDeliveryRefusesLoopbackAtDial(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	repo := newDeliveries()
+	startWebhookWorker(t, repo, &config.Config{})
+
+	r := repo.deliver(t, newDelivery("http://localhost:"+port+"/hook"))
+	if r.delivery.Status != domain.WebhookDeliveryPending || r.attempt.StatusCode != 0 {
+		t.Fatalf("delivery status = %s, status code = %d", r.delivery.Status, r.attempt.StatusCode)
+	}
+	if !strings.Contains(r.delivery.LastError, webhook.ErrPrivateAddress.Error()) {
+		t.Fatalf("last error = %q, want %q", r.delivery.LastError, webhook.ErrPrivateAddress)
+	}
+	if calls.Load() != 0 {
+		t.Fatalf("receiver got %d requests", calls.Load())
+	}
+}
+
+func TestCreateWebhookRejectsPrivateURL(t *testing.T) {
+	worker := service.NewWebhookWorker(newDeliveries(), &config.Config{}, zap.NewNop())
+	caller := service.Caller{APIKeyID: 1}
+
+	for _, url := range []string{"http://127.0.0.1/hook", "http://169.254.169.254/latest/meta-data", "http://[::1]:8080/"} {
+		_, err := worker.CreateWebhook(context.Background(), caller, url, []string{domain.WebhookTransactionCreated}, 0)
+		if !errors.Is(err, service.ErrPrivateWebhookURL) {
+			t.Fatalf("CreateWebhook(%s) = %v, want %v", url, err, service.ErrPrivateWebhookURL)
+		}
+	}
+}
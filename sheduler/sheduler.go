@@ -1,25 +1,75 @@
 package sheduler
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/go-co-op/gocron"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
 	"go.uber.org/zap"
+	"sync/atomic"
 	"time"
 	"transaction-system/config"
+	"transaction-system/pkg/metrics"
 	"transaction-system/storage"
 )
 
+const (
+	defaultProcessedRetention = 7 * 24 * time.Hour
+	defaultRelayInterval      = 5 * time.Second
+	// Проведение считается зависшим, если очередной запуск не начался за столько интервалов, но не меньше минуты
+	aliveIntervals = 3
+	minAliveWindow = time.Minute
+)
+
+const tracerName = "transaction-system/sheduler"
+
+// Названия задач в метриках и спанах планировщика
+const (
+	jobSettle     = "settle_transactions"
+	jobCleanup    = "delete_processed_events"
+	jobRelay      = "relay_events"
+	jobSignatures = "delete_expired_signatures"
+)
+
+var ErrSchedulerStopped = errors.New("scheduler is not running")
+
 type Scheduler struct {
-	dataBaseRepo *storage.DataBaseRepositoryImpl
-	updateTime   int
-	logger       *zap.Logger
+	scheduler          *gocron.Scheduler
+	dataBaseRepo       *storage.DataBaseRepositoryImpl
+	updateTime         int
+	processedRetention time.Duration
+	relayInterval      time.Duration
+	// lastRun - unix-время в наносекундах последнего запуска проведения транзакций
+	lastRun atomic.Int64
+	logger  *zap.Logger
 }
 
 func NewScheduler(cfg *config.Config, dataBaseRepo *storage.DataBaseRepositoryImpl, logger *zap.Logger) *Scheduler {
-	return &Scheduler{dataBaseRepo: dataBaseRepo, updateTime: cfg.Scheduler.Update, logger: logger}
+	processedRetention := time.Duration(cfg.EventBus.ProcessedRetention) * time.Hour
+	if processedRetention <= 0 {
+		processedRetention = defaultProcessedRetention
+	}
+
+	relayInterval := time.Duration(cfg.EventBus.RelayInterval) * time.Second
+	if relayInterval <= 0 {
+		relayInterval = defaultRelayInterval
+	}
+
+	return &Scheduler{
+		dataBaseRepo:       dataBaseRepo,
+		updateTime:         cfg.Scheduler.Update,
+		processedRetention: processedRetention,
+		relayInterval:      relayInterval,
+		logger:             logger,
+	}
 }
 
 func (r *Scheduler) Run() {
 	s := gocron.NewScheduler(time.UTC)
+	r.scheduler = s
+	r.lastRun.Store(time.Now().UnixNano())
 
 	interval := time.Duration(r.updateTime) * time.Second
 	_, err := s.Every(interval).WaitForSchedule().Do(r.callUpdateTransactionStatusToSuccess)
@@ -28,10 +78,22 @@ func (r *Scheduler) Run() {
 		return
 	}
 
-	intervalKafka := time.Duration(r.updateTime) * time.Minute
-	_, err = s.Every(intervalKafka).WaitForSchedule().Do(r.callReadFromKafka)
+	_, err = s.Every(time.Hour).Do(r.callDeleteExpiredProcessedEvents)
 	if err != nil {
-		r.logger.Error("Error scheduling WriteLogsToClickHouse", zap.Error(err))
+		r.logger.Error("Error scheduling DeleteExpiredProcessedEvents", zap.Error(err))
+		return
+	}
+
+	_, err = s.Every(time.Hour).Do(r.callDeleteExpiredAPIKeySignatures)
+	if err != nil {
+		r.logger.Error("Error scheduling DeleteExpiredAPIKeySignatures", zap.Error(err))
+		return
+	}
+
+	// Следующий запуск пропускается, пока не закончен предыдущий: он может ждать недоступный брокер
+	_, err = s.Every(r.relayInterval).SingletonMode().Do(r.callRelayEvents)
+	if err != nil {
+		r.logger.Error("Error scheduling RelayEvents", zap.Error(err))
 		return
 	}
 
@@ -40,16 +102,88 @@ func (r *Scheduler) Run() {
 	r.logger.Info("Scheduler started successfully")
 }
 
+// Stop останавливает планировщик и дожидается выполняющихся задач
+func (r *Scheduler) Stop() {
+	if r.scheduler == nil {
+		return
+	}
+
+	r.scheduler.Stop()
+	r.logger.Info("Scheduler stopped")
+}
+
+// Alive - проверка для readiness: планировщик запущен, и проведение транзакций запускается по расписанию
+func (r *Scheduler) Alive(context.Context) error {
+	if r.scheduler == nil || !r.scheduler.IsRunning() {
+		return ErrSchedulerStopped
+	}
+
+	window := aliveIntervals * time.Duration(r.updateTime) * time.Second
+	if window < minAliveWindow {
+		window = minAliveWindow
+	}
+
+	since := time.Since(time.Unix(0, r.lastRun.Load()))
+	if since > window {
+		return fmt.Errorf("settlement has not started for %s", since.Round(time.Second))
+	}
+
+	return nil
+}
+
 func (r *Scheduler) callUpdateTransactionStatusToSuccess() {
-	err := r.dataBaseRepo.UpdateTransactionStatusToSuccess()
+	r.lastRun.Store(time.Now().UnixNano())
+	err := observe(jobSettle, func(ctx context.Context) error {
+		_, err := r.dataBaseRepo.UpdateTransactionStatusToSuccess(ctx)
+		return err
+	})
 	if err != nil {
 		r.logger.Error("Error calling UpdateTransactionStatusToSuccess", zap.Error(err))
 	}
 }
 
-func (r *Scheduler) callReadFromKafka() {
-	err := r.dataBaseRepo.ReadFromKafka()
+func (r *Scheduler) callDeleteExpiredProcessedEvents() {
+	err := observe(jobCleanup, func(context.Context) error {
+		return r.dataBaseRepo.DeleteExpiredProcessedEvents(r.processedRetention)
+	})
 	if err != nil {
-		r.logger.Error("Error calling ReadFromKafka", zap.Error(err))
+		r.logger.Error("Error calling DeleteExpiredProcessedEvents", zap.Error(err))
 	}
 }
+
+func (r *Scheduler) callDeleteExpiredAPIKeySignatures() {
+	err := observe(jobSignatures, func(ctx context.Context) error {
+		_, err := r.dataBaseRepo.DeleteExpiredAPIKeySignatures(ctx)
+		return err
+	})
+	if err != nil {
+		r.logger.Error("Error calling DeleteExpiredAPIKeySignatures", zap.Error(err))
+	}
+}
+
+func (r *Scheduler) callRelayEvents() {
+	err := observe(jobRelay, func(ctx context.Context) error {
+		_, err := r.dataBaseRepo.RelayEvents(ctx)
+		return err
+	})
+	if err != nil {
+		r.logger.Error("Error calling RelayEvents", zap.Error(err))
+	}
+}
+
+// observe выполняет задачу в корневом спане трассировки и записывает ее длительность и неудачи в метрики планировщика
+func observe(job string, run func(ctx context.Context) error) error {
+	ctx, span := otel.Tracer(tracerName).Start(context.Background(), "scheduler "+job)
+	defer span.End()
+
+	start := time.Now()
+	err := run(ctx)
+	metrics.SchedulerJobDuration.WithLabelValues(job).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.SchedulerJobFailures.WithLabelValues(job).Inc()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return err
+}
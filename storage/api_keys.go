@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"github.com/go-pg/pg/v10"
+	"go.uber.org/zap"
+	"time"
+	"transaction-system/internal/domain"
+)
+
+func (dr *DataBaseRepositoryImpl) CreateAPIKey(ctx context.Context, key *domain.APIKeys) error {
+	_, err := dr.postgreClient.ModelContext(ctx, key).Insert()
+	if err != nil {
+		dr.logger.Error("Failed to insert api key", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// FindActiveAPIKey ищет неотозванный ключ по публичному идентификатору
+func (dr *DataBaseRepositoryImpl) FindActiveAPIKey(ctx context.Context, keyID string) (*domain.APIKeys, error) {
+	key := &domain.APIKeys{}
+	err := dr.postgreClient.ModelContext(ctx, key).
+		Where("key_id = ?", keyID).
+		Where("revoked_at IS NULL").
+		Select()
+	if errors.Is(err, pg.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func (dr *DataBaseRepositoryImpl) ListAPIKeys(ctx context.Context) ([]domain.APIKeys, error) {
+	var keys []domain.APIKeys
+	err := dr.postgreClient.ModelContext(ctx, &keys).Order("id ASC").Select()
+	if err != nil {
+		dr.logger.Error("Failed to list api keys", zap.Error(err))
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (dr *DataBaseRepositoryImpl) RevokeAPIKey(ctx context.Context, id int) error {
+	res, err := dr.postgreClient.ModelContext(ctx, (*domain.APIKeys)(nil)).
+		Set("revoked_at = ?", time.Now()).
+		Where("id = ?", id).
+		Where("revoked_at IS NULL").
+		Update()
+	if err != nil {
+		dr.logger.Error("Failed to revoke api key", zap.Error(err))
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// UpdateAPIKeySecret заменяет зашифрованный ключ подписи неотозванного ключа, key_id и привязка транзакций сохраняются
+func (dr *DataBaseRepositoryImpl) UpdateAPIKeySecret(ctx context.Context, id int, secretEncrypted string) (*domain.APIKeys, error) {
+	key := &domain.APIKeys{}
+	res, err := dr.postgreClient.ModelContext(ctx, key).
+		Set("secret_encrypted = ?", secretEncrypted).
+		Set("secret_hash = NULL").
+		Where("id = ?", id).
+		Where("revoked_at IS NULL").
+		Returning("*").
+		Update()
+	if err != nil {
+		dr.logger.Error("Failed to update api key secret", zap.Error(err))
+		return nil, err
+	}
+
+	if res.RowsAffected() == 0 {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	return key, nil
+}
+
+// PlaintextAPIKeys - ключи, выпущенные до шифрования: ключ подписи лежит открытым в secret_hash. id -> secret_hash
+func (dr *DataBaseRepositoryImpl) PlaintextAPIKeys(ctx context.Context) (map[int]string, error) {
+	var rows []struct {
+		ID         int
+		SecretHash string
+	}
+	_, err := dr.postgreClient.QueryContext(ctx, &rows, `
+		SELECT id, secret_hash
+		FROM api_keys
+		WHERE secret_encrypted IS NULL AND secret_hash IS NOT NULL
+	`)
+	if err != nil {
+		dr.logger.Error("Failed to select plaintext api keys", zap.Error(err))
+		return nil, err
+	}
+
+	keys := make(map[int]string, len(rows))
+	for _, row := range rows {
+		keys[row.ID] = row.SecretHash
+	}
+
+	return keys, nil
+}
+
+// EncryptAPIKey сохраняет зашифрованный ключ подписи и стирает открытый, в том числе у отозванных ключей
+func (dr *DataBaseRepositoryImpl) EncryptAPIKey(ctx context.Context, id int, secretEncrypted string) error {
+	_, err := dr.postgreClient.ModelContext(ctx, (*domain.APIKeys)(nil)).
+		Set("secret_encrypted = ?", secretEncrypted).
+		Set("secret_hash = NULL").
+		Where("id = ?", id).
+		Where("secret_encrypted IS NULL").
+		Update()
+	if err != nil {
+		dr.logger.Error("Failed to encrypt api key", zap.Error(err))
+	}
+
+	return err
+}
+
+// RememberAPIKeySignature запоминает подпись запроса до expiresAt. false - такая подпись уже была
+func (dr *DataBaseRepositoryImpl) RememberAPIKeySignature(ctx context.Context, apiKeyID int, signature string, expiresAt time.Time) (bool, error) {
+	res, err := dr.postgreClient.ModelContext(ctx, &domain.APIKeySignatures{
+		Signature: signature,
+		APIKeyID:  apiKeyID,
+		ExpiresAt: expiresAt,
+	}).OnConflict("DO NOTHING").Insert()
+	if err != nil {
+		dr.logger.Error("Failed to remember api key signature", zap.Error(err))
+		return false, err
+	}
+
+	return res.RowsAffected() == 1, nil
+}
+
+// DeleteExpiredAPIKeySignatures удаляет подписи запросов, которые уже не пройдут проверку времени
+func (dr *DataBaseRepositoryImpl) DeleteExpiredAPIKeySignatures(ctx context.Context) (int, error) {
+	res, err := dr.postgreClient.ModelContext(ctx, (*domain.APIKeySignatures)(nil)).
+		Where("expires_at < ?", time.Now()).
+		Delete()
+	if err != nil {
+		dr.logger.Error("Failed to delete expired api key signatures", zap.Error(err))
+		return 0, err
+	}
+
+	return res.RowsAffected(), nil
+}
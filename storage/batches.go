@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"github.com/go-pg/pg/v10"
+	"go.uber.org/zap"
+	"time"
+	"transaction-system/internal/domain"
+)
+
+const transactionBatchItemIndex = "transactions_batch_item_id_key"
+
+// CreateBatch сохраняет пакет вместе с элементами. Если пакет с таким ключом идемпотентности у этого API-ключа
+// уже есть, возвращается он, а второе значение равно false
+func (dr *DataBaseRepositoryImpl) CreateBatch(ctx context.Context, batch *domain.Batches) (*domain.Batches, bool, error) {
+	created := false
+	err := dr.postgreClient.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		res, err := tx.ModelContext(ctx, batch).
+			OnConflict("(COALESCE(api_key_id, 0), idempotency_key) DO NOTHING").
+			Insert()
+		if err != nil {
+			return err
+		}
+
+		if res.RowsAffected() == 0 {
+			return nil
+		}
+		created = true
+
+		for _, item := range batch.Items {
+			item.BatchID = batch.ID
+		}
+
+		_, err = tx.ModelContext(ctx, &batch.Items).Insert()
+		return err
+	})
+	if err != nil {
+		dr.logger.Error("Failed to insert batch", zap.Error(err))
+		return nil, false, err
+	}
+
+	if created {
+		return batch, true, nil
+	}
+
+	existing := &domain.Batches{}
+	err = dr.postgreClient.ModelContext(ctx, existing).
+		Relation("Items", orderItemsByPosition).
+		Where("COALESCE(api_key_id, 0) = ?", batch.APIKeyID).
+		Where("idempotency_key = ?", batch.IdempotencyKey).
+		Select()
+	if err != nil {
+		dr.logger.Error("Failed to select existing batch", zap.Error(err))
+		return nil, false, err
+	}
+
+	return existing, false, nil
+}
+
+func (dr *DataBaseRepositoryImpl) GetBatch(ctx context.Context, id int) (*domain.Batches, error) {
+	batch := &domain.Batches{}
+	err := dr.postgreClient.ModelContext(ctx, batch).
+		Relation("Items", orderItemsByPosition).
+		Where("batches.id = ?", id).
+		Select()
+	if errors.Is(err, pg.ErrNoRows) {
+		return nil, ErrBatchNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return batch, nil
+}
+
+// ClaimPendingBatch переводит в Processing самый старый принятый пакет и возвращает его вместе с элементами.
+// Пакеты, не обновлявшиеся в Processing дольше staleAfter (например, после падения сервиса), забираются повторно.
+// Если обрабатывать нечего, возвращается nil без ошибки
+func (dr *DataBaseRepositoryImpl) ClaimPendingBatch(ctx context.Context, staleAfter time.Duration) (*domain.Batches, error) {
+	batch := &domain.Batches{}
+	_, err := dr.postgreClient.QueryOneContext(ctx, batch, `
+		UPDATE batches
+		SET status = ?, updated_at = now()
+		WHERE id = (
+			SELECT id
+			FROM batches
+			WHERE status = ?
+			   OR (status = ? AND updated_at < now() - ? * interval '1 second')
+			ORDER BY id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		domain.BatchProcessing, domain.BatchPending, domain.BatchProcessing, staleAfter.Seconds())
+	if errors.Is(err, pg.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		dr.logger.Error("Failed to claim pending batch", zap.Error(err))
+		return nil, err
+	}
+
+	err = dr.postgreClient.ModelContext(ctx, &batch.Items).
+		Where("batch_id = ?", batch.ID).
+		Order("position ASC").
+		Select()
+	if err != nil {
+		dr.logger.Error("Failed to select batch items", zap.Error(err))
+		return nil, err
+	}
+
+	return batch, nil
+}
+
+// SettleBatchItem создает транзакцию по элементу пакета от имени API-ключа пакета. Если транзакция по элементу
+// уже была создана до повторного захвата пакета, возвращается существующая: уникальный batch_item_id не дает
+// провести элемент дважды
+func (dr *DataBaseRepositoryImpl) SettleBatchItem(ctx context.Context, batch *domain.Batches, item *domain.BatchItems) (*domain.Transactions, error) {
+	client, err := dr.findClientByRequisites(ctx, item.WalletNumber, item.CardNumber)
+	if err != nil {
+		dr.logger.Error("Failed to find client", zap.Error(err))
+		return nil, err
+	}
+
+	currencyID, err := dr.findCurrencyID(ctx, item.CurrencyCode)
+	if err != nil {
+		dr.logger.Error("Failed to find currency", zap.Error(err))
+		return nil, err
+	}
+
+	amount := item.Amount
+	if item.Operation == domain.OperationWithdraw {
+		amount = -amount
+	}
+
+	transaction := &domain.Transactions{
+		Amount:      amount,
+		CreatedAt:   time.Now(),
+		ClientID:    client.ID,
+		CurrencyID:  currencyID,
+		Status:      CreatedStat,
+		APIKeyID:    batch.APIKeyID,
+		BatchItemID: item.ID,
+	}
+
+	err = dr.createTransaction(ctx, transaction)
+	if isUniqueViolation(err, transactionBatchItemIndex) {
+		existing := &domain.Transactions{}
+		err = dr.postgreClient.ModelContext(ctx, existing).Where("batch_item_id = ?", item.ID).Select()
+		if err != nil {
+			return nil, err
+		}
+		return existing, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// UpdateBatchItem сохраняет результат обработки элемента, чтобы он был виден при опросе пакета до его завершения.
+// Заодно обновляется updated_at пакета: пакет, который еще обрабатывается, не считается зависшим
+func (dr *DataBaseRepositoryImpl) UpdateBatchItem(ctx context.Context, item *domain.BatchItems) error {
+	item.UpdatedAt = time.Now()
+	err := dr.postgreClient.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		_, err := tx.ModelContext(ctx, item).
+			Column("status", "transaction_id", "error_code", "error", "updated_at").
+			WherePK().
+			Update()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE batches SET updated_at = ? WHERE id = ?`, item.UpdatedAt, item.BatchID)
+		return err
+	})
+	if err != nil {
+		dr.logger.Error("Failed to update batch item", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (dr *DataBaseRepositoryImpl) FinishBatch(ctx context.Context, batch *domain.Batches) error {
+	batch.UpdatedAt = time.Now()
+	_, err := dr.postgreClient.ModelContext(ctx, batch).
+		Column("status", "succeeded", "failed", "updated_at").
+		WherePK().
+		Update()
+	if err != nil {
+		dr.logger.Error("Failed to finish batch", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func orderItemsByPosition(q *pg.Query) (*pg.Query, error) {
+	return q.Order("position ASC"), nil
+}
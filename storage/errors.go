@@ -0,0 +1,19 @@
+package storage
+
+import "errors"
+
+var (
+	ErrClientNotFound          = errors.New("client not found")
+	ErrCurrencyNotFound        = errors.New("currency not found")
+	ErrInsufficientFunds       = errors.New("insufficient funds")
+	ErrAPIKeyNotFound          = errors.New("api key not found")
+	ErrBatchNotFound           = errors.New("batch not found")
+	ErrOperationNotFound       = errors.New("operation not found")
+	ErrTransactionNotFound     = errors.New("transaction not found")
+	ErrWebhookNotFound         = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrClientExists            = errors.New("client with this wallet or card already exists")
+	ErrAlreadyRefunded         = errors.New("transaction already refunded")
+	ErrNotRefundable           = errors.New("transaction can not be refunded")
+	ErrMultipleCurrencies      = errors.New("client has balances in several currencies")
+)
@@ -0,0 +1,321 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"strconv"
+	"time"
+	"transaction-system/internal/domain"
+	"transaction-system/pkg/eventbus"
+	"transaction-system/pkg/metrics"
+)
+
+// createTransaction в одной транзакции БД увеличивает счетчик событий клиента, проверяет баланс для списаний,
+// сохраняет транзакцию и записывает событие в журнал. Событие публикуется только после коммита,
+// поэтому в шину не попадают события откатившихся транзакций, а строка клиента не заблокирована на время записи в брокер.
+// Начатая транзакция доводится до конца и после отмены ctx, из него берется только контекст трассировки
+func (dr *DataBaseRepositoryImpl) createTransaction(ctx context.Context, transaction *domain.Transactions) error {
+	ctx = context.WithoutCancel(ctx)
+
+	var event *domain.TransactionEvent
+	err := dr.postgreClient.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		_, err := tx.QueryOne(pg.Scan(&transaction.Sequence), `
+			UPDATE clients
+			SET event_seq = event_seq + 1
+			WHERE id = ?
+			RETURNING event_seq`, transaction.ClientID)
+		if err != nil {
+			dr.logger.Error("Failed to increment client event sequence", zap.Error(err))
+			return err
+		}
+
+		// Строка клиента заблокирована UPDATE выше, поэтому параллельные списания не пройдут проверку баланса одновременно
+		if transaction.Amount < 0 {
+			balance, err := dr.getSpendableBalance(tx, transaction.ClientID, transaction.CurrencyID)
+			if err != nil {
+				dr.logger.Error("Failed to fetch spendable balance", zap.Error(err))
+				return err
+			}
+
+			if balance+transaction.Amount < 0 {
+				return ErrInsufficientFunds
+			}
+		}
+
+		_, err = tx.Model(transaction).Insert()
+		if err != nil {
+			dr.logger.Error("Failed to insert transaction data", zap.Error(err))
+			return err
+		}
+
+		event, err = dr.recordEvent(tx, transaction, transaction.Sequence)
+		if err != nil {
+			dr.logger.Error("Failed to record client event", zap.Error(err))
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	dr.publishCommitted(ctx, event)
+
+	metrics.TransactionsCreated.WithLabelValues(dr.currencyName(transaction.CurrencyID), transaction.Status).Inc()
+	return nil
+}
+
+// recordEvent сохраняет событие в журнал клиента client_events, из него дочитываются пропущенные события
+func (dr *DataBaseRepositoryImpl) recordEvent(tx *pg.Tx, transaction *domain.Transactions, sequence int64) (*domain.TransactionEvent, error) {
+	event := &domain.TransactionEvent{
+		EventID:     uuid.NewString(),
+		ClientID:    transaction.ClientID,
+		Sequence:    sequence,
+		Transaction: transaction,
+	}
+
+	_, err := tx.Model(&domain.ClientEvents{
+		ClientID:      transaction.ClientID,
+		Sequence:      sequence,
+		EventID:       event.EventID,
+		TransactionID: transaction.ID,
+		Status:        transaction.Status,
+		CreatedAt:     time.Now(),
+	}).Insert()
+	if err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+// publishEvents использует ID клиента как ключ, чтобы все события клиента попадали в одну партицию и сохраняли порядок
+func (dr *DataBaseRepositoryImpl) publishEvents(ctx context.Context, events ...*domain.TransactionEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	messages := make([]eventbus.Message, 0, len(events))
+	for _, event := range events {
+		value, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		messages = append(messages, eventbus.Message{
+			Key:   []byte(strconv.Itoa(event.ClientID)),
+			Value: value,
+		})
+	}
+
+	return dr.publisher.Publish(ctx, messages...)
+}
+
+// HandleEvent - обработчик событий о транзакциях, полученных из шины.
+// Шина доставляет события как минимум один раз, поэтому ID события фиксируется в processed_events
+// в той же транзакции БД, что и побочные эффекты обработки: повторная доставка будет просто пропущена.
+func (dr *DataBaseRepositoryImpl) HandleEvent(ctx context.Context, m eventbus.Message) error {
+	event := &domain.TransactionEvent{}
+	err := json.Unmarshal(m.Value, event)
+	if err != nil {
+		return fmt.Errorf("decode transaction event: %w", err)
+	}
+
+	if event.EventID == "" || event.ClientID == 0 || event.Transaction == nil {
+		return errors.New("transaction event without id or client")
+	}
+
+	return dr.postgreClient.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO processed_events (event_id, processed_at)
+			VALUES (?, now())
+			ON CONFLICT (event_id) DO NOTHING`, event.EventID)
+		if err != nil {
+			return fmt.Errorf("record processed event: %w", err)
+		}
+
+		if res.RowsAffected() == 0 {
+			dr.logger.Info("Skipping duplicate transaction event", zap.String("event_id", event.EventID))
+			return nil
+		}
+
+		var last int64
+		_, err = tx.QueryOneContext(ctx, pg.Scan(&last), `
+			SELECT last_sequence
+			FROM client_event_offsets
+			WHERE client_id = ?
+			FOR UPDATE`, event.ClientID)
+		known := err == nil
+		if err != nil && !errors.Is(err, pg.ErrNoRows) {
+			return fmt.Errorf("load client event offset: %w", err)
+		}
+
+		switch {
+		case known && event.Sequence <= last:
+			dr.logger.Info("Skipping stale transaction event",
+				zap.Int("client_id", event.ClientID), zap.Int64("sequence", event.Sequence), zap.Int64("last_sequence", last))
+			return nil
+		case known && event.Sequence > last+1:
+			err = dr.recoverGap(ctx, tx, event.ClientID, last, event.Sequence)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = dr.processEvent(ctx, tx, event)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO client_event_offsets (client_id, last_sequence, updated_at)
+			VALUES (?, ?, now())
+			ON CONFLICT (client_id) DO UPDATE
+			SET last_sequence = EXCLUDED.last_sequence, updated_at = EXCLUDED.updated_at`, event.ClientID, event.Sequence)
+		if err != nil {
+			return fmt.Errorf("store client event offset: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// DeleteExpiredProcessedEvents удаляет записи о событиях, повторная доставка которых уже не ожидается
+func (dr *DataBaseRepositoryImpl) DeleteExpiredProcessedEvents(retention time.Duration) error {
+	res, err := dr.postgreClient.Exec(`
+		DELETE FROM processed_events
+		WHERE processed_at < ?`, time.Now().Add(-retention))
+	if err != nil {
+		dr.logger.Error("Failed to delete expired processed events", zap.Error(err))
+		return err
+	}
+
+	dr.logger.Info("Expired processed events deleted", zap.Int("count", res.RowsAffected()))
+	return nil
+}
+
+// recoverGap дочитывает из журнала client_events события, которые консьюмер не получил
+func (dr *DataBaseRepositoryImpl) recoverGap(ctx context.Context, tx *pg.Tx, clientID int, last int64, next int64) error {
+	dr.logger.Warn("Gap in client event sequence",
+		zap.Int("client_id", clientID), zap.Int64("last_sequence", last), zap.Int64("next_sequence", next))
+
+	missing, err := loadClientEvents(ctx, tx, clientID, last, next, 0)
+	if err != nil {
+		return fmt.Errorf("load missing events: %w", err)
+	}
+
+	if int64(len(missing)) != next-last-1 {
+		dr.logger.Error("Missing events not found in storage",
+			zap.Int("client_id", clientID), zap.Int("found", len(missing)), zap.Int64("expected", next-last-1))
+	}
+
+	for i := range missing {
+		err = dr.processEvent(ctx, tx, &missing[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ListClientEvents возвращает не больше limit событий клиента с номером больше after
+func (dr *DataBaseRepositoryImpl) ListClientEvents(ctx context.Context, clientID int, after int64, limit int) ([]domain.TransactionEvent, error) {
+	events, err := loadClientEvents(ctx, dr.postgreClient, clientID, after, 0, limit)
+	if err != nil {
+		dr.logger.Error("Failed to list client events", zap.Error(err))
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// loadClientEvents собирает события из журнала в диапазоне (after, before), before = 0 - без верхней границы.
+// Статус транзакции в событии берется из журнала, то есть такой, каким он был на момент события
+func loadClientEvents(ctx context.Context, db orm.DB, clientID int, after int64, before int64, limit int) ([]domain.TransactionEvent, error) {
+	var journal []domain.ClientEvents
+	q := db.ModelContext(ctx, &journal).
+		Where("client_id = ?", clientID).
+		Where("sequence > ?", after).
+		Order("sequence ASC")
+	if before > 0 {
+		q = q.Where("sequence < ?", before)
+	}
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+
+	err := q.Select()
+	if err != nil {
+		return nil, err
+	}
+
+	return journalEvents(ctx, db, journal)
+}
+
+// journalEvents собирает события из записей журнала, транзакции загружаются одним запросом
+func journalEvents(ctx context.Context, db orm.DB, journal []domain.ClientEvents) ([]domain.TransactionEvent, error) {
+	if len(journal) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]int, 0, len(journal))
+	for _, e := range journal {
+		ids = append(ids, e.TransactionID)
+	}
+
+	var transactions []domain.Transactions
+	err := db.ModelContext(ctx, &transactions).Where("id IN (?)", pg.In(ids)).Select()
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]domain.Transactions, len(transactions))
+	for _, t := range transactions {
+		byID[t.ID] = t
+	}
+
+	events := make([]domain.TransactionEvent, 0, len(journal))
+	for _, e := range journal {
+		transaction, ok := byID[e.TransactionID]
+		if !ok {
+			continue
+		}
+		transaction.Status = e.Status
+
+		events = append(events, domain.TransactionEvent{
+			EventID:     e.EventID,
+			ClientID:    e.ClientID,
+			Sequence:    e.Sequence,
+			Transaction: &transaction,
+		})
+	}
+
+	return events, nil
+}
+
+// processEvent ставит в очередь вебхуки о событии. События из журнала, перенесенные миграцией, не имеют ID и пропускаются
+func (dr *DataBaseRepositoryImpl) processEvent(ctx context.Context, tx *pg.Tx, event *domain.TransactionEvent) error {
+	dr.logger.Info("Received transaction event",
+		zap.Int("client_id", event.ClientID), zap.Int64("sequence", event.Sequence),
+		zap.Int("transaction_id", event.Transaction.ID), zap.Float64("amount", event.Transaction.Amount))
+
+	webhookEvent, ok := transactionWebhookEvent(event)
+	if !ok || event.EventID == "" {
+		return nil
+	}
+
+	err := enqueueWebhooks(ctx, tx, webhookEvent, event.ClientID, event.Transaction.APIKeyID)
+	if err != nil {
+		return fmt.Errorf("enqueue webhooks: %w", err)
+	}
+
+	return nil
+}
@@ -3,20 +3,22 @@ package storage
 import (
 	"context"
 	"errors"
-	"fmt"
-	"github.com/gin-gonic/gin"
 	"github.com/go-pg/pg/v10"
-	"github.com/segmentio/kafka-go"
 	"go.uber.org/zap"
+	"sort"
 	"strconv"
+	"sync"
 	"time"
 	"transaction-system/internal/domain"
+	"transaction-system/pkg/eventbus"
+	"transaction-system/pkg/metrics"
 )
 
 type DataBaseRepositoryImpl struct {
 	postgreClient *pg.DB
-	producer      *kafka.Writer
-	consumer      *kafka.Reader
+	publisher     eventbus.EventPublisher
+	// currencyNames - кэш названий валют по id для меток метрик
+	currencyNames sync.Map
 	logger        *zap.Logger
 }
 
@@ -26,26 +28,23 @@ const (
 	ErrorStat   = "Error"
 )
 
-func NewDataBaseRepositoryImpl(postgreClient *pg.DB, producer *kafka.Writer, consumer *kafka.Reader, logger *zap.Logger) *DataBaseRepositoryImpl {
-	return &DataBaseRepositoryImpl{postgreClient: postgreClient, producer: producer, consumer: consumer, logger: logger}
+func NewDataBaseRepositoryImpl(postgreClient *pg.DB, publisher eventbus.EventPublisher, logger *zap.Logger) *DataBaseRepositoryImpl {
+	return &DataBaseRepositoryImpl{postgreClient: postgreClient, publisher: publisher, logger: logger}
 }
 
-func (dr *DataBaseRepositoryImpl) AddAmount(c *gin.Context, currencyCode int, amount float64, walletNumber int, cardNumber int) (*domain.Transactions, error) {
-	client, err := dr.findClientByRequisites(walletNumber, cardNumber)
+func (dr *DataBaseRepositoryImpl) AddAmount(ctx context.Context, currencyCode int, amount float64, walletNumber int, cardNumber int) (*domain.Transactions, error) {
+	client, err := dr.findClientByRequisites(ctx, walletNumber, cardNumber)
 	if err != nil {
 		dr.logger.Error("Failed to find client", zap.Error(err))
 		return nil, err
 	}
 
 	// Ищем айдишку валюты для транзакции
-	currency := &domain.Currencies{}
-	err = dr.postgreClient.Model(currency).Where("currency_code = ?", currencyCode).Select()
+	currencyID, err := dr.findCurrencyID(ctx, currencyCode)
 	if err != nil {
-		// Если валюта не найдена
-		dr.logger.Error("Currency not found")
-		return nil, errors.New("currency not found")
+		dr.logger.Error("Failed to find currency", zap.Error(err))
+		return nil, err
 	}
-	currencyID := currency.ID
 
 	transaction := &domain.Transactions{
 		Amount:     amount,
@@ -53,39 +52,30 @@ func (dr *DataBaseRepositoryImpl) AddAmount(c *gin.Context, currencyCode int, am
 		ClientID:   client.ID,
 		CurrencyID: currencyID,
 		Status:     CreatedStat,
+		APIKeyID:   domain.APIKeyIDFromContext(ctx),
 	}
 
-	err = dr.sendKafkaMessage(transaction)
-	if err != nil {
-		dr.logger.Error("Failed to write message to Kafka", zap.Error(err))
-		return nil, err
-	}
-
-	_, err = dr.postgreClient.Model(transaction).Insert()
+	err = dr.createTransaction(ctx, transaction)
 	if err != nil {
-		dr.logger.Error("Failed to insert transaction data", zap.Error(err))
 		return nil, err
 	}
 
 	return transaction, nil
 }
 
-func (dr *DataBaseRepositoryImpl) WithdrawAmount(c *gin.Context, currencyCode int, amount float64, walletNumber int, cardNumber int) (*domain.Transactions, error) {
-	client, err := dr.findClientByRequisites(walletNumber, cardNumber)
+func (dr *DataBaseRepositoryImpl) WithdrawAmount(ctx context.Context, currencyCode int, amount float64, walletNumber int, cardNumber int) (*domain.Transactions, error) {
+	client, err := dr.findClientByRequisites(ctx, walletNumber, cardNumber)
 	if err != nil {
 		dr.logger.Error("Failed to find client", zap.Error(err))
 		return nil, err
 	}
 
 	// Ищем айдишку валюты для транзакции
-	currency := &domain.Currencies{}
-	err = dr.postgreClient.Model(currency).Where("currency_code = ?", currencyCode).Select()
+	currencyID, err := dr.findCurrencyID(ctx, currencyCode)
 	if err != nil {
-		// Если валюта не найдена
-		dr.logger.Error("Currency not found")
-		return nil, errors.New("currency not found")
+		dr.logger.Error("Failed to find currency", zap.Error(err))
+		return nil, err
 	}
-	currencyID := currency.ID
 
 	transaction := &domain.Transactions{
 		Amount:     -amount,
@@ -93,33 +83,33 @@ func (dr *DataBaseRepositoryImpl) WithdrawAmount(c *gin.Context, currencyCode in
 		ClientID:   client.ID,
 		CurrencyID: currencyID,
 		Status:     CreatedStat,
+		APIKeyID:   domain.APIKeyIDFromContext(ctx),
 	}
 
-	err = dr.sendKafkaMessage(transaction)
-	if err != nil {
-		dr.logger.Error("Failed to write message to Kafka", zap.Error(err))
-		return nil, err
-	}
-
-	_, err = dr.postgreClient.Model(transaction).Insert()
+	err = dr.createTransaction(ctx, transaction)
 	if err != nil {
-		dr.logger.Error("Failed to insert transaction data", zap.Error(err))
 		return nil, err
 	}
 
 	return transaction, nil
 }
 
-func (dr *DataBaseRepositoryImpl) GetAvailableBalance(c *gin.Context, walletNumber int, cardNumber int) (float64, error) {
-	client, err := dr.findClientByRequisites(walletNumber, cardNumber) // Поиск клиента по номеру кошелька
+// GetAvailableBalance возвращает подтвержденный баланс одной суммой, поэтому работает только для клиента с одной валютой
+func (dr *DataBaseRepositoryImpl) GetAvailableBalance(ctx context.Context, walletNumber int, cardNumber int) (float64, error) {
+	client, err := dr.findClientByRequisites(ctx, walletNumber, cardNumber) // Поиск клиента по номеру кошелька
 	if err != nil {
 		dr.logger.Error("Failed to find client", zap.Error(err))
 		return 0, err
 	}
 
+	err = dr.checkSingleCurrency(ctx, client.ID)
+	if err != nil {
+		return 0, err
+	}
+
 	var totalAmount float64
-	_ = dr.postgreClient.Model((*domain.Transactions)(nil)).
-		ColumnExpr("SUM(amount)").
+	err = dr.postgreClient.Model((*domain.Transactions)(nil)).
+		ColumnExpr("COALESCE(SUM(amount), 0)").
 		Where("client_id = ?", client.ID).
 		Where("status = ?", SuccessStat).
 		Select(&totalAmount)
@@ -133,16 +123,22 @@ func (dr *DataBaseRepositoryImpl) GetAvailableBalance(c *gin.Context, walletNumb
 
 }
 
-func (dr *DataBaseRepositoryImpl) GetFrozenBalance(c *gin.Context, walletNumber int, cardNumber int) (float64, error) {
-	client, err := dr.findClientByRequisites(walletNumber, cardNumber) // Поиск клиента по номеру кошелька
+// GetFrozenBalance возвращает замороженный баланс одной суммой, поэтому работает только для клиента с одной валютой
+func (dr *DataBaseRepositoryImpl) GetFrozenBalance(ctx context.Context, walletNumber int, cardNumber int) (float64, error) {
+	client, err := dr.findClientByRequisites(ctx, walletNumber, cardNumber) // Поиск клиента по номеру кошелька
 	if err != nil {
 		dr.logger.Error("Failed to find client", zap.Error(err))
 		return 0, err
 	}
 
+	err = dr.checkSingleCurrency(ctx, client.ID)
+	if err != nil {
+		return 0, err
+	}
+
 	var totalAmount float64
-	_ = dr.postgreClient.Model((*domain.Transactions)(nil)).
-		ColumnExpr("SUM(amount)").
+	err = dr.postgreClient.Model((*domain.Transactions)(nil)).
+		ColumnExpr("COALESCE(SUM(amount), 0)").
 		Where("client_id = ?", client.ID).
 		Where("status = ?", CreatedStat).
 		Select(&totalAmount)
@@ -156,67 +152,228 @@ func (dr *DataBaseRepositoryImpl) GetFrozenBalance(c *gin.Context, walletNumber
 
 }
 
-func (dr *DataBaseRepositoryImpl) UpdateTransactionStatusToSuccess() error {
-	_, err := dr.postgreClient.Exec(`
-		UPDATE transactions
-		SET status = ?
-		WHERE status = ?`, SuccessStat, CreatedStat)
+func (dr *DataBaseRepositoryImpl) GetBalance(ctx context.Context, walletNumber int, cardNumber int) (*domain.Balance, error) {
+	client, err := dr.findClientByRequisites(ctx, walletNumber, cardNumber)
 	if err != nil {
-		dr.logger.Error("Failed to update transaction status", zap.Error(err))
+		dr.logger.Error("Failed to find client", zap.Error(err))
+		return nil, err
+	}
+
+	var currencies []domain.CurrencyBalance
+	_, err = dr.postgreClient.Query(&currencies, `
+		SELECT c.currency_code,
+		       c.currency_name,
+		       COALESCE(SUM(t.amount) FILTER (WHERE t.status = ?), 0) AS available,
+		       COALESCE(SUM(t.amount) FILTER (WHERE t.status = ?), 0) AS frozen
+		FROM transactions t
+		JOIN currencies c ON c.id = t.currency_id
+		WHERE t.client_id = ?
+		GROUP BY c.currency_code, c.currency_name
+		ORDER BY c.currency_code`, SuccessStat, CreatedStat, client.ID)
+	if err != nil {
+		dr.logger.Error("Failed to fetch balance", zap.Error(err))
+		return nil, err
+	}
+
+	return &domain.Balance{Currencies: currencies}, nil
+}
+
+// checkSingleCurrency возвращает ErrMultipleCurrencies, если у клиента есть транзакции в нескольких валютах:
+// суммы в разных валютах нельзя складывать в один баланс
+func (dr *DataBaseRepositoryImpl) checkSingleCurrency(ctx context.Context, clientID int) error {
+	var currencies int
+	err := dr.postgreClient.ModelContext(ctx, (*domain.Transactions)(nil)).
+		ColumnExpr("COUNT(DISTINCT currency_id)").
+		Where("client_id = ?", clientID).
+		Select(&currencies)
+	if err != nil {
+		dr.logger.Error("Failed to count client currencies", zap.Error(err))
 		return err
 	}
-	dr.logger.Info("Transaction status updated successfully")
+
+	if currencies > 1 {
+		return ErrMultipleCurrencies
+	}
+
 	return nil
 }
 
-func (dr *DataBaseRepositoryImpl) findClientByRequisites(walletNumber int, cardNumber int) (*domain.Clients, error) {
+func (dr *DataBaseRepositoryImpl) GetTransaction(ctx context.Context, id int) (*domain.Transactions, error) {
+	transaction := &domain.Transactions{}
+	err := dr.postgreClient.ModelContext(ctx, transaction).
+		Relation("Client").
+		Where("transactions.id = ?", id).
+		Select()
+	if errors.Is(err, pg.ErrNoRows) {
+		return nil, ErrTransactionNotFound
+	}
+	if err != nil {
+		dr.logger.Error("Failed to fetch transaction", zap.Error(err))
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// UpdateTransactionStatusToSuccess проводит созданные транзакции и по каждой записывает в журнал событие о смене статуса
+// с очередным номером в последовательности клиента, события публикуются после коммита. Возвращает число проведенных транзакций
+func (dr *DataBaseRepositoryImpl) UpdateTransactionStatusToSuccess(ctx context.Context) (int, error) {
+	var events []*domain.TransactionEvent
+
+	err := dr.postgreClient.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		var updated []domain.Transactions
+		_, err := tx.Query(&updated, `
+			UPDATE transactions
+			SET status = ?
+			WHERE status = ?
+			RETURNING *`, SuccessStat, CreatedStat)
+		if err != nil {
+			return err
+		}
+
+		// Счетчики клиентов блокируются в одном порядке, чтобы не ловить взаимоблокировки с createTransaction
+		sort.Slice(updated, func(i, j int) bool {
+			if updated[i].ClientID != updated[j].ClientID {
+				return updated[i].ClientID < updated[j].ClientID
+			}
+			return updated[i].ID < updated[j].ID
+		})
+
+		events = make([]*domain.TransactionEvent, 0, len(updated))
+		for i := range updated {
+			var sequence int64
+			_, err = tx.QueryOne(pg.Scan(&sequence), `
+				UPDATE clients
+				SET event_seq = event_seq + 1
+				WHERE id = ?
+				RETURNING event_seq`, updated[i].ClientID)
+			if err != nil {
+				return err
+			}
+
+			event, err := dr.recordEvent(tx, &updated[i], sequence)
+			if err != nil {
+				return err
+			}
+			events = append(events, event)
+		}
+
+		return nil
+	})
+	if err != nil {
+		dr.logger.Error("Failed to update transaction status", zap.Error(err))
+		return 0, err
+	}
+
+	dr.publishCommitted(ctx, events...)
+
+	for _, event := range events {
+		metrics.TransactionsSettled.WithLabelValues(dr.currencyName(event.Transaction.CurrencyID), event.Transaction.Status).Inc()
+	}
+
+	dr.logger.Info("Transaction status updated successfully", zap.Int("count", len(events)))
+	return len(events), nil
+}
+
+// FindClient ищет клиента по номеру кошелька или карты
+func (dr *DataBaseRepositoryImpl) FindClient(ctx context.Context, walletNumber int, cardNumber int) (*domain.Clients, error) {
+	return dr.findClientByRequisites(ctx, walletNumber, cardNumber)
+}
+
+// findClientByRequisites ищет клиента сначала по кошельку, затем по карте. ErrClientNotFound возвращается,
+// только если клиента нет ни по одному реквизиту, ошибки БД возвращаются как есть
+func (dr *DataBaseRepositoryImpl) findClientByRequisites(ctx context.Context, walletNumber int, cardNumber int) (*domain.Clients, error) {
 	client := &domain.Clients{}
 
 	if walletNumber != 0 {
-		err := dr.postgreClient.Model(client).Where("wallet_number = ?", walletNumber).Select()
+		err := dr.postgreClient.ModelContext(ctx, client).Where("wallet_number = ?", walletNumber).Select()
 		if err == nil {
 			return client, nil
 		}
+		if !errors.Is(err, pg.ErrNoRows) {
+			return nil, err
+		}
 	}
 
 	if cardNumber != 0 {
-		err := dr.postgreClient.Model(client).Where("card_number = ?", cardNumber).Select()
+		err := dr.postgreClient.ModelContext(ctx, client).Where("card_number = ?", cardNumber).Select()
 		if err == nil {
 			return client, nil
 		}
+		if !errors.Is(err, pg.ErrNoRows) {
+			return nil, err
+		}
 	}
 
-	return nil, errors.New("client not found")
+	return nil, ErrClientNotFound
 }
 
-func (dr *DataBaseRepositoryImpl) sendKafkaMessage(transaction *domain.Transactions) error {
-	ctx := context.Background()
-	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
+func (dr *DataBaseRepositoryImpl) findCurrencyID(ctx context.Context, currencyCode int) (int, error) {
+	currency := &domain.Currencies{}
+	err := dr.postgreClient.ModelContext(ctx, currency).Where("currency_code = ?", currencyCode).Select()
+	if errors.Is(err, pg.ErrNoRows) {
+		return 0, ErrCurrencyNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
 
-	return dr.producer.WriteMessages(ctx, kafka.Message{
-		Key:   []byte(strconv.Itoa(transaction.ID)),
-		Value: []byte(fmt.Sprintf("%v", transaction)),
-	})
+	dr.currencyNames.Store(currency.ID, currency.CurrencyName)
+	return currency.ID, nil
 }
 
-func (dr *DataBaseRepositoryImpl) ReadFromKafka() error {
-	for {
-		m, err := dr.consumer.ReadMessage(context.Background())
-		if err != nil {
-			dr.logger.Error("Failed to read message from Kafka", zap.Error(err))
-			return err
-		}
-		dr.logger.Info("Received message from Kafka", zap.String("key", string(m.Key)), zap.String("value", string(m.Value)))
+// currencyName - название валюты для метки метрик. При промахе кэш перечитывается из справочника целиком,
+// если валюта так и не найдена, возвращается ее id
+func (dr *DataBaseRepositoryImpl) currencyName(id int) string {
+	if name, ok := dr.currencyNames.Load(id); ok {
+		return name.(string)
+	}
+
+	var currencies []domain.Currencies
+	err := dr.postgreClient.Model(&currencies).Select()
+	if err != nil {
+		dr.logger.Warn("Failed to load currencies", zap.Error(err))
 	}
+	for _, currency := range currencies {
+		dr.currencyNames.Store(currency.ID, currency.CurrencyName)
+	}
+
+	if name, ok := dr.currencyNames.Load(id); ok {
+		return name.(string)
+	}
+
+	return strconv.Itoa(id)
 }
 
-func (dr *DataBaseRepositoryImpl) getCurrentBalance(clientID int) (float64, error) {
+// PendingTransactions - число транзакций в статусе Created и возраст самой старой из них по валютам
+func (dr *DataBaseRepositoryImpl) PendingTransactions(ctx context.Context) ([]metrics.PendingTransactions, error) {
+	var pending []metrics.PendingTransactions
+	_, err := dr.postgreClient.QueryContext(ctx, &pending, `
+		SELECT c.currency_name AS currency,
+		       count(*) AS count,
+		       EXTRACT(EPOCH FROM now() - min(t.created_at)) AS oldest_age
+		FROM transactions t
+		JOIN currencies c ON c.id = t.currency_id
+		WHERE t.status = ?
+		GROUP BY c.currency_name`, CreatedStat)
+	if err != nil {
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+// getSpendableBalance - подтвержденный баланс клиента в валюте за вычетом еще не проведенных списаний
+func (dr *DataBaseRepositoryImpl) getSpendableBalance(tx *pg.Tx, clientID int, currencyID int) (float64, error) {
 	var totalAmount float64
-	err := dr.postgreClient.Model((*domain.Transactions)(nil)).
+	err := tx.Model((*domain.Transactions)(nil)).
 		ColumnExpr("COALESCE(SUM(amount), 0)").
 		Where("client_id = ?", clientID).
-		Where("status = ?", SuccessStat).
+		Where("currency_id = ?", currencyID).
+		WhereGroup(func(q *pg.Query) (*pg.Query, error) {
+			return q.Where("status = ?", SuccessStat).
+				WhereOr("status = ? AND amount < 0", CreatedStat), nil
+		}).
 		Select(&totalAmount)
 	if err != nil {
 		return 0, err
@@ -0,0 +1,192 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"github.com/go-pg/pg/v10"
+	"go.uber.org/zap"
+	"time"
+	"transaction-system/internal/domain"
+)
+
+const (
+	uniqueViolation           = "23505"
+	transactionOperationIndex = "transactions_operation_id_key"
+)
+
+// CreateOperation сохраняет операцию. Если операция с таким ключом идемпотентности у этого API-ключа
+// уже есть, возвращается она, а второе значение равно false
+func (dr *DataBaseRepositoryImpl) CreateOperation(ctx context.Context, operation *domain.Operations) (*domain.Operations, bool, error) {
+	res, err := dr.postgreClient.ModelContext(ctx, operation).
+		OnConflict("(COALESCE(api_key_id, 0), idempotency_key) WHERE idempotency_key IS NOT NULL DO NOTHING").
+		Insert()
+	if err != nil {
+		dr.logger.Error("Failed to insert operation", zap.Error(err))
+		return nil, false, err
+	}
+
+	if res.RowsAffected() > 0 {
+		return operation, true, nil
+	}
+
+	existing := &domain.Operations{}
+	err = dr.postgreClient.ModelContext(ctx, existing).
+		Where("COALESCE(api_key_id, 0) = ?", operation.APIKeyID).
+		Where("idempotency_key = ?", operation.IdempotencyKey).
+		Select()
+	if err != nil {
+		dr.logger.Error("Failed to select existing operation", zap.Error(err))
+		return nil, false, err
+	}
+
+	return existing, false, nil
+}
+
+// DeleteOperation удаляет синхронную операцию, которая не была проведена, чтобы повтор с тем же ключом
+// идемпотентности провел ее заново. Операция с транзакцией не удаляется
+func (dr *DataBaseRepositoryImpl) DeleteOperation(ctx context.Context, id int) error {
+	_, err := dr.postgreClient.ExecContext(ctx, `
+		DELETE FROM operations
+		WHERE id = ?
+		  AND NOT EXISTS (SELECT 1 FROM transactions WHERE operation_id = ?)`, id, id)
+	if err != nil {
+		dr.logger.Error("Failed to delete operation", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (dr *DataBaseRepositoryImpl) GetOperation(ctx context.Context, id int) (*domain.Operations, error) {
+	operation := &domain.Operations{ID: id}
+	err := dr.postgreClient.ModelContext(ctx, operation).WherePK().Select()
+	if errors.Is(err, pg.ErrNoRows) {
+		return nil, ErrOperationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return operation, nil
+}
+
+// ClaimPendingOperation переводит в Processing самую старую операцию, готовую к проведению, и возвращает ее.
+// Операции, зависшие в Processing дольше staleAfter (например, после падения сервиса), забираются повторно.
+// Если проводить нечего, возвращается nil без ошибки
+func (dr *DataBaseRepositoryImpl) ClaimPendingOperation(ctx context.Context, staleAfter time.Duration) (*domain.Operations, error) {
+	operation := &domain.Operations{}
+	_, err := dr.postgreClient.QueryOneContext(ctx, operation, `
+		UPDATE operations
+		SET status = ?, updated_at = now()
+		WHERE id = (
+			SELECT id
+			FROM operations
+			WHERE (status = ? AND (next_attempt_at IS NULL OR next_attempt_at <= now()))
+			   OR (status = ? AND updated_at < now() - ? * interval '1 second')
+			ORDER BY id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		domain.OperationProcessing, domain.OperationPending, domain.OperationProcessing, staleAfter.Seconds())
+	if errors.Is(err, pg.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		dr.logger.Error("Failed to claim pending operation", zap.Error(err))
+		return nil, err
+	}
+
+	return operation, nil
+}
+
+// SettleOperation создает транзакцию по операции. Если транзакция по ней уже была создана,
+// возвращается существующая: уникальный operation_id не дает провести операцию дважды
+func (dr *DataBaseRepositoryImpl) SettleOperation(ctx context.Context, operation *domain.Operations) (*domain.Transactions, error) {
+	client, err := dr.findClientByRequisites(ctx, operation.WalletNumber, operation.CardNumber)
+	if err != nil {
+		dr.logger.Error("Failed to find client", zap.Error(err))
+		return nil, err
+	}
+
+	currencyID, err := dr.findCurrencyID(ctx, operation.CurrencyCode)
+	if err != nil {
+		dr.logger.Error("Failed to find currency", zap.Error(err))
+		return nil, err
+	}
+
+	amount := operation.Amount
+	if operation.Operation == domain.OperationWithdraw {
+		amount = -amount
+	}
+
+	transaction := &domain.Transactions{
+		Amount:      amount,
+		CreatedAt:   time.Now(),
+		ClientID:    client.ID,
+		CurrencyID:  currencyID,
+		Status:      CreatedStat,
+		APIKeyID:    operation.APIKeyID,
+		OperationID: operation.ID,
+	}
+
+	err = dr.createTransaction(ctx, transaction)
+	if isUniqueViolation(err, transactionOperationIndex) {
+		existing := &domain.Transactions{}
+		err = dr.postgreClient.ModelContext(ctx, existing).Where("operation_id = ?", operation.ID).Select()
+		if err != nil {
+			return nil, err
+		}
+		return existing, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// FinishOperation сохраняет результат проведения. Для операции, завершенной с ошибкой, ставятся в очередь вебхуки operation.failed
+func (dr *DataBaseRepositoryImpl) FinishOperation(ctx context.Context, operation *domain.Operations) error {
+	operation.UpdatedAt = time.Now()
+
+	var clientID int
+	if operation.Status == domain.OperationFailed {
+		// Клиента может не существовать: такая ошибка и привела к Failed, тогда остаются подписки API-ключа
+		client, err := dr.findClientByRequisites(ctx, operation.WalletNumber, operation.CardNumber)
+		if err == nil {
+			clientID = client.ID
+		}
+	}
+
+	err := dr.postgreClient.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		_, err := tx.ModelContext(ctx, operation).
+			Column("status", "transaction_id", "error_code", "error", "attempts", "next_attempt_at", "updated_at").
+			WherePK().
+			Update()
+		if err != nil {
+			return err
+		}
+
+		if operation.Status != domain.OperationFailed {
+			return nil
+		}
+
+		return enqueueWebhooks(ctx, tx, operationWebhookEvent(operation), clientID, operation.APIKeyID)
+	})
+	if err != nil {
+		dr.logger.Error("Failed to update operation", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func isUniqueViolation(err error, constraint string) bool {
+	var pgErr pg.Error
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+
+	return pgErr.Field('C') == uniqueViolation && pgErr.Field('n') == constraint
+}
@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"context"
+	"github.com/go-pg/pg/v10"
+	"go.uber.org/zap"
+	"time"
+	"transaction-system/internal/domain"
+)
+
+const (
+	outboxBatchSize = 100
+	// Событиями моложе этого срока еще занимается publishCommitted запроса, который их создал
+	outboxRelayDelay = 5 * time.Second
+)
+
+// publishCommitted публикует события закоммиченной транзакции и отмечает их в журнале отправленными.
+// Неудача только логируется: события остаются в журнале неотправленными, их доставит RelayEvents
+func (dr *DataBaseRepositoryImpl) publishCommitted(ctx context.Context, events ...*domain.TransactionEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	err := dr.publishEvents(ctx, events...)
+	if err != nil {
+		dr.logger.Warn("Failed to publish transaction events, relay will retry", zap.Int("count", len(events)), zap.Error(err))
+		return
+	}
+
+	err = markPublished(ctx, dr.postgreClient, events...)
+	if err != nil {
+		dr.logger.Warn("Failed to mark transaction events as published", zap.Error(err))
+	}
+}
+
+// RelayEvents публикует события журнала, которые не были отправлены после коммита: брокер был недоступен
+// или процесс упал между коммитом и публикацией. Строки журнала блокируются с SKIP LOCKED, поэтому
+// экземпляры сервиса не отправляют одни и те же события одновременно. Возвращает число отправленных событий
+func (dr *DataBaseRepositoryImpl) RelayEvents(ctx context.Context) (int, error) {
+	var count int
+
+	err := dr.postgreClient.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		var journal []domain.ClientEvents
+		err := tx.ModelContext(ctx, &journal).
+			Where("published_at IS NULL").
+			Where("created_at < ?", time.Now().Add(-outboxRelayDelay)).
+			Order("client_id ASC", "sequence ASC").
+			Limit(outboxBatchSize).
+			For("UPDATE SKIP LOCKED").
+			Select()
+		if err != nil {
+			return err
+		}
+
+		loaded, err := journalEvents(ctx, tx, journal)
+		if err != nil {
+			return err
+		}
+
+		events := make([]*domain.TransactionEvent, 0, len(loaded))
+		for i := range loaded {
+			events = append(events, &loaded[i])
+		}
+
+		err = dr.publishEvents(ctx, events...)
+		if err != nil {
+			return err
+		}
+
+		// Строки журнала без транзакции отправить нечем. Если оставить их неотправленными, они каждый раз
+		// будут занимать место в выборке и со временем остановят отправку
+		orphans := dr.orphanEvents(journal, loaded)
+
+		count = len(events)
+		return markPublished(ctx, tx, append(events, orphans...)...)
+	})
+	if err != nil {
+		dr.logger.Error("Failed to relay transaction events", zap.Error(err))
+		return 0, err
+	}
+
+	if count > 0 {
+		dr.logger.Info("Transaction events relayed", zap.Int("count", count))
+	}
+	return count, nil
+}
+
+// orphanEvents - строки журнала, для которых не нашлась транзакция. Они логируются и отмечаются отправленными вместе с остальными
+func (dr *DataBaseRepositoryImpl) orphanEvents(journal []domain.ClientEvents, loaded []domain.TransactionEvent) []*domain.TransactionEvent {
+	if len(journal) == len(loaded) {
+		return nil
+	}
+
+	type key struct {
+		clientID int
+		sequence int64
+	}
+	found := make(map[key]struct{}, len(loaded))
+	for _, event := range loaded {
+		found[key{event.ClientID, event.Sequence}] = struct{}{}
+	}
+
+	var orphans []*domain.TransactionEvent
+	for _, e := range journal {
+		if _, ok := found[key{e.ClientID, e.Sequence}]; ok {
+			continue
+		}
+
+		dr.logger.Error("Skipping journal event without transaction",
+			zap.Int("client_id", e.ClientID), zap.Int64("sequence", e.Sequence), zap.Int("transaction_id", e.TransactionID))
+		orphans = append(orphans, &domain.TransactionEvent{ClientID: e.ClientID, Sequence: e.Sequence})
+	}
+
+	return orphans
+}
+
+// markPublished отмечает события отправленными, строки журнала ищутся по первичному ключу
+func markPublished(ctx context.Context, db pg.DBI, events ...*domain.TransactionEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	keys := make([]interface{}, 0, len(events))
+	for _, event := range events {
+		keys = append(keys, []interface{}{event.ClientID, event.Sequence})
+	}
+
+	_, err := db.ExecContext(ctx, `
+		UPDATE client_events
+		SET published_at = now()
+		WHERE (client_id, sequence) IN (?) AND published_at IS NULL`, pg.InMulti(keys...))
+	return err
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"transaction-system/internal/domain"
+)
+
+// Reconcile сверяет данные, которые сервис поддерживает согласованными, и возвращает найденные нарушения:
+// отрицательный доступный баланс, счетчик событий клиента позади журнала, завершенные операции без транзакции
+// и возвраты, не совпадающие с исходной транзакцией
+func (dr *DataBaseRepositoryImpl) Reconcile(ctx context.Context) ([]domain.Discrepancy, error) {
+	var discrepancies []domain.Discrepancy
+
+	var balances []struct {
+		ClientID   int
+		CurrencyID int
+		Balance    float64
+	}
+	_, err := dr.postgreClient.QueryContext(ctx, &balances, `
+		SELECT client_id, currency_id, SUM(amount) AS balance
+		FROM transactions
+		WHERE status = ? OR (status = ? AND amount < 0)
+		GROUP BY client_id, currency_id
+		HAVING SUM(amount) < 0
+		ORDER BY client_id, currency_id`, SuccessStat, CreatedStat)
+	if err != nil {
+		dr.logger.Error("Failed to reconcile balances", zap.Error(err))
+		return nil, err
+	}
+	for _, b := range balances {
+		discrepancies = append(discrepancies, domain.Discrepancy{
+			Check:    domain.CheckNegativeBalance,
+			ClientID: b.ClientID,
+			Detail:   fmt.Sprintf("currency %d balance %.2f", b.CurrencyID, b.Balance),
+		})
+	}
+
+	var sequences []struct {
+		ID          int
+		EventSeq    int64
+		MaxSequence int64
+	}
+	_, err = dr.postgreClient.QueryContext(ctx, &sequences, `
+		SELECT c.id, c.event_seq, MAX(e.sequence) AS max_sequence
+		FROM clients c
+		JOIN client_events e ON e.client_id = c.id
+		GROUP BY c.id, c.event_seq
+		HAVING MAX(e.sequence) > c.event_seq
+		ORDER BY c.id`)
+	if err != nil {
+		dr.logger.Error("Failed to reconcile event sequences", zap.Error(err))
+		return nil, err
+	}
+	for _, s := range sequences {
+		discrepancies = append(discrepancies, domain.Discrepancy{
+			Check:    domain.CheckEventSequence,
+			ClientID: s.ID,
+			Detail:   fmt.Sprintf("event_seq %d is behind journal sequence %d", s.EventSeq, s.MaxSequence),
+		})
+	}
+
+	var operations []domain.Operations
+	_, err = dr.postgreClient.QueryContext(ctx, &operations, `
+		SELECT o.*
+		FROM operations o
+		LEFT JOIN transactions t ON t.id = o.transaction_id
+		WHERE o.status = ? AND t.id IS NULL
+		ORDER BY o.id`, domain.OperationCompleted)
+	if err != nil {
+		dr.logger.Error("Failed to reconcile operations", zap.Error(err))
+		return nil, err
+	}
+	for _, op := range operations {
+		discrepancies = append(discrepancies, domain.Discrepancy{
+			Check:  domain.CheckOperationTransaction,
+			ID:     op.ID,
+			Detail: fmt.Sprintf("completed operation references missing transaction %d", op.TransactionID),
+		})
+	}
+
+	var refunds []domain.Transactions
+	_, err = dr.postgreClient.QueryContext(ctx, &refunds, `
+		SELECT r.*
+		FROM transactions r
+		JOIN transactions o ON o.id = r.refund_of
+		WHERE r.amount <> -o.amount OR r.client_id <> o.client_id OR r.currency_id <> o.currency_id
+		ORDER BY r.id`)
+	if err != nil {
+		dr.logger.Error("Failed to reconcile refunds", zap.Error(err))
+		return nil, err
+	}
+	for _, r := range refunds {
+		discrepancies = append(discrepancies, domain.Discrepancy{
+			Check:    domain.CheckRefundMismatch,
+			ClientID: r.ClientID,
+			ID:       r.ID,
+			Detail:   fmt.Sprintf("refund does not mirror transaction %d", r.RefundOf),
+		})
+	}
+
+	return discrepancies, nil
+}
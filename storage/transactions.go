@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"github.com/go-pg/pg/v10"
+	"go.uber.org/zap"
+	"time"
+	"transaction-system/internal/domain"
+)
+
+const transactionRefundIndex = "transactions_refund_of_key"
+
+// ListTransactions - транзакции клиента по фильтру, новые первыми
+func (dr *DataBaseRepositoryImpl) ListTransactions(ctx context.Context, filter domain.TransactionFilter) ([]domain.Transactions, error) {
+	client, err := dr.findClientByRequisites(ctx, filter.WalletNumber, filter.CardNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	var transactions []domain.Transactions
+	query := dr.postgreClient.ModelContext(ctx, &transactions).
+		Where("client_id = ?", client.ID).
+		Order("id DESC").
+		Limit(filter.Limit)
+	if filter.Status != "" {
+		query.Where("status = ?", filter.Status)
+	}
+	if filter.BeforeID != 0 {
+		query.Where("id < ?", filter.BeforeID)
+	}
+
+	err = query.Select()
+	if err != nil {
+		dr.logger.Error("Failed to list transactions", zap.Error(err))
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
+// RefundTransaction создает возврат - транзакцию на ту же сумму с обратным знаком. Вернуть можно только проведенную
+// транзакцию своего API-ключа, администратор возвращает любые. Чужая транзакция не отличается от несуществующей.
+// Возврат проходит через те же проверки баланса, что и списание. Уникальный refund_of не дает вернуть транзакцию дважды
+func (dr *DataBaseRepositoryImpl) RefundTransaction(ctx context.Context, id int) (*domain.Transactions, error) {
+	original, err := dr.GetTransaction(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !domain.IsAdminFromContext(ctx) {
+		apiKeyID := domain.APIKeyIDFromContext(ctx)
+		if apiKeyID == 0 || original.APIKeyID != apiKeyID {
+			return nil, ErrTransactionNotFound
+		}
+	}
+
+	if original.Status != SuccessStat || original.RefundOf != 0 {
+		return nil, ErrNotRefundable
+	}
+
+	refund := &domain.Transactions{
+		Amount:     -original.Amount,
+		CreatedAt:  time.Now(),
+		ClientID:   original.ClientID,
+		CurrencyID: original.CurrencyID,
+		Status:     CreatedStat,
+		APIKeyID:   domain.APIKeyIDFromContext(ctx),
+		RefundOf:   original.ID,
+	}
+
+	err = dr.createTransaction(ctx, refund)
+	if isUniqueViolation(err, transactionRefundIndex) {
+		return nil, ErrAlreadyRefunded
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return refund, nil
+}
+
+// CreateClient заводит клиента. Кошелек и карта не должны принадлежать другому клиенту
+func (dr *DataBaseRepositoryImpl) CreateClient(ctx context.Context, client *domain.Clients) error {
+	err := dr.postgreClient.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		// Таблица клиентов блокируется до конца транзакции, чтобы параллельные запросы не завели одинаковые реквизиты
+		_, err := tx.ExecContext(ctx, `LOCK TABLE clients IN SHARE ROW EXCLUSIVE MODE`)
+		if err != nil {
+			return err
+		}
+
+		query := tx.ModelContext(ctx, &domain.Clients{}).Where("wallet_number = ?", client.WalletNumber)
+		if client.CardNumber != 0 {
+			query.WhereOr("card_number = ?", client.CardNumber)
+		}
+
+		exists, err := query.Exists()
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrClientExists
+		}
+
+		_, err = tx.ModelContext(ctx, client).Insert()
+		return err
+	})
+	if err != nil && !errors.Is(err, ErrClientExists) {
+		dr.logger.Error("Failed to create client", zap.Error(err))
+	}
+
+	return err
+}
@@ -0,0 +1,262 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	"go.uber.org/zap"
+	"strconv"
+	"time"
+	"transaction-system/internal/domain"
+)
+
+func (dr *DataBaseRepositoryImpl) CreateWebhook(ctx context.Context, hook *domain.Webhooks) error {
+	_, err := dr.postgreClient.ModelContext(ctx, hook).Insert()
+	if err != nil {
+		dr.logger.Error("Failed to insert webhook", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// ListWebhooks возвращает подписки API-ключа apiKeyID и клиента clientID, нулевые значения не ограничивают выборку
+func (dr *DataBaseRepositoryImpl) ListWebhooks(ctx context.Context, apiKeyID int, clientID int) ([]domain.Webhooks, error) {
+	var hooks []domain.Webhooks
+	q := dr.postgreClient.ModelContext(ctx, &hooks).Order("id ASC")
+	if apiKeyID != 0 {
+		q = q.Where("api_key_id = ?", apiKeyID)
+	}
+	if clientID != 0 {
+		q = q.Where("client_id = ?", clientID)
+	}
+
+	err := q.Select()
+	if err != nil {
+		dr.logger.Error("Failed to list webhooks", zap.Error(err))
+		return nil, err
+	}
+
+	return hooks, nil
+}
+
+func (dr *DataBaseRepositoryImpl) GetWebhook(ctx context.Context, id int) (*domain.Webhooks, error) {
+	hook := &domain.Webhooks{ID: id}
+	err := dr.postgreClient.ModelContext(ctx, hook).WherePK().Select()
+	if errors.Is(err, pg.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return hook, nil
+}
+
+// UpdateWebhookSecret заменяет секрет подписи. Доставки в очереди будут подписаны новым секретом
+func (dr *DataBaseRepositoryImpl) UpdateWebhookSecret(ctx context.Context, id int, secret string) (*domain.Webhooks, error) {
+	hook := &domain.Webhooks{ID: id}
+	res, err := dr.postgreClient.ModelContext(ctx, hook).
+		Set("secret = ?", secret).
+		WherePK().
+		Returning("*").
+		Update()
+	if err != nil {
+		dr.logger.Error("Failed to update webhook secret", zap.Error(err))
+		return nil, err
+	}
+
+	if res.RowsAffected() == 0 {
+		return nil, ErrWebhookNotFound
+	}
+
+	return hook, nil
+}
+
+// DeleteWebhook удаляет подписку вместе с журналом ее доставок
+func (dr *DataBaseRepositoryImpl) DeleteWebhook(ctx context.Context, id int) error {
+	res, err := dr.postgreClient.ModelContext(ctx, &domain.Webhooks{ID: id}).WherePK().Delete()
+	if err != nil {
+		dr.logger.Error("Failed to delete webhook", zap.Error(err))
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// ListWebhookDeliveries возвращает последние доставки подписки с журналом попыток, status пустой - все статусы
+func (dr *DataBaseRepositoryImpl) ListWebhookDeliveries(ctx context.Context, webhookID int, status string, limit int) ([]domain.WebhookDeliveries, error) {
+	var deliveries []domain.WebhookDeliveries
+	q := dr.postgreClient.ModelContext(ctx, &deliveries).
+		Relation("Log", func(q *pg.Query) (*pg.Query, error) {
+			return q.Order("attempt ASC"), nil
+		}).
+		Where("webhook_id = ?", webhookID).
+		Order("id DESC").
+		Limit(limit)
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+
+	err := q.Select()
+	if err != nil {
+		dr.logger.Error("Failed to list webhook deliveries", zap.Error(err))
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// ClaimWebhookDelivery переводит в Sending самую старую доставку, готовую к отправке, и возвращает ее вместе с подпиской.
+// Доставки, зависшие в Sending дольше staleAfter, забираются повторно. Если отправлять нечего, возвращается nil без ошибки
+func (dr *DataBaseRepositoryImpl) ClaimWebhookDelivery(ctx context.Context, staleAfter time.Duration) (*domain.WebhookDeliveries, error) {
+	delivery := &domain.WebhookDeliveries{}
+	_, err := dr.postgreClient.QueryOneContext(ctx, delivery, `
+		UPDATE webhook_deliveries
+		SET status = ?, updated_at = now()
+		WHERE id = (
+			SELECT id
+			FROM webhook_deliveries
+			WHERE (status = ? AND (next_attempt_at IS NULL OR next_attempt_at <= now()))
+			   OR (status = ? AND updated_at < now() - ? * interval '1 second')
+			ORDER BY id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		domain.WebhookDeliverySending, domain.WebhookDeliveryPending, domain.WebhookDeliverySending, staleAfter.Seconds())
+	if errors.Is(err, pg.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		dr.logger.Error("Failed to claim webhook delivery", zap.Error(err))
+		return nil, err
+	}
+
+	delivery.Webhook, err = dr.GetWebhook(ctx, delivery.WebhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// FinishWebhookDelivery сохраняет результат попытки доставки и добавляет ее в журнал
+func (dr *DataBaseRepositoryImpl) FinishWebhookDelivery(ctx context.Context, delivery *domain.WebhookDeliveries, attempt *domain.WebhookAttempts) error {
+	delivery.UpdatedAt = time.Now()
+	err := dr.postgreClient.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		_, err := tx.ModelContext(ctx, delivery).
+			Column("status", "attempts", "next_attempt_at", "last_status_code", "last_error", "updated_at", "delivered_at").
+			WherePK().
+			Update()
+		if err != nil {
+			return err
+		}
+
+		attempt.DeliveryID = delivery.ID
+		_, err = tx.ModelContext(ctx, attempt).Insert()
+		return err
+	})
+	if err != nil {
+		dr.logger.Error("Failed to save webhook delivery result", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// ResendWebhookDelivery возвращает доставку в очередь с новым запасом попыток
+func (dr *DataBaseRepositoryImpl) ResendWebhookDelivery(ctx context.Context, webhookID int, deliveryID int) (*domain.WebhookDeliveries, error) {
+	delivery := &domain.WebhookDeliveries{}
+	_, err := dr.postgreClient.QueryOneContext(ctx, delivery, `
+		UPDATE webhook_deliveries
+		SET status = ?, attempts = 0, next_attempt_at = now(), updated_at = now()
+		WHERE id = ? AND webhook_id = ? AND status <> ?
+		RETURNING *`,
+		domain.WebhookDeliveryPending, deliveryID, webhookID, domain.WebhookDeliverySending)
+	if errors.Is(err, pg.ErrNoRows) {
+		return nil, ErrWebhookDeliveryNotFound
+	}
+	if err != nil {
+		dr.logger.Error("Failed to resend webhook delivery", zap.Error(err))
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// enqueueWebhooks создает доставки события для подписок клиента и API-ключа, которые на него подписаны.
+// Вызывается в транзакции БД, в которой событие фиксируется, поэтому событие не теряется и не дублируется
+func enqueueWebhooks(ctx context.Context, db orm.DB, event domain.WebhookEvent, clientID int, apiKeyID int) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload, status, attempts, next_attempt_at, created_at, updated_at)
+		SELECT id, ?, ?, ?, ?, 0, now(), now(), now()
+		FROM webhooks
+		WHERE (client_id = ? OR api_key_id = ?)
+		  AND (cardinality(events) = 0 OR ? = ANY(events))
+		ON CONFLICT (webhook_id, event_id) DO NOTHING`,
+		event.ID, event.Type, string(payload), domain.WebhookDeliveryPending, clientID, apiKeyID, event.Type)
+
+	return err
+}
+
+// transactionWebhookEvent - событие вебхука о смене статуса транзакции
+func transactionWebhookEvent(event *domain.TransactionEvent) (domain.WebhookEvent, bool) {
+	var eventType string
+	switch event.Transaction.Status {
+	case CreatedStat:
+		eventType = domain.WebhookTransactionCreated
+	case SuccessStat:
+		eventType = domain.WebhookTransactionSettled
+	case ErrorStat:
+		eventType = domain.WebhookTransactionFailed
+	default:
+		return domain.WebhookEvent{}, false
+	}
+
+	t := event.Transaction
+	return domain.WebhookEvent{
+		ID:        event.EventID,
+		Type:      eventType,
+		CreatedAt: time.Now(),
+		Data: domain.WebhookTransaction{
+			TransactionID: t.ID,
+			ClientID:      t.ClientID,
+			Sequence:      event.Sequence,
+			Status:        t.Status,
+			Amount:        t.Amount,
+			CurrencyID:    t.CurrencyID,
+			OperationID:   t.OperationID,
+			CreatedAt:     t.CreatedAt,
+		},
+	}, true
+}
+
+func operationWebhookEvent(op *domain.Operations) domain.WebhookEvent {
+	return domain.WebhookEvent{
+		ID:        "operation-" + strconv.Itoa(op.ID) + "-failed",
+		Type:      domain.WebhookOperationFailed,
+		CreatedAt: time.Now(),
+		Data: domain.WebhookOperation{
+			OperationID:  op.ID,
+			Operation:    op.Operation,
+			CurrencyCode: op.CurrencyCode,
+			Amount:       op.Amount,
+			WalletNumber: op.WalletNumber,
+			CardNumber:   op.CardNumber,
+			ErrorCode:    op.ErrorCode,
+			Error:        op.Error,
+		},
+	}
+}
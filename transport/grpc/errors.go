@@ -0,0 +1,37 @@
+package grpc
+
+import (
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"transaction-system/service"
+)
+
+const errorDomain = "transaction-system"
+
+// Коды gRPC видов ошибок сервиса
+var errorCodes = map[service.ErrorKind]codes.Code{
+	service.KindInvalidArgument:  codes.InvalidArgument,
+	service.KindMissingArgument:  codes.InvalidArgument,
+	service.KindNotFound:         codes.NotFound,
+	service.KindConflict:         codes.FailedPrecondition,
+	service.KindPermissionDenied: codes.PermissionDenied,
+}
+
+// statusError переводит ошибку сервиса в статус gRPC. Код и текст те же, что в HTTP API, код передается в ErrorInfo.Reason
+func statusError(err error) error {
+	classified := service.ClassifyError(err)
+
+	code, ok := errorCodes[classified.Kind]
+	if !ok {
+		code = codes.Internal
+	}
+
+	st := status.New(code, classified.Message)
+	detailed, detailsErr := st.WithDetails(&errdetails.ErrorInfo{Reason: classified.Code, Domain: errorDomain})
+	if detailsErr != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
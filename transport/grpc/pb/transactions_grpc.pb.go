@@ -0,0 +1,295 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: transactions.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Transactions_Invoice_FullMethodName           = "/transactions.v1.Transactions/Invoice"
+	Transactions_Withdraw_FullMethodName          = "/transactions.v1.Transactions/Withdraw"
+	Transactions_GetBalance_FullMethodName        = "/transactions.v1.Transactions/GetBalance"
+	Transactions_GetTransaction_FullMethodName    = "/transactions.v1.Transactions/GetTransaction"
+	Transactions_WatchTransactions_FullMethodName = "/transactions.v1.Transactions/WatchTransactions"
+)
+
+// TransactionsClient is the client API for Transactions service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type TransactionsClient interface {
+	// Invoice зачисляет средства на кошелек или карту
+	Invoice(ctx context.Context, in *OperationRequest, opts ...grpc.CallOption) (*Transaction, error)
+	// Withdraw списывает средства с кошелька или карты
+	Withdraw(ctx context.Context, in *OperationRequest, opts ...grpc.CallOption) (*Transaction, error)
+	// GetBalance возвращает подтвержденный и замороженный баланс по валютам
+	GetBalance(ctx context.Context, in *BalanceRequest, opts ...grpc.CallOption) (*Balance, error)
+	// GetTransaction возвращает транзакцию по ID
+	GetTransaction(ctx context.Context, in *GetTransactionRequest, opts ...grpc.CallOption) (*Transaction, error)
+	// WatchTransactions отдает события о транзакциях кошелька: сначала пропущенные после after_sequence, затем новые
+	WatchTransactions(ctx context.Context, in *WatchTransactionsRequest, opts ...grpc.CallOption) (Transactions_WatchTransactionsClient, error)
+}
+
+type transactionsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTransactionsClient(cc grpc.ClientConnInterface) TransactionsClient {
+	return &transactionsClient{cc}
+}
+
+func (c *transactionsClient) Invoice(ctx context.Context, in *OperationRequest, opts ...grpc.CallOption) (*Transaction, error) {
+	out := new(Transaction)
+	err := c.cc.Invoke(ctx, Transactions_Invoice_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transactionsClient) Withdraw(ctx context.Context, in *OperationRequest, opts ...grpc.CallOption) (*Transaction, error) {
+	out := new(Transaction)
+	err := c.cc.Invoke(ctx, Transactions_Withdraw_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transactionsClient) GetBalance(ctx context.Context, in *BalanceRequest, opts ...grpc.CallOption) (*Balance, error) {
+	out := new(Balance)
+	err := c.cc.Invoke(ctx, Transactions_GetBalance_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transactionsClient) GetTransaction(ctx context.Context, in *GetTransactionRequest, opts ...grpc.CallOption) (*Transaction, error) {
+	out := new(Transaction)
+	err := c.cc.Invoke(ctx, Transactions_GetTransaction_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *transactionsClient) WatchTransactions(ctx context.Context, in *WatchTransactionsRequest, opts ...grpc.CallOption) (Transactions_WatchTransactionsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Transactions_ServiceDesc.Streams[0], Transactions_WatchTransactions_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &transactionsWatchTransactionsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Transactions_WatchTransactionsClient interface {
+	Recv() (*TransactionEvent, error)
+	grpc.ClientStream
+}
+
+type transactionsWatchTransactionsClient struct {
+	grpc.ClientStream
+}
+
+func (x *transactionsWatchTransactionsClient) Recv() (*TransactionEvent, error) {
+	m := new(TransactionEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// TransactionsServer is the server API for Transactions service.
+// All implementations must embed UnimplementedTransactionsServer
+// for forward compatibility
+type TransactionsServer interface {
+	// Invoice зачисляет средства на кошелек или карту
+	Invoice(context.Context, *OperationRequest) (*Transaction, error)
+	// Withdraw списывает средства с кошелька или карты
+	Withdraw(context.Context, *OperationRequest) (*Transaction, error)
+	// GetBalance возвращает подтвержденный и замороженный баланс по валютам
+	GetBalance(context.Context, *BalanceRequest) (*Balance, error)
+	// GetTransaction возвращает транзакцию по ID
+	GetTransaction(context.Context, *GetTransactionRequest) (*Transaction, error)
+	// WatchTransactions отдает события о транзакциях кошелька: сначала пропущенные после after_sequence, затем новые
+	WatchTransactions(*WatchTransactionsRequest, Transactions_WatchTransactionsServer) error
+	mustEmbedUnimplementedTransactionsServer()
+}
+
+// UnimplementedTransactionsServer must be embedded to have forward compatible implementations.
+type UnimplementedTransactionsServer struct {
+}
+
+func (UnimplementedTransactionsServer) Invoice(context.Context, *OperationRequest) (*Transaction, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Invoice not implemented")
+}
+func (UnimplementedTransactionsServer) Withdraw(context.Context, *OperationRequest) (*Transaction, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Withdraw not implemented")
+}
+func (UnimplementedTransactionsServer) GetBalance(context.Context, *BalanceRequest) (*Balance, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBalance not implemented")
+}
+func (UnimplementedTransactionsServer) GetTransaction(context.Context, *GetTransactionRequest) (*Transaction, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTransaction not implemented")
+}
+func (UnimplementedTransactionsServer) WatchTransactions(*WatchTransactionsRequest, Transactions_WatchTransactionsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchTransactions not implemented")
+}
+func (UnimplementedTransactionsServer) mustEmbedUnimplementedTransactionsServer() {}
+
+// UnsafeTransactionsServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TransactionsServer will
+// result in compilation errors.
+type UnsafeTransactionsServer interface {
+	mustEmbedUnimplementedTransactionsServer()
+}
+
+func RegisterTransactionsServer(s grpc.ServiceRegistrar, srv TransactionsServer) {
+	s.RegisterService(&Transactions_ServiceDesc, srv)
+}
+
+func _Transactions_Invoice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OperationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransactionsServer).Invoice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Transactions_Invoice_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransactionsServer).Invoice(ctx, req.(*OperationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Transactions_Withdraw_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OperationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransactionsServer).Withdraw(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Transactions_Withdraw_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransactionsServer).Withdraw(ctx, req.(*OperationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Transactions_GetBalance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BalanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransactionsServer).GetBalance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Transactions_GetBalance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransactionsServer).GetBalance(ctx, req.(*BalanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Transactions_GetTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TransactionsServer).GetTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Transactions_GetTransaction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TransactionsServer).GetTransaction(ctx, req.(*GetTransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Transactions_WatchTransactions_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchTransactionsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TransactionsServer).WatchTransactions(m, &transactionsWatchTransactionsServer{stream})
+}
+
+type Transactions_WatchTransactionsServer interface {
+	Send(*TransactionEvent) error
+	grpc.ServerStream
+}
+
+type transactionsWatchTransactionsServer struct {
+	grpc.ServerStream
+}
+
+func (x *transactionsWatchTransactionsServer) Send(m *TransactionEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Transactions_ServiceDesc is the grpc.ServiceDesc for Transactions service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Transactions_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "transactions.v1.Transactions",
+	HandlerType: (*TransactionsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Invoice",
+			Handler:    _Transactions_Invoice_Handler,
+		},
+		{
+			MethodName: "Withdraw",
+			Handler:    _Transactions_Withdraw_Handler,
+		},
+		{
+			MethodName: "GetBalance",
+			Handler:    _Transactions_GetBalance_Handler,
+		},
+		{
+			MethodName: "GetTransaction",
+			Handler:    _Transactions_GetTransaction_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchTransactions",
+			Handler:       _Transactions_WatchTransactions_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "transactions.proto",
+}
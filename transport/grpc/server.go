@@ -0,0 +1,281 @@
+package grpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"net"
+	"strings"
+	"transaction-system/config"
+	"transaction-system/internal/domain"
+	"transaction-system/service"
+	"transaction-system/transport/grpc/pb"
+)
+
+var ErrTokenRequired = errors.New("grpc server requires GRPC.TOKEN")
+
+//go:generate protoc -I proto --go_out=pb --go_opt=paths=source_relative --go-grpc_out=pb --go-grpc_opt=paths=source_relative transactions.proto
+
+type Wat interface {
+	AddAmountController(ctx context.Context, currencyCode int, amount float64, walletNumber int, cardNumber int) (*domain.Transactions, error)
+	WithdrawAmountController(ctx context.Context, currencyCode int, amount float64, walletNumber int, cardNumber int) (*domain.Transactions, error)
+	GetBalanceController(ctx context.Context, walletNumber int, cardNumber int) (*domain.Balance, error)
+	GetTransactionController(ctx context.Context, id int) (*domain.Transactions, error)
+}
+
+type WalletStream interface {
+	Subscribe(ctx context.Context, walletNumber int) (*service.Subscription, error)
+	Replay(ctx context.Context, sub *service.Subscription, after int64, send func(domain.TransactionEvent) error) (int64, error)
+}
+
+// Server - gRPC API поверх тех же сервисов, что и HTTP-контроллер, вместе с health и reflection
+type Server struct {
+	pb.UnimplementedTransactionsServer
+
+	wat      Wat
+	stream   WalletStream
+	addr     string
+	server   *grpc.Server
+	health   *health.Server
+	stopping chan struct{}
+	logger   *zap.Logger
+}
+
+// NewServer не создает сервер без GRPC.TOKEN: без него Invoice и Withdraw были бы доступны любому, кто достучится до порта
+func NewServer(cfg *config.Config, wat Wat, stream WalletStream, logger *zap.Logger) (*Server, error) {
+	if cfg.GRPC.Token == "" {
+		return nil, ErrTokenRequired
+	}
+
+	s := &Server{
+		wat:      wat,
+		stream:   stream,
+		addr:     cfg.GRPC.Addr,
+		health:   health.NewServer(),
+		stopping: make(chan struct{}),
+		logger:   logger,
+	}
+
+	s.server = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unaryAuth(cfg.GRPC.Token)),
+		grpc.ChainStreamInterceptor(streamAuth(cfg.GRPC.Token)),
+	)
+	pb.RegisterTransactionsServer(s.server, s)
+	healthpb.RegisterHealthServer(s.server, s.health)
+	reflection.Register(s.server)
+
+	return s, nil
+}
+
+// Start слушает ADDR и блокируется до остановки сервера
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	s.health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	s.health.SetServingStatus(pb.Transactions_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+
+	return s.server.Serve(listener)
+}
+
+// Stop переводит health в NOT_SERVING, закрывает потоки WatchTransactions и дожидается завершения текущих вызовов.
+// Если они не завершились до отмены ctx, соединения обрываются
+func (s *Server) Stop(ctx context.Context) error {
+	s.health.Shutdown()
+	close(s.stopping)
+
+	done := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		return ctx.Err()
+	}
+}
+
+func (s *Server) Invoice(ctx context.Context, req *pb.OperationRequest) (*pb.Transaction, error) {
+	transaction, err := s.wat.AddAmountController(ctx, int(req.CurrencyCode), req.Amount, int(req.WalletNumber), int(req.CardNumber))
+	if err != nil {
+		s.logger.Error("Failed to add amount to database", zap.Error(err))
+		return nil, statusError(err)
+	}
+
+	return newTransaction(transaction), nil
+}
+
+func (s *Server) Withdraw(ctx context.Context, req *pb.OperationRequest) (*pb.Transaction, error) {
+	transaction, err := s.wat.WithdrawAmountController(ctx, int(req.CurrencyCode), req.Amount, int(req.WalletNumber), int(req.CardNumber))
+	if err != nil {
+		s.logger.Error("Failed to withdraw amount from database", zap.Error(err))
+		return nil, statusError(err)
+	}
+
+	return newTransaction(transaction), nil
+}
+
+func (s *Server) GetBalance(ctx context.Context, req *pb.BalanceRequest) (*pb.Balance, error) {
+	if req.WalletNumber == 0 && req.CardNumber == 0 {
+		return nil, statusError(missingRequisites("wallet_number or card_number is required"))
+	}
+
+	balance, err := s.wat.GetBalanceController(ctx, int(req.WalletNumber), int(req.CardNumber))
+	if err != nil {
+		s.logger.Error("Failed to fetch balance", zap.Error(err))
+		return nil, statusError(err)
+	}
+
+	response := &pb.Balance{}
+	for _, currency := range balance.Currencies {
+		response.Currencies = append(response.Currencies, &pb.CurrencyBalance{
+			CurrencyCode: int32(currency.CurrencyCode),
+			CurrencyName: currency.CurrencyName,
+			Available:    currency.Available,
+			Frozen:       currency.Frozen,
+		})
+	}
+
+	return response, nil
+}
+
+func (s *Server) GetTransaction(ctx context.Context, req *pb.GetTransactionRequest) (*pb.Transaction, error) {
+	transaction, err := s.wat.GetTransactionController(ctx, int(req.Id))
+	if err != nil {
+		s.logger.Error("Failed to fetch transaction", zap.Error(err))
+		return nil, statusError(err)
+	}
+
+	return newTransaction(transaction), nil
+}
+
+// WatchTransactions работает так же, как поток кошелька в HTTP API: подписка оформляется до чтения журнала,
+// поэтому события между пропущенными и новыми не теряются, а повторы отбрасываются по номеру
+func (s *Server) WatchTransactions(req *pb.WatchTransactionsRequest, stream pb.Transactions_WatchTransactionsServer) error {
+	ctx := stream.Context()
+
+	if req.WalletNumber == 0 {
+		return statusError(missingRequisites("wallet_number is required"))
+	}
+
+	sub, err := s.stream.Subscribe(ctx, int(req.WalletNumber))
+	if err != nil {
+		s.logger.Error("Failed to subscribe to wallet events", zap.Error(err))
+		return statusError(err)
+	}
+	defer sub.Close()
+
+	send := func(event domain.TransactionEvent) error {
+		return stream.Send(&pb.TransactionEvent{Sequence: event.Sequence, Transaction: newTransaction(event.Transaction)})
+	}
+
+	last := req.AfterSequence
+	if req.AfterSequence > 0 {
+		last, err = s.stream.Replay(ctx, sub, req.AfterSequence, send)
+		if err != nil {
+			s.logger.Error("Failed to replay wallet events", zap.Error(err))
+			return statusError(err)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.stopping:
+			return status.Error(codes.Unavailable, "server is shutting down, reconnect with after_sequence")
+		case event, ok := <-sub.Events:
+			if !ok {
+				return status.Error(codes.Unavailable, "subscriber is too slow, reconnect with after_sequence")
+			}
+
+			if event.Sequence <= last {
+				continue
+			}
+
+			err = send(event)
+			if err != nil {
+				return err
+			}
+			last = event.Sequence
+		}
+	}
+}
+
+func missingRequisites(message string) error {
+	return &service.ValidationError{Fields: []service.FieldError{
+		{Field: "wallet_number", Message: message, Err: service.ErrMissingRequisites},
+	}}
+}
+
+func newTransaction(t *domain.Transactions) *pb.Transaction {
+	return &pb.Transaction{
+		Id:          int64(t.ID),
+		ClientId:    int64(t.ClientID),
+		CurrencyId:  int64(t.CurrencyID),
+		Amount:      t.Amount,
+		Status:      t.Status,
+		Sequence:    t.Sequence,
+		OperationId: int64(t.OperationID),
+		CreatedAt:   timestamppb.New(t.CreatedAt),
+	}
+}
+
+// Health и reflection доступны без токена, чтобы ими могли пользоваться балансировщики и grpcurl
+func skipAuth(method string) bool {
+	return strings.HasPrefix(method, "/grpc.health.") || strings.HasPrefix(method, "/grpc.reflection.")
+}
+
+func authorize(ctx context.Context, token string) error {
+	values := metadata.ValueFromIncomingContext(ctx, "authorization")
+	if len(values) == 0 {
+		return status.Error(codes.Unauthenticated, "bearer token required")
+	}
+
+	provided, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+		return status.Error(codes.Unauthenticated, "invalid token")
+	}
+
+	return nil
+}
+
+// unaryAuth проверяет токен из GRPC.TOKEN
+func unaryAuth(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !skipAuth(info.FullMethod) {
+			if err := authorize(ctx, token); err != nil {
+				return nil, err
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func streamAuth(token string) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !skipAuth(info.FullMethod) {
+			if err := authorize(ss.Context(), token); err != nil {
+				return err
+			}
+		}
+
+		return handler(srv, ss)
+	}
+}
@@ -0,0 +1,111 @@
+package grpc
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"math"
+	"net"
+	"testing"
+	"transaction-system/config"
+	"transaction-system/internal/domain"
+	"transaction-system/service"
+	"transaction-system/transport/grpc/pb"
+)
+
+const testToken = "grpc-token"
+
+// repo - хранилище за сервисом, считает операции, дошедшие до базы
+type repo struct {
+	service.DataBaseRepository
+	operations int
+}
+
+func (r *repo) AddAmount(_ context.Context, _ int, amount float64, walletNumber int, _ int) (*domain.Transactions, error) {
+	r.operations++
+	return &domain.Transactions{ID: 1, Amount: amount, Client: &domain.Clients{WalletNumber: walletNumber}}, nil
+}
+
+func (r *repo) WithdrawAmount(_ context.Context, _ int, amount float64, walletNumber int, _ int) (*domain.Transactions, error) {
+	r.operations++
+	return &domain.Transactions{ID: 1, Amount: -amount, Client: &domain.Clients{WalletNumber: walletNumber}}, nil
+}
+
+func newTestClient(t *testing.T, r *repo) pb.TransactionsClient {
+	t.Helper()
+
+	cfg := &config.Config{GRPC: config.GRPC{Token: testToken}}
+	server, err := NewServer(cfg, service.NewDataBaseWorker(r, cfg), nil, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener := bufconn.Listen(1 << 20)
+	go server.server.Serve(listener)
+	t.Cleanup(server.server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewTransactionsClient(conn)
+}
+
+func TestOperationRejectsNonFiniteAmount(t *testing.T) {
+	r := &repo{}
+	client := newTestClient(t, r)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+testToken)
+
+	for _, amount := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		for name, call := range map[string]func(context.Context, *pb.OperationRequest, ...grpc.CallOption) (*pb.Transaction, error){
+			"Invoice":  client.Invoice,
+			"Withdraw": client.Withdraw,
+		} {
+			_, err := call(ctx, &pb.OperationRequest{CurrencyCode: 840, Amount: amount, WalletNumber: 101234567})
+
+			st := status.Convert(err)
+			if st.Code() != codes.InvalidArgument {
+				t.Fatalf("%s(%v): code = %v, want %v", name, amount, st.Code(), codes.InvalidArgument)
+			}
+
+			var reason string
+			for _, detail := range st.Details() {
+				if info, ok := detail.(*errdetails.ErrorInfo); ok {
+					reason = info.Reason
+				}
+			}
+			if reason != service.CodeInvalidAmount {
+				t.Fatalf("%s(%v): reason = %q, want %q", name, amount, reason, service.CodeInvalidAmount)
+			}
+		}
+	}
+
+	if r.operations != 0 {
+		t.Fatalf("operations reached storage: %d", r.operations)
+	}
+}
+
+func TestOperationAcceptsFiniteAmount(t *testing.T) {
+	r := &repo{}
+	client := newTestClient(t, r)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+testToken)
+
+	transaction, err := client.Invoice(ctx, &pb.OperationRequest{CurrencyCode: 840, Amount: 10, WalletNumber: 101234567})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transaction.Amount != 10 || r.operations != 1 {
+		t.Fatalf("transaction amount = %v, operations = %d", transaction.Amount, r.operations)
+	}
+}
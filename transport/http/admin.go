@@ -0,0 +1,151 @@
+package http
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+	"transaction-system/internal/domain"
+)
+
+type APIKeyManager interface {
+	IssueAPIKey(ctx context.Context, name string) (*domain.APIKeys, string, error)
+	ListAPIKeys(ctx context.Context) ([]domain.APIKeys, error)
+	RevokeAPIKey(ctx context.Context, id int) error
+}
+
+type ClientManager interface {
+	CreateClientController(ctx context.Context, walletNumber int, cardNumber int) (*domain.Clients, error)
+}
+
+type AdminController struct {
+	apiKeys APIKeyManager
+	clients ClientManager
+	logger  *zap.Logger
+}
+
+func NewAdminController(apiKeys APIKeyManager, clients ClientManager, logger *zap.Logger) *AdminController {
+	return &AdminController{apiKeys: apiKeys, clients: clients, logger: logger}
+}
+
+type IssueAPIKeyRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+type APIKeyResponse struct {
+	ID        int        `json:"id"`
+	KeyID     string     `json:"key_id"`
+	Name      string     `json:"name"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	// Secret возвращается только при выпуске ключа
+	Secret string `json:"secret,omitempty"`
+}
+
+type CreateClientRequest struct {
+	WalletNumber int `json:"wallet_number" binding:"required,min=1"`
+	CardNumber   int `json:"card_number" binding:"omitempty,min=1"`
+}
+
+type ClientResponse struct {
+	ID           int `json:"id"`
+	WalletNumber int `json:"wallet_number"`
+	CardNumber   int `json:"card_number,omitempty"`
+}
+
+type APIKeyURI struct {
+	ID int `uri:"id" binding:"required"`
+}
+
+func newAPIKeyResponse(key *domain.APIKeys) APIKeyResponse {
+	return APIKeyResponse{
+		ID:        key.ID,
+		KeyID:     key.KeyID,
+		Name:      key.Name,
+		CreatedAt: key.CreatedAt,
+		RevokedAt: key.RevokedAt,
+	}
+}
+
+func (ac *AdminController) IssueAPIKey(c *gin.Context) {
+	var req IssueAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ac.logger.Error("Failed to parse request body", logFields(c, err)...)
+		respondBindingError(c, err)
+		return
+	}
+
+	key, secret, err := ac.apiKeys.IssueAPIKey(c, req.Name)
+	if err != nil {
+		ac.logger.Error("Failed to issue api key", logFields(c, err)...)
+		respondError(c, err, "Failed to issue api key")
+		return
+	}
+
+	response := newAPIKeyResponse(key)
+	response.Secret = secret
+
+	c.JSON(http.StatusCreated, response)
+}
+
+func (ac *AdminController) ListAPIKeys(c *gin.Context) {
+	keys, err := ac.apiKeys.ListAPIKeys(c)
+	if err != nil {
+		ac.logger.Error("Failed to list api keys", logFields(c, err)...)
+		respondError(c, err, "Failed to list api keys")
+		return
+	}
+
+	response := make([]APIKeyResponse, 0, len(keys))
+	for i := range keys {
+		response = append(response, newAPIKeyResponse(&keys[i]))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (ac *AdminController) RevokeAPIKey(c *gin.Context) {
+	var uri APIKeyURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		ac.logger.Error("Failed to parse api key id", logFields(c, err)...)
+		respondBindingError(c, err)
+		return
+	}
+
+	err := ac.apiKeys.RevokeAPIKey(c, uri.ID)
+	if err != nil {
+		ac.logger.Error("Failed to revoke api key", logFields(c, err)...)
+		respondError(c, err, "Failed to revoke api key")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// CreateClient заводит клиента с кошельком и необязательной картой
+func (ac *AdminController) CreateClient(c *gin.Context) {
+	var req CreateClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ac.logger.Error("Failed to parse request body", logFields(c, err)...)
+		respondBindingError(c, err)
+		return
+	}
+
+	client, err := ac.clients.CreateClientController(c, req.WalletNumber, req.CardNumber)
+	if err != nil {
+		ac.logger.Error("Failed to create client", logFields(c, err)...)
+		respondError(c, err, "Failed to create client")
+		return
+	}
+
+	c.JSON(http.StatusCreated, ClientResponse{ID: client.ID, WalletNumber: client.WalletNumber, CardNumber: client.CardNumber})
+}
+
+// RegisterRoutes - административные ручки версии v1
+func (ac *AdminController) RegisterRoutes(rg gin.IRoutes) {
+	rg.POST("/api-keys", ac.IssueAPIKey)
+	rg.GET("/api-keys", ac.ListAPIKeys)
+	rg.DELETE("/api-keys/:id", ac.RevokeAPIKey)
+	rg.POST("/clients", ac.CreateClient)
+}
@@ -0,0 +1,210 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+	"transaction-system/internal/domain"
+	"transaction-system/storage"
+)
+
+const (
+	APIKeyHeader     = "X-API-Key"
+	TimestampHeader  = "X-Timestamp"
+	SignatureHeader  = "X-Signature"
+	AdminTokenHeader = "X-Admin-Token"
+)
+
+const (
+	CodeUnauthorized     = "unauthorized"
+	CodeInvalidSignature = "invalid_signature"
+	CodeRequestExpired   = "request_expired"
+	CodeReplayedRequest  = "replayed_request"
+	CodeInvalidToken     = "invalid_token"
+)
+
+const (
+	defaultReplayWindow = 5 * time.Minute
+	apiKeyIDKey         = "api_key_id"
+)
+
+// APIKeyLookup ищет ключ с расшифрованным ключом подписи и запоминает подписи принятых запросов
+type APIKeyLookup interface {
+	FindActiveAPIKey(ctx context.Context, keyID string) (*domain.APIKeys, error)
+	RememberSignature(ctx context.Context, apiKeyID int, signature string, expiresAt time.Time) (bool, error)
+}
+
+// SignRequest - подпись запроса: hex(HMAC-SHA256(signingKey, METHOD\nPATH\nQUERY\nTIMESTAMP\nBODY)),
+// где signingKey - SHA-256 от секрета ключа в hex, QUERY - CanonicalQuery строки запроса
+func SignRequest(signingKey string, method string, path string, query string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(signingKey))
+	mac.Write([]byte(method + "\n" + path + "\n" + CanonicalQuery(query) + "\n" + timestamp + "\n"))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CanonicalQuery - параметры строки запроса, отсортированные по имени и заново закодированные,
+// чтобы порядок параметров и способ кодирования не меняли подпись
+func CanonicalQuery(rawQuery string) string {
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return rawQuery
+	}
+
+	return values.Encode()
+}
+
+// APIKeyAuth проверяет HMAC-подпись запроса ключом мерчанта. Запрос отклоняется, если его время
+// вышло за окно window или такая же подпись уже принята этим или другим экземпляром сервиса
+func APIKeyAuth(keys APIKeyLookup, window time.Duration, logger *zap.Logger) gin.HandlerFunc {
+	if window <= 0 {
+		window = defaultReplayWindow
+	}
+
+	return func(c *gin.Context) {
+		keyID := c.GetHeader(APIKeyHeader)
+		timestamp := c.GetHeader(TimestampHeader)
+		signature := c.GetHeader(SignatureHeader)
+		if keyID == "" || timestamp == "" || signature == "" {
+			abortWithError(c, http.StatusUnauthorized, CodeUnauthorized, "Missing API key signature headers")
+			return
+		}
+
+		unix, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			abortWithError(c, http.StatusUnauthorized, CodeUnauthorized, "Invalid request timestamp")
+			return
+		}
+
+		signedAt := time.Unix(unix, 0)
+		if age := time.Since(signedAt); age > window || age < -window {
+			abortWithError(c, http.StatusUnauthorized, CodeRequestExpired, "Request timestamp is outside of the allowed window")
+			return
+		}
+
+		key, err := keys.FindActiveAPIKey(c, keyID)
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			abortWithError(c, http.StatusUnauthorized, CodeUnauthorized, "Unknown or revoked API key")
+			return
+		}
+		if err != nil {
+			logger.Error("Failed to find api key", logFields(c, err)...)
+			abortWithError(c, http.StatusInternalServerError, CodeInternal, "Failed to verify API key")
+			return
+		}
+
+		body, err := readBody(c)
+		if err != nil {
+			respondBodyError(c, err)
+			return
+		}
+
+		expected := SignRequest(key.SigningKey, c.Request.Method, c.Request.URL.Path, c.Request.URL.RawQuery, timestamp, body)
+		if !hmac.Equal([]byte(expected), []byte(signature)) {
+			abortWithError(c, http.StatusUnauthorized, CodeInvalidSignature, "Invalid request signature")
+			return
+		}
+
+		// Подпись помнится, пока время запроса не выйдет за окно: позже повтор отклонит проверка времени
+		fresh, err := keys.RememberSignature(c, key.ID, signature, signedAt.Add(window))
+		if err != nil {
+			logger.Error("Failed to remember api key signature", logFields(c, err)...)
+			abortWithError(c, http.StatusInternalServerError, CodeInternal, "Failed to verify API key")
+			return
+		}
+		if !fresh {
+			abortWithError(c, http.StatusUnauthorized, CodeReplayedRequest, "Request has already been processed")
+			return
+		}
+
+		c.Set(apiKeyIDKey, key.ID)
+		c.Request = c.Request.WithContext(domain.ContextWithAPIKeyID(c.Request.Context(), key.ID))
+		c.Next()
+	}
+}
+
+// Authenticate пропускает запрос с JWT одной из ролей roles. Если токена нет, запрос передается
+// в apiKeyAuth (проверка HMAC-подписи), а при выключенных API-ключах отклоняется
+func Authenticate(verifier *JWTVerifier, apiKeyAuth gin.HandlerFunc, roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := bearerToken(c)
+		if ok && verifier != nil {
+			authenticateJWT(c, verifier, token, roles)
+			return
+		}
+
+		if apiKeyAuth != nil {
+			apiKeyAuth(c)
+			return
+		}
+
+		abortWithError(c, http.StatusUnauthorized, CodeUnauthorized, "Bearer token required")
+	}
+}
+
+// AdminAuth пускает к административным ручкам со статическим токеном из конфига или с JWT роли admin
+func AdminAuth(adminToken string, verifier *JWTVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := bearerToken(c)
+		if ok && verifier != nil {
+			authenticateJWT(c, verifier, token, []string{RoleAdmin})
+			return
+		}
+
+		provided := c.GetHeader(AdminTokenHeader)
+		if adminToken == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(adminToken)) != 1 {
+			abortWithError(c, http.StatusUnauthorized, CodeUnauthorized, "Admin token required")
+			return
+		}
+
+		c.Request = c.Request.WithContext(domain.ContextWithAdmin(c.Request.Context()))
+		c.Next()
+	}
+}
+
+func authenticateJWT(c *gin.Context, verifier *JWTVerifier, token string, roles []string) {
+	principal, err := verifier.Verify(token)
+	if err != nil {
+		abortWithError(c, http.StatusUnauthorized, CodeInvalidToken, "Invalid or expired token")
+		return
+	}
+
+	if !slices.Contains(roles, principal.Role) {
+		abortWithError(c, http.StatusForbidden, CodeForbidden, "Role is not allowed to access this resource")
+		return
+	}
+
+	c.Set(principalKey, principal)
+	if principal.Role == RoleAdmin {
+		c.Request = c.Request.WithContext(domain.ContextWithAdmin(c.Request.Context()))
+	}
+	c.Next()
+}
+
+// readBody вычитывает тело для подписи и возвращает его обратно в запрос
+func readBody(c *gin.Context) ([]byte, error) {
+	if c.Request.Body == nil {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
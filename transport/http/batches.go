@@ -0,0 +1,176 @@
+package http
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"time"
+	"transaction-system/internal/domain"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+type BatchProcessor interface {
+	SubmitBatch(ctx context.Context, apiKeyID int, idempotencyKey string, items []*domain.BatchItems) (*domain.Batches, bool, error)
+	GetBatch(ctx context.Context, apiKeyID int, id int) (*domain.Batches, error)
+}
+
+type BatchController struct {
+	batches BatchProcessor
+	logger  *zap.Logger
+}
+
+func NewBatchController(batches BatchProcessor, logger *zap.Logger) *BatchController {
+	return &BatchController{batches: batches, logger: logger}
+}
+
+type BatchRequest struct {
+	Items []BatchItemRequest `json:"items" binding:"required,min=1,dive"`
+}
+
+// BatchItemRequest - реквизиты и сумма проверяются при проведении операции, ошибка попадает в результат элемента,
+// а не отклоняет весь пакет
+type BatchItemRequest struct {
+	Operation    string  `json:"operation" binding:"required,oneof=invoice withdraw"`
+	CurrencyCode int     `json:"currency_code"`
+	Amount       float64 `json:"amount"`
+	WalletNumber int     `json:"wallet_number"`
+	CardNumber   int     `json:"card_number"`
+}
+
+type BatchURI struct {
+	ID int `uri:"id" binding:"required"`
+}
+
+type BatchResponse struct {
+	ID        int                 `json:"id"`
+	Status    string              `json:"status"`
+	Total     int                 `json:"total"`
+	Succeeded int                 `json:"succeeded"`
+	Failed    int                 `json:"failed"`
+	Items     []BatchItemResponse `json:"items"`
+	CreatedAt time.Time           `json:"created_at"`
+	UpdatedAt time.Time           `json:"updated_at"`
+}
+
+type BatchItemResponse struct {
+	Position      int             `json:"position"`
+	Operation     string          `json:"operation"`
+	CurrencyCode  int             `json:"currency_code"`
+	Amount        float64         `json:"amount"`
+	WalletNumber  int             `json:"wallet_number,omitempty"`
+	CardNumber    int             `json:"card_number,omitempty"`
+	Status        string          `json:"status"`
+	TransactionID int             `json:"transaction_id,omitempty"`
+	Error         *OperationError `json:"error,omitempty"`
+}
+
+type OperationError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func newBatchResponse(batch *domain.Batches) BatchResponse {
+	response := BatchResponse{
+		ID:        batch.ID,
+		Status:    batch.Status,
+		Total:     batch.Total,
+		Succeeded: batch.Succeeded,
+		Failed:    batch.Failed,
+		Items:     make([]BatchItemResponse, 0, len(batch.Items)),
+		CreatedAt: batch.CreatedAt,
+		UpdatedAt: batch.UpdatedAt,
+	}
+
+	for _, item := range batch.Items {
+		itemResponse := BatchItemResponse{
+			Position:      item.Position,
+			Operation:     item.Operation,
+			CurrencyCode:  item.CurrencyCode,
+			Amount:        item.Amount,
+			WalletNumber:  item.WalletNumber,
+			CardNumber:    item.CardNumber,
+			Status:        item.Status,
+			TransactionID: item.TransactionID,
+		}
+		if item.ErrorCode != "" {
+			itemResponse.Error = &OperationError{Code: item.ErrorCode, Message: item.Error}
+		}
+		response.Items = append(response.Items, itemResponse)
+	}
+
+	return response
+}
+
+// SubmitBatch принимает пакет в фоновую обработку и отвечает 202 с Location для опроса результата.
+// Повтор с тем же Idempotency-Key отдает сохраненный пакет с кодом 200
+func (bc *BatchController) SubmitBatch(c *gin.Context) {
+	var req BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bc.logger.Error("Failed to parse request body", logFields(c, err)...)
+		respondBindingError(c, err)
+		return
+	}
+
+	items := make([]*domain.BatchItems, 0, len(req.Items))
+	for _, item := range req.Items {
+		if !authorizeRequisites(c, item.WalletNumber, item.CardNumber) {
+			return
+		}
+
+		items = append(items, &domain.BatchItems{
+			Operation:    item.Operation,
+			CurrencyCode: item.CurrencyCode,
+			Amount:       item.Amount,
+			WalletNumber: item.WalletNumber,
+			CardNumber:   item.CardNumber,
+		})
+	}
+
+	batch, created, err := bc.batches.SubmitBatch(c, c.GetInt(apiKeyIDKey), c.GetHeader(IdempotencyKeyHeader), items)
+	if err != nil {
+		bc.logger.Error("Failed to submit batch", logFields(c, err)...)
+		respondError(c, err, "Failed to submit batch")
+		return
+	}
+
+	if !created {
+		c.JSON(http.StatusOK, newBatchResponse(batch))
+		return
+	}
+
+	c.Header("Location", c.Request.URL.Path+"/"+strconv.Itoa(batch.ID))
+	c.JSON(http.StatusAccepted, newBatchResponse(batch))
+}
+
+func (bc *BatchController) GetBatch(c *gin.Context) {
+	var uri BatchURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		bc.logger.Error("Failed to parse batch id", logFields(c, err)...)
+		respondBindingError(c, err)
+		return
+	}
+
+	batch, err := bc.batches.GetBatch(c, c.GetInt(apiKeyIDKey), uri.ID)
+	if err != nil {
+		bc.logger.Error("Failed to fetch batch", logFields(c, err)...)
+		respondError(c, err, "Failed to fetch batch")
+		return
+	}
+
+	for _, item := range batch.Items {
+		if !authorizeRequisites(c, item.WalletNumber, item.CardNumber) {
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, newBatchResponse(batch))
+}
+
+// RegisterRoutes - ручки пакетных операций версии v1
+func (bc *BatchController) RegisterRoutes(rg gin.IRoutes) {
+	rg.POST("/batches", bc.SubmitBatch)
+	rg.GET("/batches/:id", bc.GetBatch)
+}
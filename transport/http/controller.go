@@ -1,6 +1,7 @@
 package http
 
 import (
+	"context"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"net/http"
@@ -8,32 +9,52 @@ import (
 )
 
 type Wat interface {
-	AddAmountController(c *gin.Context, currencyCode int, amount float64, walletNumber int, cardNumber int) (*domain.Transactions, error)
-	WithdrawAmountController(c *gin.Context, currencyCode int, amount float64, walletNumber int, cardNumber int) (*domain.Transactions, error)
-	GetAvailableBalanceController(c *gin.Context, walletNumber int, cardNumber int) (float64, error)
-	GetFrozenBalanceController(c *gin.Context, walletNumber int, cardNumber int) (float64, error)
+	AddAmountController(ctx context.Context, currencyCode int, amount float64, walletNumber int, cardNumber int) (*domain.Transactions, error)
+	WithdrawAmountController(ctx context.Context, currencyCode int, amount float64, walletNumber int, cardNumber int) (*domain.Transactions, error)
+	GetAvailableBalanceController(ctx context.Context, walletNumber int, cardNumber int) (float64, error)
+	GetFrozenBalanceController(ctx context.Context, walletNumber int, cardNumber int) (float64, error)
+	GetBalanceController(ctx context.Context, walletNumber int, cardNumber int) (*domain.Balance, error)
 }
 
 type Controller struct {
-	wat    Wat
-	logger *zap.Logger
+	wat            Wat
+	operations     OperationQueue
+	asyncByDefault bool
+	logger         *zap.Logger
 }
 
-func NewWatController(wat Wat, logger *zap.Logger) *Controller {
-	return &Controller{wat: wat, logger: logger}
+// NewWatController - operations может быть nil, тогда ручки записи работают только синхронно.
+// asyncByDefault включает ответ 202 без заголовка Prefer: respond-async
+func NewWatController(wat Wat, operations OperationQueue, asyncByDefault bool, logger *zap.Logger) *Controller {
+	return &Controller{wat: wat, operations: operations, asyncByDefault: asyncByDefault, logger: logger}
 }
 
 func (c2 *Controller) AddAmount(c *gin.Context) {
 	var req Request
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c2.logger.Error("Failed to parse request body", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		c2.logger.Error("Failed to parse request body", logFields(c, err)...)
+		respondBindingError(c, err)
 		return
 	}
+
+	if !authorizeRequisites(c, req.WalletNumber, req.CardNumber) {
+		return
+	}
+
+	if c2.respondAsync(c) {
+		c2.enqueue(c, domain.OperationInvoice, req)
+		return
+	}
+
+	if c2.idempotent(c) {
+		c2.perform(c, domain.OperationInvoice, req)
+		return
+	}
+
 	transaction, err := c2.wat.AddAmountController(c, req.CurrencyCode, req.Amount, req.WalletNumber, req.CardNumber)
 	if err != nil {
-		c2.logger.Error("Failed to add amount to database", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add amount to database"})
+		c2.logger.Error("Failed to add amount to database", logFields(c, err)...)
+		respondError(c, err, "Failed to add amount to database")
 		return
 	}
 
@@ -43,15 +64,29 @@ func (c2 *Controller) AddAmount(c *gin.Context) {
 func (c2 *Controller) WithdrawAmount(c *gin.Context) {
 	var req Request
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c2.logger.Error("Failed to parse request body", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		c2.logger.Error("Failed to parse request body", logFields(c, err)...)
+		respondBindingError(c, err)
+		return
+	}
+
+	if !authorizeRequisites(c, req.WalletNumber, req.CardNumber) {
+		return
+	}
+
+	if c2.respondAsync(c) {
+		c2.enqueue(c, domain.OperationWithdraw, req)
+		return
+	}
+
+	if c2.idempotent(c) {
+		c2.perform(c, domain.OperationWithdraw, req)
 		return
 	}
 
 	transaction, err := c2.wat.WithdrawAmountController(c, req.CurrencyCode, req.Amount, req.WalletNumber, req.CardNumber)
 	if err != nil {
-		c2.logger.Error("Failed to withdraw amount from database", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to withdraw amount from database"})
+		c2.logger.Error("Failed to withdraw amount from database", logFields(c, err)...)
+		respondError(c, err, "Failed to withdraw amount from database")
 		return
 	}
 
@@ -59,17 +94,21 @@ func (c2 *Controller) WithdrawAmount(c *gin.Context) {
 }
 
 func (c2 *Controller) GetAvailableBalance(c *gin.Context) {
-	var req Request
+	var req BalanceRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c2.logger.Error("Failed to parse request body", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		c2.logger.Error("Failed to parse request body", logFields(c, err)...)
+		respondBindingError(c, err)
+		return
+	}
+
+	if !authorizeRequisites(c, req.WalletNumber, req.CardNumber) {
 		return
 	}
 
 	availableBalance, err := c2.wat.GetAvailableBalanceController(c, req.WalletNumber, req.CardNumber)
 	if err != nil {
-		c2.logger.Error("Failed to fetch available balance", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch available balance"})
+		c2.logger.Error("Failed to fetch available balance", logFields(c, err)...)
+		respondError(c, err, "Failed to fetch available balance")
 		return
 	}
 
@@ -77,19 +116,73 @@ func (c2 *Controller) GetAvailableBalance(c *gin.Context) {
 }
 
 func (c2 *Controller) GetFrozenBalance(c *gin.Context) {
-	var req Request
+	var req BalanceRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c2.logger.Error("Failed to parse request body", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		c2.logger.Error("Failed to parse request body", logFields(c, err)...)
+		respondBindingError(c, err)
+		return
+	}
+
+	if !authorizeRequisites(c, req.WalletNumber, req.CardNumber) {
 		return
 	}
 
 	frozenBalance, err := c2.wat.GetFrozenBalanceController(c, req.WalletNumber, req.CardNumber)
 	if err != nil {
-		c2.logger.Error("Failed to fetch frozen balance", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch frozen balance"})
+		c2.logger.Error("Failed to fetch frozen balance", logFields(c, err)...)
+		respondError(c, err, "Failed to fetch frozen balance")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"frozen_balance": frozenBalance})
 }
+
+func (c2 *Controller) GetWalletBalance(c *gin.Context) {
+	var uri WalletURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c2.logger.Error("Failed to parse wallet number", logFields(c, err)...)
+		respondBindingError(c, err)
+		return
+	}
+
+	if !authorizeRequisites(c, uri.WalletNumber, 0) {
+		return
+	}
+
+	c2.getBalance(c, uri.WalletNumber, 0)
+}
+
+func (c2 *Controller) GetCardBalance(c *gin.Context) {
+	var uri CardURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c2.logger.Error("Failed to parse card number", logFields(c, err)...)
+		respondBindingError(c, err)
+		return
+	}
+
+	if !authorizeRequisites(c, 0, uri.CardNumber) {
+		return
+	}
+
+	c2.getBalance(c, 0, uri.CardNumber)
+}
+
+func (c2 *Controller) getBalance(c *gin.Context, walletNumber int, cardNumber int) {
+	balance, err := c2.wat.GetBalanceController(c, walletNumber, cardNumber)
+	if err != nil {
+		c2.logger.Error("Failed to fetch balance", logFields(c, err)...)
+		respondError(c, err, "Failed to fetch balance")
+		return
+	}
+
+	c.JSON(http.StatusOK, newBalanceResponse(balance))
+}
+
+// RegisterRoutes - ручки версии v1
+func (c2 *Controller) RegisterRoutes(rg gin.IRoutes) {
+	rg.POST("/invoice", c2.AddAmount)
+	rg.POST("/withdraw", c2.WithdrawAmount)
+	rg.GET("/wallets/:wallet_number/balance", c2.GetWalletBalance)
+	rg.GET("/cards/:card_number/balance", c2.GetCardBalance)
+	rg.GET("/operations/:id", c2.GetOperation)
+}
@@ -0,0 +1,191 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"net/http"
+	"reflect"
+	"strings"
+	"transaction-system/service"
+)
+
+// Коды ошибок API. Клиенты должны опираться на них, а не на текст сообщения.
+// Коды ошибок сервиса определены в service и совпадают с кодами gRPC API и результатов фоновых операций
+const (
+	CodeInvalidRequest      = "invalid_request"
+	CodeBodyTooLarge        = "body_too_large"
+	CodeInvalidAmount       = service.CodeInvalidAmount
+	CodeClientNotFound      = service.CodeClientNotFound
+	CodeCurrencyNotFound    = service.CodeCurrencyNotFound
+	CodeInsufficientFunds   = service.CodeInsufficientFunds
+	CodeValidationFailed    = service.CodeValidationFailed
+	CodeRequired            = service.CodeRequired
+	CodeMissingRequisites   = service.CodeMissingRequisites
+	CodeAmountLimit         = service.CodeAmountLimit
+	CodeAPIKeyNotFound      = service.CodeAPIKeyNotFound
+	CodeBatchNotFound       = service.CodeBatchNotFound
+	CodeOperationNotFound   = service.CodeOperationNotFound
+	CodeBatchTooLarge       = service.CodeBatchTooLarge
+	CodeInvalidOperation    = service.CodeInvalidOperation
+	CodeIdempotencyKey      = service.CodeIdempotencyKey
+	CodeIdempotencyReused   = service.CodeIdempotencyReused
+	CodeWebhookNotFound     = service.CodeWebhookNotFound
+	CodeDeliveryNotFound    = service.CodeDeliveryNotFound
+	CodeInvalidURL          = service.CodeInvalidURL
+	CodeInvalidEvent        = service.CodeInvalidEvent
+	CodeTransactionNotFound = service.CodeTransactionNotFound
+	CodeClientExists        = service.CodeClientExists
+	CodeAlreadyRefunded     = service.CodeAlreadyRefunded
+	CodeNotRefundable       = service.CodeNotRefundable
+	CodeMultipleCurrencies  = service.CodeMultipleCurrencies
+	CodeForbidden           = service.CodeForbidden
+	CodeInternal            = service.CodeInternal
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	traceIDKey      = "trace_id"
+)
+
+type ErrorResponse struct {
+	Error   string        `json:"error"`
+	Code    string        `json:"code"`
+	TraceID string        `json:"trace_id"`
+	Details []FieldDetail `json:"details,omitempty"`
+}
+
+type FieldDetail struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// HTTP-статусы видов ошибок сервиса
+var errorStatuses = map[service.ErrorKind]int{
+	service.KindInvalidArgument:  http.StatusUnprocessableEntity,
+	service.KindMissingArgument:  http.StatusBadRequest,
+	service.KindNotFound:         http.StatusNotFound,
+	service.KindConflict:         http.StatusConflict,
+	service.KindPermissionDenied: http.StatusForbidden,
+}
+
+// respondError переводит ошибку сервиса в HTTP-ответ. Неизвестные ошибки отдаются как 500 с сообщением fallback
+func respondError(c *gin.Context, err error, fallback string) {
+	var validationErr *service.ValidationError
+	if errors.As(err, &validationErr) {
+		details := make([]FieldDetail, 0, len(validationErr.Fields))
+		for _, f := range validationErr.Fields {
+			details = append(details, FieldDetail{Field: f.Field, Code: f.Code(), Message: f.Message})
+		}
+		abortWithValidation(c, details)
+		return
+	}
+
+	classified := service.ClassifyError(err)
+	status, ok := errorStatuses[classified.Kind]
+	if !ok {
+		abortWithError(c, http.StatusInternalServerError, CodeInternal, fallback)
+		return
+	}
+
+	abortWithError(c, status, classified.Code, classified.Message)
+}
+
+// respondBodyError отдает 413, если тело больше HTTP.MAX_BODY_SIZE, и 400, если его не удалось прочитать или разобрать
+func respondBodyError(c *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		abortWithError(c, http.StatusRequestEntityTooLarge, CodeBodyTooLarge, fmt.Sprintf("Request body must not exceed %d bytes", tooLarge.Limit))
+		return
+	}
+
+	abortWithError(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
+}
+
+// respondBindingError отдает 422 с деталями по полям, если тело не прошло проверку binding-тегов, и 400 или 413,
+// если не разобралось
+func respondBindingError(c *gin.Context, err error) {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		respondBodyError(c, err)
+		return
+	}
+
+	details := make([]FieldDetail, 0, len(validationErrs))
+	for _, fe := range validationErrs {
+		details = append(details, bindingFieldDetail(fe))
+	}
+	abortWithValidation(c, details)
+}
+
+func bindingFieldDetail(fe validator.FieldError) FieldDetail {
+	switch fe.Tag() {
+	case "required_without":
+		return FieldDetail{Field: fe.Field(), Code: CodeMissingRequisites, Message: "wallet_number or card_number is required"}
+	case "oneof":
+		return FieldDetail{Field: fe.Field(), Code: CodeInvalidOperation, Message: "must be one of: " + fe.Param()}
+	case "gt":
+		return FieldDetail{Field: fe.Field(), Code: CodeInvalidAmount, Message: "must be greater than " + fe.Param()}
+	default:
+		return FieldDetail{Field: fe.Field(), Code: CodeRequired, Message: "is required"}
+	}
+}
+
+// Если нарушено одно правило, его код выносится на верхний уровень, иначе используется validation_failed
+func abortWithValidation(c *gin.Context, details []FieldDetail) {
+	code := CodeValidationFailed
+	if len(details) == 1 {
+		code = details[0].Code
+	}
+
+	c.AbortWithStatusJSON(http.StatusUnprocessableEntity, ErrorResponse{
+		Error:   "Request validation failed",
+		Code:    code,
+		TraceID: c.GetString(traceIDKey),
+		Details: details,
+	})
+}
+
+func abortWithError(c *gin.Context, status int, code string, message string) {
+	c.AbortWithStatusJSON(status, ErrorResponse{
+		Error:   message,
+		Code:    code,
+		TraceID: c.GetString(traceIDKey),
+	})
+}
+
+// RequestID берет идентификатор запроса из заголовка или генерирует новый, он же возвращается как trace_id в ошибках
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		traceID := c.GetHeader(RequestIDHeader)
+		if traceID == "" {
+			traceID = uuid.NewString()
+		}
+
+		c.Set(traceIDKey, traceID)
+		c.Header(RequestIDHeader, traceID)
+		c.Next()
+	}
+}
+
+func logFields(c *gin.Context, err error) []zap.Field {
+	return []zap.Field{zap.String(traceIDKey, c.GetString(traceIDKey)), zap.Error(err)}
+}
+
+// Имена полей в ошибках валидации берутся из json-тегов, чтобы совпадать с телом запроса
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package http
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"transaction-system/service"
+)
+
+const (
+	healthStatusOK       = "ok"
+	healthStatusReady    = "ready"
+	healthStatusNotReady = "not_ready"
+)
+
+type Readiness interface {
+	Ready(ctx context.Context) service.HealthReport
+}
+
+// HealthController - /healthz для liveness и /readyz для readiness. Ручки не требуют аутентификации
+type HealthController struct {
+	readiness Readiness
+}
+
+func NewHealthController(readiness Readiness) *HealthController {
+	return &HealthController{readiness: readiness}
+}
+
+type HealthResponse struct {
+	Status     string              `json:"status"`
+	Components []ComponentResponse `json:"components,omitempty"`
+}
+
+type ComponentResponse struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Liveness отвечает 200, пока процесс обслуживает запросы. Зависимости не проверяются,
+// чтобы их недоступность не приводила к перезапуску сервиса
+func (hc *HealthController) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, HealthResponse{Status: healthStatusOK})
+}
+
+// Readiness проверяет зависимости и отвечает 503, если хотя бы одна из них не готова
+func (hc *HealthController) Readiness(c *gin.Context) {
+	report := hc.readiness.Ready(c)
+
+	response := HealthResponse{Status: healthStatusReady, Components: make([]ComponentResponse, 0, len(report.Components))}
+	for _, component := range report.Components {
+		response.Components = append(response.Components, ComponentResponse{
+			Name:      component.Name,
+			Status:    component.Status,
+			LatencyMs: float64(component.Latency.Microseconds()) / 1000,
+			Error:     component.Error,
+		})
+	}
+
+	code := http.StatusOK
+	if !report.Ready {
+		response.Status = healthStatusNotReady
+		code = http.StatusServiceUnavailable
+	}
+
+	c.JSON(code, response)
+}
+
+// RegisterRoutes - ручки проверки состояния, монтируются без префикса версии
+func (hc *HealthController) RegisterRoutes(rg gin.IRoutes) {
+	rg.GET("/healthz", hc.Liveness)
+	rg.GET("/readyz", hc.Readiness)
+}
@@ -0,0 +1,210 @@
+package http
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"transaction-system/config"
+)
+
+// Роли пользователей в JWT
+const (
+	RoleClient = "client"
+	RoleAdmin  = "admin"
+)
+
+const principalKey = "principal"
+
+var ErrUnknownSigningKey = errors.New("unknown jwt signing key")
+
+// Claims - роль и кошелек, к которому привязан клиентский токен
+type Claims struct {
+	Role         string `json:"role"`
+	WalletNumber int    `json:"wallet_number,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Principal - аутентифицированный пользователь JWT
+type Principal struct {
+	Subject      string
+	Role         string
+	WalletNumber int
+}
+
+// JWTVerifier проверяет токены HS256 общим секретом из конфига и RS256 ключами из локального JWKS-файла
+type JWTVerifier struct {
+	hmacSecret []byte
+	rsaKeys    map[string]*rsa.PublicKey
+	parser     *jwt.Parser
+}
+
+func NewJWTVerifier(cfg config.JWT) (*JWTVerifier, error) {
+	v := &JWTVerifier{rsaKeys: map[string]*rsa.PublicKey{}}
+	methods := make([]string, 0, 2)
+
+	if cfg.HS256Secret != "" {
+		v.hmacSecret = []byte(cfg.HS256Secret)
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+
+	if cfg.JWKSFile != "" {
+		keys, err := loadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		v.rsaKeys = keys
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+
+	if len(methods) == 0 {
+		return nil, errors.New("jwt auth requires HS256_SECRET or JWKS_FILE")
+	}
+
+	options := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired()}
+	if cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(cfg.Audience))
+	}
+	v.parser = jwt.NewParser(options...)
+
+	return v, nil
+}
+
+func (v *JWTVerifier) Verify(token string) (*Principal, error) {
+	claims := &Claims{}
+	_, err := v.parser.ParseWithClaims(token, claims, v.keyFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	switch claims.Role {
+	case RoleAdmin:
+	case RoleClient:
+		if claims.WalletNumber == 0 {
+			return nil, errors.New("client token is not bound to a wallet")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported role %q", claims.Role)
+	}
+
+	return &Principal{Subject: claims.Subject, Role: claims.Role, WalletNumber: claims.WalletNumber}, nil
+}
+
+func (v *JWTVerifier) keyFunc(token *jwt.Token) (interface{}, error) {
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		return v.hmacSecret, nil
+	case jwt.SigningMethodRS256.Alg():
+		kid, _ := token.Header["kid"].(string)
+		key, ok := v.rsaKeys[kid]
+		if !ok {
+			return nil, ErrUnknownSigningKey
+		}
+		return key, nil
+	default:
+		return nil, ErrUnknownSigningKey
+	}
+}
+
+// bearerToken возвращает токен из заголовка Authorization: Bearer <token>
+func bearerToken(c *gin.Context) (string, bool) {
+	header := c.GetHeader("Authorization")
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || token == "" {
+		return "", false
+	}
+
+	return token, true
+}
+
+func principalFromContext(c *gin.Context) *Principal {
+	value, ok := c.Get(principalKey)
+	if !ok {
+		return nil
+	}
+
+	principal, _ := value.(*Principal)
+	return principal
+}
+
+// authorizeRequisites не дает клиентскому токену обращаться к чужому кошельку.
+// Номер карты в запросах с клиентским токеном запрещен, даже вместе с кошельком: владельца карты нельзя сверить с токеном,
+// а хранилище может найти клиента по карте
+func authorizeRequisites(c *gin.Context, walletNumber int, cardNumber int) bool {
+	principal := principalFromContext(c)
+	if principal == nil || principal.Role != RoleClient {
+		return true
+	}
+
+	if cardNumber != 0 {
+		abortWithError(c, http.StatusForbidden, CodeForbidden, "Client tokens must address their wallet by wallet_number only")
+		return false
+	}
+
+	if walletNumber == principal.WalletNumber {
+		return true
+	}
+
+	abortWithError(c, http.StatusForbidden, CodeForbidden, "Token is not allowed to access this wallet")
+
+	return false
+}
+
+type jwks struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+// loadJWKS читает RSA-ключи подписи из JWKS-файла
+func loadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read jwks file: %w", err)
+	}
+
+	var set jwks
+	err = json.Unmarshal(data, &set)
+	if err != nil {
+		return nil, fmt.Errorf("decode jwks file: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("decode jwks key %s modulus: %w", k.Kid, err)
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("decode jwks key %s exponent: %w", k.Kid, err)
+		}
+
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("jwks file contains no RSA signing keys")
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,28 @@
+package http
+
+import (
+	"github.com/gin-gonic/gin"
+	"strconv"
+	"time"
+	"transaction-system/pkg/metrics"
+)
+
+// unmatchedRoute - метка маршрута для запросов, не попавших ни в одну ручку, чтобы случайные пути не плодили серии
+const unmatchedRoute = "unmatched"
+
+// Metrics записывает длительность запроса в гистограмму по методу, шаблону пути и коду ответа
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		metrics.HTTPRequestDuration.
+			WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
@@ -1,8 +1,51 @@
 package http
 
+import "transaction-system/internal/domain"
+
 type Request struct {
+	CurrencyCode int     `json:"currency_code" binding:"required"`
+	Amount       float64 `json:"amount" binding:"required,gt=0"`
+	WalletNumber int     `json:"wallet_number" binding:"required_without=CardNumber"`
+	CardNumber   int     `json:"card_number" binding:"required_without=WalletNumber"`
+}
+
+type BalanceRequest struct {
+	WalletNumber int `json:"wallet_number" binding:"required_without=CardNumber"`
+	CardNumber   int `json:"card_number" binding:"required_without=WalletNumber"`
+}
+
+type WalletURI struct {
+	WalletNumber int `uri:"wallet_number" binding:"required"`
+}
+
+type CardURI struct {
+	CardNumber int `uri:"card_number" binding:"required"`
+}
+
+type BalanceResponse struct {
+	Currencies []CurrencyBalanceResponse `json:"currencies"`
+}
+
+type CurrencyBalanceResponse struct {
 	CurrencyCode int     `json:"currency_code"`
-	Amount       float64 `json:"amount"`
-	WalletNumber int     `json:"wallet_number"`
-	CardNumber   int     `json:"card_number"`
+	CurrencyName string  `json:"currency_name"`
+	Available    float64 `json:"available"`
+	Frozen       float64 `json:"frozen"`
+}
+
+func newBalanceResponse(balance *domain.Balance) BalanceResponse {
+	response := BalanceResponse{
+		Currencies: make([]CurrencyBalanceResponse, 0, len(balance.Currencies)),
+	}
+
+	for _, currency := range balance.Currencies {
+		response.Currencies = append(response.Currencies, CurrencyBalanceResponse{
+			CurrencyCode: currency.CurrencyCode,
+			CurrencyName: currency.CurrencyName,
+			Available:    currency.Available,
+			Frozen:       currency.Frozen,
+		})
+	}
+
+	return response
 }
@@ -0,0 +1,143 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+)
+
+const CodeContractViolation = "contract_violation"
+
+//go:embed openapi.json
+var openAPISpec []byte
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>transaction-system API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>`
+
+func serveOpenAPISpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", openAPISpec)
+}
+
+func serveSwaggerUI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIPage))
+}
+
+// OpenAPIValidator сверяет запросы и ответы с openapi.json. Предназначен для dev-режима:
+// запрос, нарушающий контракт, отклоняется с 400, расхождение ответа только логируется.
+func OpenAPIValidator(logger *zap.Logger) (gin.HandlerFunc, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(openAPISpec)
+	if err != nil {
+		return nil, err
+	}
+
+	err = doc.Validate(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	router, err := legacy.NewRouter(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(c *gin.Context) {
+		route, pathParams, err := router.FindRoute(c.Request)
+		if err != nil {
+			// Ручки вне контракта (документация, служебные) не проверяем
+			c.Next()
+			return
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc},
+		}
+
+		err = validateRequest(c, input)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondBodyError(c, err)
+			return
+		}
+		if err != nil {
+			logger.Warn("Request violates OpenAPI contract", logFields(c, err)...)
+			abortWithError(c, http.StatusBadRequest, CodeContractViolation, err.Error())
+			return
+		}
+
+		// Поток событий не заканчивается, его ответ не копируется и не проверяется
+		if isStreamRequest(c) {
+			c.Next()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		responseInput := &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 recorder.Status(),
+			Header:                 recorder.Header(),
+			Options:                &openapi3filter.Options{IncludeResponseStatus: true},
+		}
+		responseInput.SetBodyBytes(recorder.body.Bytes())
+
+		err = openapi3filter.ValidateResponse(c.Request.Context(), responseInput)
+		if err != nil {
+			logger.Warn("Response violates OpenAPI contract", append(logFields(c, err), zap.String("route", route.Path))...)
+		}
+	}, nil
+}
+
+// validateRequest читает тело для проверки и возвращает его обратно, чтобы обработчик мог его разобрать
+func validateRequest(c *gin.Context, input *openapi3filter.RequestValidationInput) error {
+	body, err := readBody(c)
+	if err != nil {
+		return err
+	}
+
+	err = openapi3filter.ValidateRequest(c.Request.Context(), input)
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	return err
+}
+
+// responseRecorder копирует тело ответа для проверки, продолжая писать его клиенту
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,143 @@
+package http
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"transaction-system/internal/domain"
+)
+
+const (
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	PreferHeader             = "Prefer"
+	PreferenceAppliedHeader  = "Preference-Applied"
+	preferRespondAsync       = "respond-async"
+)
+
+type OperationQueue interface {
+	EnqueueOperation(ctx context.Context, apiKeyID int, idempotencyKey string, operation string, currencyCode int, amount float64, walletNumber int, cardNumber int) (*domain.Operations, error)
+	PerformOperation(ctx context.Context, apiKeyID int, idempotencyKey string, operation string, currencyCode int, amount float64, walletNumber int, cardNumber int) (*domain.Transactions, bool, error)
+	GetOperation(ctx context.Context, apiKeyID int, id int) (*domain.Operations, error)
+}
+
+type OperationURI struct {
+	ID int `uri:"id" binding:"required"`
+}
+
+type OperationResponse struct {
+	ID            int             `json:"id"`
+	Operation     string          `json:"operation"`
+	Status        string          `json:"status"`
+	CurrencyCode  int             `json:"currency_code"`
+	Amount        float64         `json:"amount"`
+	WalletNumber  int             `json:"wallet_number,omitempty"`
+	CardNumber    int             `json:"card_number,omitempty"`
+	TransactionID int             `json:"transaction_id,omitempty"`
+	Attempts      int             `json:"attempts"`
+	Error         *OperationError `json:"error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+func newOperationResponse(op *domain.Operations) OperationResponse {
+	response := OperationResponse{
+		ID:            op.ID,
+		Operation:     op.Operation,
+		Status:        op.Status,
+		CurrencyCode:  op.CurrencyCode,
+		Amount:        op.Amount,
+		WalletNumber:  op.WalletNumber,
+		CardNumber:    op.CardNumber,
+		TransactionID: op.TransactionID,
+		Attempts:      op.Attempts,
+		CreatedAt:     op.CreatedAt,
+		UpdatedAt:     op.UpdatedAt,
+	}
+	if op.ErrorCode != "" {
+		response.Error = &OperationError{Code: op.ErrorCode, Message: op.Error}
+	}
+
+	return response
+}
+
+// respondAsync - операцию нужно принять в очередь: асинхронный режим включен по умолчанию или запрошен заголовком
+// Prefer: respond-async
+func (c2 *Controller) respondAsync(c *gin.Context) bool {
+	if c2.operations == nil {
+		return false
+	}
+
+	if strings.Contains(c.GetHeader(PreferHeader), preferRespondAsync) {
+		c.Header(PreferenceAppliedHeader, preferRespondAsync)
+		return true
+	}
+
+	return c2.asyncByDefault
+}
+
+// enqueue отвечает 202 Accepted и ссылкой на статус операции в заголовке Location
+func (c2 *Controller) enqueue(c *gin.Context, operation string, req Request) {
+	op, err := c2.operations.EnqueueOperation(c, c.GetInt(apiKeyIDKey), c.GetHeader(IdempotencyKeyHeader), operation, req.CurrencyCode, req.Amount, req.WalletNumber, req.CardNumber)
+	if err != nil {
+		c2.logger.Error("Failed to enqueue operation", logFields(c, err)...)
+		respondError(c, err, "Failed to enqueue operation")
+		return
+	}
+
+	// Статус операции живет рядом с ручкой, которая ее приняла: /v1/invoice -> /v1/operations/{id}
+	c.Header("Location", path.Join(path.Dir(c.FullPath()), "operations", strconv.Itoa(op.ID)))
+	c.JSON(http.StatusAccepted, newOperationResponse(op))
+}
+
+// idempotent - синхронный запрос передан с Idempotency-Key, и его нужно провести через журнал операций
+func (c2 *Controller) idempotent(c *gin.Context) bool {
+	return c2.operations != nil && c.GetHeader(IdempotencyKeyHeader) != ""
+}
+
+// perform проводит операцию с Idempotency-Key синхронно. Ответ на повтор совпадает с ответом на первый запрос
+// и помечается заголовком Idempotent-Replayed
+func (c2 *Controller) perform(c *gin.Context, operation string, req Request) {
+	transaction, created, err := c2.operations.PerformOperation(c, c.GetInt(apiKeyIDKey), c.GetHeader(IdempotencyKeyHeader), operation, req.CurrencyCode, req.Amount, req.WalletNumber, req.CardNumber)
+	if err != nil {
+		c2.logger.Error("Failed to perform operation", logFields(c, err)...)
+		respondError(c, err, "Failed to perform operation")
+		return
+	}
+
+	if !created {
+		c.Header(IdempotentReplayedHeader, "true")
+	}
+
+	c.JSON(http.StatusOK, transaction)
+}
+
+func (c2 *Controller) GetOperation(c *gin.Context) {
+	var uri OperationURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		c2.logger.Error("Failed to parse operation id", logFields(c, err)...)
+		respondBindingError(c, err)
+		return
+	}
+
+	if c2.operations == nil {
+		abortWithError(c, http.StatusNotFound, CodeOperationNotFound, "Operation not found")
+		return
+	}
+
+	op, err := c2.operations.GetOperation(c, c.GetInt(apiKeyIDKey), uri.ID)
+	if err != nil {
+		c2.logger.Error("Failed to fetch operation", logFields(c, err)...)
+		respondError(c, err, "Failed to fetch operation")
+		return
+	}
+
+	if !authorizeRequisites(c, op.WalletNumber, op.CardNumber) {
+		return
+	}
+
+	c.JSON(http.StatusOK, newOperationResponse(op))
+}
@@ -0,0 +1,145 @@
+package http
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"transaction-system/config"
+	"transaction-system/pkg/metrics"
+	"transaction-system/pkg/ratelimit"
+)
+
+const CodeRateLimited = "rate_limited"
+
+// RateLimiter хранит корзины для всех групп ручек, чтобы /withdraw и /v1/withdraw делили один лимит
+type RateLimiter struct {
+	limiter *ratelimit.Limiter
+	rules   map[string]ratelimit.Rule
+	def     ratelimit.Rule
+	logger  *zap.Logger
+}
+
+func NewRateLimiter(cfg config.RateLimit, logger *zap.Logger) *RateLimiter {
+	rules := make(map[string]ratelimit.Rule, len(cfg.Routes))
+	for _, route := range cfg.Routes {
+		rules[route.Path] = ratelimit.Rule{RPS: route.RPS, Burst: route.Burst}
+	}
+
+	return &RateLimiter{
+		limiter: ratelimit.NewLimiter(),
+		rules:   rules,
+		def:     ratelimit.Rule{RPS: cfg.Default.RPS, Burst: cfg.Default.Burst},
+		logger:  logger,
+	}
+}
+
+// IPMiddleware ограничивает запросы группы ручек с префиксом версии prefix по IP клиента. Ставится до аутентификации,
+// чтобы перебор ключей и токенов с одного адреса тоже упирался в лимит
+func (l *RateLimiter) IPMiddleware(prefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		l.allow(c, prefix, func(add func(dimension string, value string)) {
+			add("ip", c.ClientIP())
+		})
+	}
+}
+
+// Middleware ограничивает запросы группы ручек с префиксом версии prefix по API-ключу и кошельку.
+// Ставится после аутентификации, чтобы лимит по API-ключу учитывал ключ или субъект JWT
+func (l *RateLimiter) Middleware(prefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal := principalFromContext(c)
+		wallet, err := walletForRateLimit(c, principal)
+		if err != nil {
+			respondBodyError(c, err)
+			return
+		}
+
+		allowed := l.allow(c, prefix, func(add func(dimension string, value string)) {
+			if principal != nil {
+				add("api_key", "jwt:"+principal.Subject)
+			} else if id := c.GetInt(apiKeyIDKey); id != 0 {
+				add("api_key", "key:"+strconv.Itoa(id))
+			}
+
+			if wallet != "" {
+				add("wallet", wallet)
+			}
+		})
+		if allowed {
+			metrics.RateLimitAllowed.WithLabelValues(strings.TrimPrefix(c.FullPath(), prefix)).Inc()
+		}
+	}
+}
+
+// allow списывает токены из корзин, которые собирает keys, и отвечает 429, если хотя бы одна из них пуста
+func (l *RateLimiter) allow(c *gin.Context, prefix string, keys func(add func(dimension string, value string))) bool {
+	route := strings.TrimPrefix(c.FullPath(), prefix)
+	rule, ok := l.rules[route]
+	if !ok {
+		rule = l.def
+	}
+
+	var dimensions, buckets []string
+	keys(func(dimension string, value string) {
+		dimensions = append(dimensions, dimension)
+		buckets = append(buckets, route+"|"+dimension+":"+value)
+	})
+
+	allowed, exhausted, wait := l.limiter.AllowAll(buckets, rule)
+	if !allowed {
+		// Отклоненные запросы считаются по признаку, по которому сработал лимит (api_key, wallet, ip)
+		metrics.RateLimitRejected.WithLabelValues(dimensions[exhausted], route).Inc()
+		l.logger.Warn("Rate limit exceeded", append(logFields(c, nil), zap.String("route", route), zap.String("limit", dimensions[exhausted]))...)
+
+		c.Header("Retry-After", retryAfter(wait))
+		abortWithError(c, http.StatusTooManyRequests, CodeRateLimited, "Too many requests")
+		return false
+	}
+
+	return true
+}
+
+// walletForRateLimit - кошелек запроса из токена, пути или тела. Ошибка возвращается, только если тело не прочиталось,
+// например, оказалось больше HTTP.MAX_BODY_SIZE
+func walletForRateLimit(c *gin.Context, principal *Principal) (string, error) {
+	if principal != nil && principal.Role == RoleClient {
+		return strconv.Itoa(principal.WalletNumber), nil
+	}
+
+	if wallet := c.Param("wallet_number"); wallet != "" {
+		return wallet, nil
+	}
+
+	if c.Request.ContentLength == 0 {
+		return "", nil
+	}
+
+	body, err := readBody(c)
+	if err != nil {
+		return "", err
+	}
+
+	var req struct {
+		WalletNumber int `json:"wallet_number"`
+	}
+	if json.Unmarshal(body, &req) != nil || req.WalletNumber == 0 {
+		return "", nil
+	}
+
+	return strconv.Itoa(req.WalletNumber), nil
+}
+
+// retryAfter - значение Retry-After в целых секундах, не меньше одной
+func retryAfter(wait time.Duration) string {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	return strconv.Itoa(seconds)
+}
@@ -1,58 +1,269 @@
 package http
 
 import (
+	"context"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"net"
 	"net/http"
+	"time"
 	"transaction-system/config"
+	"transaction-system/pkg/metrics"
+)
+
+// Текущая версия API. Ручки без префикса версии остаются устаревшими псевдонимами для нее
+const CurrentVersion = "/v1"
+
+const (
+	defaultReadTimeout  = 15 * time.Second
+	defaultWriteTimeout = 30 * time.Second
+	defaultIdleTimeout  = 2 * time.Minute
+	defaultMaxBodySize  = 1 << 20
 )
 
 type Router interface {
-	Start()
+	Start() error
+	Shutdown(ctx context.Context) error
 	RegisterRoutes()
 }
 
+// VersionedAPI регистрирует ручки одной версии API в группе роутера.
+// Чтобы добавить /v2, достаточно реализовать интерфейс новым контроллером и вызвать MountVersion.
+type VersionedAPI interface {
+	RegisterRoutes(rg gin.IRoutes)
+}
+
+type apiVersion struct {
+	prefix string
+	api    VersionedAPI
+}
+
 type RouterImpl struct {
-	controller *Controller
-	server     *gin.Engine
-	logger     *zap.Logger
-	url        string
+	controller      *Controller
+	adminController *AdminController
+	apiKeys         APIKeyLookup
+	versions        []apiVersion
+	root            []VersionedAPI
+	server          *gin.Engine
+	httpServer      *http.Server
+	logger          *zap.Logger
+	url             string
+	validateOpenAPI bool
+	trustedProxies  []string
+	auth            config.Auth
+	jwt             *JWTVerifier
+	rateLimiter     *RateLimiter
+	maxBodySize     int64
+}
+
+func NewRouter(cfg *config.Config, logger *zap.Logger, watController *Controller, adminController *AdminController, apiKeys APIKeyLookup) *RouterImpl {
+	r := &RouterImpl{
+		controller:      watController,
+		adminController: adminController,
+		apiKeys:         apiKeys,
+		logger:          logger,
+		url:             cfg.LocalURL,
+		validateOpenAPI: cfg.HTTP.ValidateOpenAPI,
+		trustedProxies:  cfg.HTTP.TrustedProxies,
+		auth:            cfg.Auth,
+		maxBodySize:     cfg.HTTP.MaxBodySize,
+	}
+	if r.maxBodySize <= 0 {
+		r.maxBodySize = defaultMaxBodySize
+	}
+
+	// Потоки SSE и WebSocket живут дольше любого таймаута запроса, поэтому они закрываются
+	// отменой базового контекста в начале Shutdown, а не ожиданием
+	baseCtx, cancelStreams := context.WithCancel(context.Background())
+	r.httpServer = &http.Server{
+		Addr:              cfg.LocalURL,
+		ReadTimeout:       seconds(cfg.HTTP.ReadTimeout, defaultReadTimeout),
+		ReadHeaderTimeout: seconds(cfg.HTTP.ReadTimeout, defaultReadTimeout),
+		WriteTimeout:      seconds(cfg.HTTP.WriteTimeout, defaultWriteTimeout),
+		IdleTimeout:       seconds(cfg.HTTP.IdleTimeout, defaultIdleTimeout),
+		BaseContext:       func(net.Listener) context.Context { return baseCtx },
+	}
+	r.httpServer.RegisterOnShutdown(cancelStreams)
+
+	if cfg.Auth.JWT.Enabled {
+		verifier, err := NewJWTVerifier(cfg.Auth.JWT)
+		if err != nil {
+			logger.Error("Failed to init JWT verifier, bearer tokens are rejected", zap.Error(err))
+		} else {
+			r.jwt = verifier
+		}
+	}
+
+	if cfg.RateLimit.Enabled {
+		r.rateLimiter = NewRateLimiter(cfg.RateLimit, logger)
+	}
+
+	r.MountVersion(CurrentVersion, watController)
+
+	return r
 }
 
-func NewRouter(cfg *config.Config, logger *zap.Logger, watController *Controller) *RouterImpl {
-	return &RouterImpl{controller: watController, logger: logger, url: cfg.LocalURL}
+// MountVersion добавляет версию API, вызывать до RegisterRoutes
+func (r *RouterImpl) MountVersion(prefix string, api VersionedAPI) {
+	r.versions = append(r.versions, apiVersion{prefix: prefix, api: api})
+}
+
+// MountRoot добавляет служебные ручки без префикса версии, аутентификации и лимитов, вызывать до RegisterRoutes
+func (r *RouterImpl) MountRoot(api VersionedAPI) {
+	r.root = append(r.root, api)
 }
 
 func (r *RouterImpl) RegisterRoutes() {
-	router := gin.Default()
+	router := gin.New()
+	router.Use(AccessLog(), gin.Recovery())
+	// Значения, положенные мидлварями в контекст запроса, доступны через *gin.Context в сервисе и хранилище
+	router.ContextWithFallback = true
+	// От IP клиента зависят лимиты, поэтому X-Forwarded-For принимается только от перечисленных прокси
+	err := router.SetTrustedProxies(r.trustedProxies)
+	if err != nil {
+		r.logger.Error("Invalid HTTP.TRUSTED_PROXIES, forwarded headers are ignored", zap.Error(err))
+		_ = router.SetTrustedProxies(nil)
+	}
+	router.Use(RequestID(), Tracing(), Metrics(), StreamAccessToken(), BodyLimit(r.maxBodySize))
 
-	router.POST("/invoice", func(c *gin.Context) {
+	if r.validateOpenAPI {
+		validator, err := OpenAPIValidator(r.logger)
+		if err != nil {
+			r.logger.Error("Failed to load OpenAPI spec, contract validation disabled", zap.Error(err))
+		} else {
+			router.Use(validator)
+		}
+	}
 
-		r.controller.AddAmount(c)
-	})
+	for _, v := range r.versions {
+		v.api.RegisterRoutes(router.Group(v.prefix, r.clientMiddleware(v.prefix)...))
+	}
 
-	router.POST("/withdraw", func(c *gin.Context) {
+	admin := router.Group(CurrentVersion+"/admin", AdminAuth(r.auth.AdminToken, r.jwt))
+	r.adminController.RegisterRoutes(admin)
 
-		r.controller.WithdrawAmount(c)
-	})
+	// Старые пути без версии
+	legacy := router.Group("", r.clientMiddleware("", deprecatedAlias(CurrentVersion))...)
+	r.controller.RegisterRoutes(legacy)
 
-	router.GET("/available-balance", func(c *gin.Context) {
+	// Устаревшие ручки: GET с телом теряется на прокси и в кэшах, вместо них /v1/wallets/:wallet_number/balance
+	legacyBalance := router.Group("", r.clientMiddleware("", deprecated(CurrentVersion+"/wallets/{wallet_number}/balance"))...)
+	legacyBalance.GET("/available-balance", func(c *gin.Context) {
 
 		r.controller.GetAvailableBalance(c)
 	})
 
-	router.GET("/frozen-balance", func(c *gin.Context) {
+	legacyBalance.GET("/frozen-balance", func(c *gin.Context) {
 
 		r.controller.GetFrozenBalance(c)
 	})
 
+	router.GET("/openapi.json", serveOpenAPISpec)
+	router.GET("/docs", serveSwaggerUI)
+
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
 	router.GET("/ping", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "200 OK"})
 	})
 
+	for _, api := range r.root {
+		api.RegisterRoutes(router)
+	}
+
 	r.server = router
+	r.httpServer.Handler = router
+}
+
+// clientAuth - мидлвари аутентификации для клиентских ручек, пустой список, если аутентификация выключена.
+// Принимается JWT роли client или admin либо подпись API-ключа.
+func (r *RouterImpl) clientAuth() []gin.HandlerFunc {
+	if !r.auth.APIKeys.Enabled && !r.auth.JWT.Enabled {
+		return nil
+	}
+
+	var apiKeyAuth gin.HandlerFunc
+	if r.auth.APIKeys.Enabled {
+		window := time.Duration(r.auth.APIKeys.ReplayWindow) * time.Second
+		apiKeyAuth = APIKeyAuth(r.apiKeys, window, r.logger)
+	}
+
+	return []gin.HandlerFunc{Authenticate(r.jwt, apiKeyAuth, RoleClient, RoleAdmin)}
 }
 
+// clientMiddleware - мидлвари клиентских ручек группы с префиксом версии prefix: пометки устаревшей ручки marks,
+// лимит по IP, аутентификация и лимиты по API-ключу и кошельку. Лимит по IP стоит до аутентификации,
+// поэтому запросы с неверной подписью или токеном тоже расходуют лимит
+func (r *RouterImpl) clientMiddleware(prefix string, marks ...gin.HandlerFunc) []gin.HandlerFunc {
+	middleware := append([]gin.HandlerFunc{}, marks...)
+	if r.rateLimiter != nil {
+		middleware = append(middleware, r.rateLimiter.IPMiddleware(prefix))
+	}
+
+	middleware = append(middleware, r.clientAuth()...)
+	if r.rateLimiter != nil {
+		middleware = append(middleware, r.rateLimiter.Middleware(prefix))
+	}
+
+	return middleware
+}
+
+// Handler - зарегистрированные ручки, доступен после RegisterRoutes
+func (r *RouterImpl) Handler() http.Handler {
+	return r.server
+}
+
+// Start слушает THIS_APP_URL и блокируется до Shutdown, после которого возвращает nil
 func (r *RouterImpl) Start() error {
-	return r.server.Run(r.url)
+	r.logger.Info("Listening and serving HTTP", zap.String("addr", r.url))
+
+	err := r.httpServer.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+// Shutdown перестает принимать соединения, закрывает потоки и дожидается текущих запросов, но не дольше ctx
+func (r *RouterImpl) Shutdown(ctx context.Context) error {
+	return r.httpServer.Shutdown(ctx)
+}
+
+func seconds(value int, fallback time.Duration) time.Duration {
+	if value <= 0 {
+		return fallback
+	}
+
+	return time.Duration(value) * time.Second
+}
+
+// BodyLimit ограничивает тело запроса limit байтами. Тело читают до аутентификации (подпись, лимиты по кошельку,
+// проверка контракта), поэтому ограничение стоит до них, а чтение сверх limit завершается ответом 413
+func BodyLimit(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		}
+		c.Next()
+	}
+}
+
+// deprecated помечает ручку заголовком Deprecation и ссылкой на замену
+func deprecated(successor string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		c.Header("Link", "<"+successor+`>; rel="successor-version"`)
+		c.Next()
+	}
+}
+
+// deprecatedAlias помечает ручку без версии ссылкой на такой же путь под префиксом версии
+func deprecatedAlias(prefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		c.Header("Link", "<"+prefix+c.FullPath()+`>; rel="successor-version"`)
+		c.Next()
+	}
 }
@@ -0,0 +1,358 @@
+package http
+
+import (
+	"context"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"transaction-system/config"
+	"transaction-system/internal/domain"
+	"transaction-system/service"
+)
+
+const (
+	LastEventIDHeader = "Last-Event-ID"
+
+	streamEventTransaction = "transaction"
+	streamEventBalance     = "balance"
+
+	defaultStreamHeartbeat = 15 * time.Second
+	streamWriteTimeout     = 10 * time.Second
+)
+
+type WalletStream interface {
+	Subscribe(ctx context.Context, walletNumber int) (*service.Subscription, error)
+	Replay(ctx context.Context, sub *service.Subscription, after int64, send func(domain.TransactionEvent) error) (int64, error)
+	Balance(ctx context.Context, walletNumber int) (*domain.Balance, error)
+}
+
+type StreamController struct {
+	stream    WalletStream
+	heartbeat time.Duration
+	upgrader  websocket.Upgrader
+	logger    *zap.Logger
+}
+
+func NewStreamController(stream WalletStream, cfg *config.Config, logger *zap.Logger) *StreamController {
+	heartbeat := time.Duration(cfg.Stream.Heartbeat) * time.Second
+	if heartbeat <= 0 {
+		heartbeat = defaultStreamHeartbeat
+	}
+
+	return &StreamController{
+		stream:    stream,
+		heartbeat: heartbeat,
+		upgrader:  websocket.Upgrader{CheckOrigin: checkOrigin(cfg.Stream.AllowedOrigins)},
+		logger:    logger,
+	}
+}
+
+// StreamTransactionEvent - смена статуса транзакции в потоке кошелька
+type StreamTransactionEvent struct {
+	Sequence      int64     `json:"sequence"`
+	TransactionID int       `json:"transaction_id"`
+	Status        string    `json:"status"`
+	Amount        float64   `json:"amount"`
+	CurrencyID    int       `json:"currency_id"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// StreamMessage - сообщение WebSocket, поля совпадают с полями события SSE
+type StreamMessage struct {
+	ID    string `json:"id,omitempty"`
+	Event string `json:"event"`
+	Data  any    `json:"data"`
+}
+
+// streamWriter - транспорт потока: SSE или WebSocket
+type streamWriter interface {
+	send(event string, id string, data any) error
+	heartbeat() error
+	close()
+}
+
+// Events отдает поток кошелька как Server-Sent Events
+func (sc *StreamController) Events(c *gin.Context) {
+	sc.serve(c, func(c *gin.Context) (streamWriter, error) {
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		// Отключает буферизацию ответа в nginx
+		c.Header("X-Accel-Buffering", "no")
+
+		// Таймауты сервера рассчитаны на обычные запросы: поток снимает их и ограничивает каждую запись отдельно
+		rc := http.NewResponseController(c.Writer)
+		if err := rc.SetReadDeadline(time.Time{}); err != nil {
+			return nil, err
+		}
+
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+
+		return &sseWriter{c: c, rc: rc}, nil
+	})
+}
+
+// WebSocket отдает тот же поток, что и Events, сообщениями StreamMessage
+func (sc *StreamController) WebSocket(c *gin.Context) {
+	sc.serve(c, func(c *gin.Context) (streamWriter, error) {
+		conn, err := sc.upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		// Таймауты сервера к соединению не относятся: оно живет, пока клиент отвечает pong на ping каждого heartbeat.
+		// Клиент, пропустивший два ping подряд, отключается
+		pongWait := 2 * sc.heartbeat
+		err = conn.SetReadDeadline(time.Now().Add(pongWait))
+		if err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(pongWait))
+		})
+
+		// Сообщения клиента не нужны, но читать их необходимо, чтобы заметить закрытие соединения
+		ctx, cancel := context.WithCancel(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+		go func() {
+			defer cancel()
+			for {
+				if _, _, err := conn.NextReader(); err != nil {
+					return
+				}
+			}
+		}()
+
+		return &wsWriter{conn: conn}, nil
+	})
+}
+
+// serve подписывается на события кошелька, дочитывает из журнала события после Last-Event-ID,
+// отправляет текущий баланс, а затем события из шины, после каждого - обновленный баланс
+func (sc *StreamController) serve(c *gin.Context, open func(c *gin.Context) (streamWriter, error)) {
+	var uri WalletURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		sc.logger.Error("Failed to parse wallet number", logFields(c, err)...)
+		respondBindingError(c, err)
+		return
+	}
+
+	if !authorizeRequisites(c, uri.WalletNumber, 0) {
+		return
+	}
+
+	lastID, resume, ok := lastEventID(c)
+	if !ok {
+		abortWithError(c, http.StatusBadRequest, CodeInvalidRequest, "Last-Event-ID must be an event sequence number")
+		return
+	}
+
+	// Подписка оформляется до чтения журнала, чтобы не потерять события между ними
+	sub, err := sc.stream.Subscribe(c, uri.WalletNumber)
+	if err != nil {
+		sc.logger.Error("Failed to subscribe to wallet events", logFields(c, err)...)
+		respondError(c, err, "Failed to subscribe to wallet events")
+		return
+	}
+	defer sub.Close()
+
+	w, err := open(c)
+	if err != nil {
+		sc.logger.Error("Failed to open wallet stream", logFields(c, err)...)
+		return
+	}
+	defer w.close()
+
+	last := lastID
+	sendTransaction := func(event domain.TransactionEvent) error {
+		return w.send(streamEventTransaction, strconv.FormatInt(event.Sequence, 10), newStreamTransactionEvent(event))
+	}
+
+	if resume {
+		last, err = sc.stream.Replay(c, sub, lastID, sendTransaction)
+		if err != nil {
+			sc.logger.Error("Failed to replay wallet events", logFields(c, err)...)
+			return
+		}
+	}
+
+	err = sc.sendBalance(c, w, uri.WalletNumber)
+	if err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(sc.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-ticker.C:
+			if w.heartbeat() != nil {
+				return
+			}
+		case event, ok := <-sub.Events:
+			if !ok {
+				// Подписчик не успевал забирать события, клиент переподключится с Last-Event-ID
+				return
+			}
+
+			// Событие уже отправлено из журнала
+			if event.Sequence <= last {
+				continue
+			}
+
+			if sendTransaction(event) != nil {
+				return
+			}
+			last = event.Sequence
+
+			if sc.sendBalance(c, w, uri.WalletNumber) != nil {
+				return
+			}
+		}
+	}
+}
+
+func (sc *StreamController) sendBalance(c *gin.Context, w streamWriter, walletNumber int) error {
+	balance, err := sc.stream.Balance(c, walletNumber)
+	if err != nil {
+		sc.logger.Error("Failed to fetch balance", logFields(c, err)...)
+		return err
+	}
+
+	return w.send(streamEventBalance, "", newBalanceResponse(balance))
+}
+
+// lastEventID - номер последнего полученного события из заголовка Last-Event-ID или параметра last_event_id.
+// EventSource сам передает заголовок при переподключении, параметр нужен для WebSocket.
+// Третье значение равно false, если номер передан, но не разобран
+func lastEventID(c *gin.Context) (int64, bool, bool) {
+	value := c.GetHeader(LastEventIDHeader)
+	if value == "" {
+		value = c.Query("last_event_id")
+	}
+	if value == "" {
+		return 0, false, true
+	}
+
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		return 0, false, false
+	}
+
+	return id, true, true
+}
+
+func newStreamTransactionEvent(event domain.TransactionEvent) StreamTransactionEvent {
+	return StreamTransactionEvent{
+		Sequence:      event.Sequence,
+		TransactionID: event.Transaction.ID,
+		Status:        event.Transaction.Status,
+		Amount:        event.Transaction.Amount,
+		CurrencyID:    event.Transaction.CurrencyID,
+		CreatedAt:     event.Transaction.CreatedAt,
+	}
+}
+
+// checkOrigin пускает WebSocket со страниц того же хоста и из allowed, например https://app.example.com.
+// Браузер всегда передает Origin страницы, поэтому чужой сайт не откроет поток даже с токеном пользователя.
+// Клиенты не из браузера Origin не передают, их запросы проверяет только аутентификация
+func checkOrigin(allowed []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" || slices.Contains(allowed, origin) {
+			return true
+		}
+
+		parsed, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(parsed.Host, r.Host)
+	}
+}
+
+// isStreamRequest - запрос открывает поток SSE или WebSocket
+func isStreamRequest(c *gin.Context) bool {
+	return strings.Contains(c.GetHeader("Accept"), "text/event-stream") ||
+		strings.EqualFold(c.GetHeader("Upgrade"), "websocket")
+}
+
+// StreamAccessToken переносит JWT из параметра access_token в заголовок Authorization для запросов потока:
+// EventSource и WebSocket в браузере не умеют передавать свои заголовки. Параметр убирается из запроса,
+// чтобы токен не попал в журналы дальше по цепочке, а AccessLog не пишет строку запроса вовсе
+func StreamAccessToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := c.Request.URL.Query()
+		token := query.Get("access_token")
+		if token != "" {
+			query.Del("access_token")
+			c.Request.URL.RawQuery = query.Encode()
+		}
+
+		if token != "" && c.GetHeader("Authorization") == "" && isStreamRequest(c) {
+			c.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+		c.Next()
+	}
+}
+
+type sseWriter struct {
+	c  *gin.Context
+	rc *http.ResponseController
+}
+
+func (w *sseWriter) send(event string, id string, data any) error {
+	_ = w.rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	err := sse.Encode(w.c.Writer, sse.Event{Id: id, Event: event, Data: data})
+	if err != nil {
+		return err
+	}
+	w.c.Writer.Flush()
+
+	return nil
+}
+
+func (w *sseWriter) heartbeat() error {
+	_ = w.rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	_, err := w.c.Writer.WriteString(": heartbeat\n\n")
+	if err != nil {
+		return err
+	}
+	w.c.Writer.Flush()
+
+	return nil
+}
+
+func (w *sseWriter) close() {}
+
+type wsWriter struct {
+	conn *websocket.Conn
+}
+
+func (w *wsWriter) send(event string, id string, data any) error {
+	_ = w.conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	return w.conn.WriteJSON(StreamMessage{ID: id, Event: event, Data: data})
+}
+
+func (w *wsWriter) heartbeat() error {
+	return w.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout))
+}
+
+func (w *wsWriter) close() {
+	_ = w.conn.Close()
+}
+
+// RegisterRoutes - ручки потока событий кошелька
+func (sc *StreamController) RegisterRoutes(rg gin.IRoutes) {
+	rg.GET("/wallets/:wallet_number/events", sc.Events)
+	rg.GET("/wallets/:wallet_number/ws", sc.WebSocket)
+}
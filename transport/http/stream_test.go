@@ -0,0 +1,141 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"transaction-system/config"
+	"transaction-system/internal/domain"
+	"transaction-system/pkg/eventbus"
+	"transaction-system/service"
+	transport "transaction-system/transport/http"
+)
+
+const (
+	testWallet   = 101234567
+	testClientID = 1
+)
+
+// wallet - кошелек testWallet без событий в журнале
+type wallet struct {
+	service.DataBaseRepository
+}
+
+func (wallet) FindClient(_ context.Context, _ int, _ int) (*domain.Clients, error) {
+	return &domain.Clients{ID: testClientID, WalletNumber: testWallet}, nil
+}
+
+func (wallet) ListClientEvents(_ context.Context, _ int, _ int64, _ int) ([]domain.TransactionEvent, error) {
+	return nil, nil
+}
+
+func (wallet) GetBalance(_ context.Context, _ int, _ int) (*domain.Balance, error) {
+	return &domain.Balance{Currencies: []domain.CurrencyBalance{{CurrencyCode: 840, Available: 10}}}, nil
+}
+
+// openWebSocket открывает поток кошелька testWallet на сервере с READ_TIMEOUT и WRITE_TIMEOUT меньше heartbeat
+func openWebSocket(t *testing.T) (*service.StreamHub, *websocket.Conn) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{Stream: config.Stream{Heartbeat: 1}}
+	logger := zap.NewNop()
+	hub := service.NewStreamHub(wallet{}, service.NewDataBaseWorker(wallet{}, cfg), cfg, logger)
+
+	router := gin.New()
+	transport.NewStreamController(hub, cfg, logger).RegisterRoutes(router)
+
+	server := httptest.NewUnstartedServer(router)
+	server.Config.ReadTimeout = 500 * time.Millisecond
+	server.Config.WriteTimeout = 500 * time.Millisecond
+	server.Start()
+	t.Cleanup(server.Close)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/wallets/101234567/ws"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return hub, conn
+}
+
+// Поток WebSocket не должен обрываться по READ_TIMEOUT сервера, пока клиент отвечает на ping
+func TestWebSocketOutlivesReadTimeout(t *testing.T) {
+	hub, conn := openWebSocket(t)
+
+	// Клиент постоянно читает, чтобы отвечать на ping сервера
+	messages := make(chan transport.StreamMessage)
+	go func() {
+		defer close(messages)
+		for {
+			var message transport.StreamMessage
+			if conn.ReadJSON(&message) != nil {
+				return
+			}
+			messages <- message
+		}
+	}()
+
+	if message := <-messages; message.Event != "balance" {
+		t.Fatalf("first event = %q, want balance", message.Event)
+	}
+
+	time.Sleep(3 * time.Second)
+
+	value, err := json.Marshal(domain.TransactionEvent{
+		ClientID:    testClientID,
+		Sequence:    1,
+		Transaction: &domain.Transactions{ID: 1, ClientID: testClientID, Amount: 5, Status: "Success"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = hub.HandleEvent(context.Background(), eventbus.Message{Value: value})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case message, ok := <-messages:
+		if !ok {
+			t.Fatal("stream closed before the event")
+		}
+		if message.Event != "transaction" || message.ID != "1" {
+			t.Fatalf("event = %q id = %q, want transaction 1", message.Event, message.ID)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event within 2s")
+	}
+}
+
+// Клиент, который не отвечает на ping, отключается после двух heartbeat
+func TestWebSocketDropsClientWithoutPong(t *testing.T) {
+	_, conn := openWebSocket(t)
+
+	// Пока клиент не читает, ping остаются без ответа
+	time.Sleep(3 * time.Second)
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		_, _, err := conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			t.Fatal("stream is still open after missed pings")
+		}
+		return
+	}
+}
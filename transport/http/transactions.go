@@ -0,0 +1,128 @@
+package http
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+	"transaction-system/internal/domain"
+)
+
+const defaultTransactionsLimit = 50
+
+type TransactionLedger interface {
+	GetTransactionController(ctx context.Context, id int) (*domain.Transactions, error)
+	ListTransactionsController(ctx context.Context, filter domain.TransactionFilter) ([]domain.Transactions, error)
+	RefundTransactionController(ctx context.Context, id int) (*domain.Transactions, error)
+}
+
+type TransactionController struct {
+	ledger TransactionLedger
+	logger *zap.Logger
+}
+
+func NewTransactionController(ledger TransactionLedger, logger *zap.Logger) *TransactionController {
+	return &TransactionController{ledger: ledger, logger: logger}
+}
+
+type TransactionURI struct {
+	ID int `uri:"id" binding:"required"`
+}
+
+type TransactionListQuery struct {
+	WalletNumber int    `form:"wallet_number" binding:"required_without=CardNumber"`
+	CardNumber   int    `form:"card_number" binding:"required_without=WalletNumber"`
+	Status       string `form:"status" binding:"omitempty,oneof=Created Success Error"`
+	BeforeID     int    `form:"before_id" binding:"omitempty,min=1"`
+	Limit        int    `form:"limit" binding:"omitempty,min=1,max=500"`
+}
+
+func (tc *TransactionController) GetTransaction(c *gin.Context) {
+	var uri TransactionURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		tc.logger.Error("Failed to parse transaction id", logFields(c, err)...)
+		respondBindingError(c, err)
+		return
+	}
+
+	transaction, err := tc.ledger.GetTransactionController(c, uri.ID)
+	if err != nil {
+		tc.logger.Error("Failed to fetch transaction", logFields(c, err)...)
+		respondError(c, err, "Failed to fetch transaction")
+		return
+	}
+
+	if !authorizeRequisites(c, transaction.Client.WalletNumber, 0) {
+		return
+	}
+
+	c.JSON(http.StatusOK, transaction)
+}
+
+// ListTransactions - транзакции клиента, новые первыми. Следующая страница - ?before_id=<ID последней транзакции>
+func (tc *TransactionController) ListTransactions(c *gin.Context) {
+	var query TransactionListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		tc.logger.Error("Failed to parse query", logFields(c, err)...)
+		respondBindingError(c, err)
+		return
+	}
+
+	if !authorizeRequisites(c, query.WalletNumber, query.CardNumber) {
+		return
+	}
+
+	if query.Limit == 0 {
+		query.Limit = defaultTransactionsLimit
+	}
+
+	transactions, err := tc.ledger.ListTransactionsController(c, domain.TransactionFilter{
+		WalletNumber: query.WalletNumber,
+		CardNumber:   query.CardNumber,
+		Status:       query.Status,
+		BeforeID:     query.BeforeID,
+		Limit:        query.Limit,
+	})
+	if err != nil {
+		tc.logger.Error("Failed to list transactions", logFields(c, err)...)
+		respondError(c, err, "Failed to list transactions")
+		return
+	}
+
+	if transactions == nil {
+		transactions = []domain.Transactions{}
+	}
+
+	c.JSON(http.StatusOK, transactions)
+}
+
+// RefundTransaction создает возврат транзакции и отвечает 201. Клиентским токенам возвраты недоступны
+func (tc *TransactionController) RefundTransaction(c *gin.Context) {
+	var uri TransactionURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		tc.logger.Error("Failed to parse transaction id", logFields(c, err)...)
+		respondBindingError(c, err)
+		return
+	}
+
+	if principalWallet(c) != 0 {
+		abortWithError(c, http.StatusForbidden, CodeForbidden, "Client tokens are not allowed to refund transactions")
+		return
+	}
+
+	refund, err := tc.ledger.RefundTransactionController(c, uri.ID)
+	if err != nil {
+		tc.logger.Error("Failed to refund transaction", logFields(c, err)...)
+		respondError(c, err, "Failed to refund transaction")
+		return
+	}
+
+	c.JSON(http.StatusCreated, refund)
+}
+
+// RegisterRoutes - ручки транзакций версии v1
+func (tc *TransactionController) RegisterRoutes(rg gin.IRoutes) {
+	rg.GET("/transactions", tc.ListTransactions)
+	rg.GET("/transactions/:id", tc.GetTransaction)
+	rg.POST("/transactions/:id/refund", tc.RefundTransaction)
+}
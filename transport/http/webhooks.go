@@ -0,0 +1,314 @@
+package http
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"time"
+	"transaction-system/internal/domain"
+	"transaction-system/service"
+)
+
+const defaultDeliveriesLimit = 50
+
+type WebhookManager interface {
+	CreateWebhook(ctx context.Context, caller service.Caller, url string, events []string, walletNumber int) (*domain.Webhooks, error)
+	ListWebhooks(ctx context.Context, caller service.Caller, walletNumber int) ([]domain.Webhooks, error)
+	GetWebhook(ctx context.Context, caller service.Caller, id int) (*domain.Webhooks, error)
+	DeleteWebhook(ctx context.Context, caller service.Caller, id int) error
+	ListDeliveries(ctx context.Context, caller service.Caller, id int, status string, limit int) ([]domain.WebhookDeliveries, error)
+	ResendDelivery(ctx context.Context, caller service.Caller, id int, deliveryID int) (*domain.WebhookDeliveries, error)
+}
+
+type WebhookController struct {
+	webhooks WebhookManager
+	logger   *zap.Logger
+}
+
+func NewWebhookController(webhooks WebhookManager, logger *zap.Logger) *WebhookController {
+	return &WebhookController{webhooks: webhooks, logger: logger}
+}
+
+// WebhookRequest - без wallet_number подписка создается на транзакции API-ключа запроса
+type WebhookRequest struct {
+	URL          string   `json:"url" binding:"required,max=2048"`
+	Events       []string `json:"events"`
+	WalletNumber int      `json:"wallet_number"`
+}
+
+type WebhookURI struct {
+	ID int `uri:"id" binding:"required"`
+}
+
+type WebhookDeliveryURI struct {
+	ID         int `uri:"id" binding:"required"`
+	DeliveryID int `uri:"delivery_id" binding:"required"`
+}
+
+type WebhookListQuery struct {
+	WalletNumber int `form:"wallet_number"`
+}
+
+type WebhookDeliveriesQuery struct {
+	Status string `form:"status" binding:"omitempty,oneof=Pending Sending Delivered Failed"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=500"`
+}
+
+type WebhookResponse struct {
+	ID        int       `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	ClientID  int       `json:"client_id,omitempty"`
+	APIKeyID  int       `json:"api_key_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// Secret возвращается только при создании подписки
+	Secret string `json:"secret,omitempty"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID             int                      `json:"id"`
+	EventID        string                   `json:"event_id"`
+	EventType      string                   `json:"event_type"`
+	Status         string                   `json:"status"`
+	Attempts       int                      `json:"attempts"`
+	NextAttemptAt  *time.Time               `json:"next_attempt_at,omitempty"`
+	LastStatusCode int                      `json:"last_status_code,omitempty"`
+	LastError      string                   `json:"last_error,omitempty"`
+	CreatedAt      time.Time                `json:"created_at"`
+	DeliveredAt    *time.Time               `json:"delivered_at,omitempty"`
+	Log            []WebhookAttemptResponse `json:"log"`
+}
+
+type WebhookAttemptResponse struct {
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func newWebhookResponse(hook *domain.Webhooks) WebhookResponse {
+	events := hook.Events
+	if events == nil {
+		events = []string{}
+	}
+
+	return WebhookResponse{
+		ID:        hook.ID,
+		URL:       hook.URL,
+		Events:    events,
+		ClientID:  hook.ClientID,
+		APIKeyID:  hook.APIKeyID,
+		CreatedAt: hook.CreatedAt,
+	}
+}
+
+func newWebhookDeliveryResponse(delivery *domain.WebhookDeliveries) WebhookDeliveryResponse {
+	response := WebhookDeliveryResponse{
+		ID:             delivery.ID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		NextAttemptAt:  delivery.NextAttemptAt,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+		DeliveredAt:    delivery.DeliveredAt,
+		Log:            make([]WebhookAttemptResponse, 0, len(delivery.Log)),
+	}
+
+	for _, attempt := range delivery.Log {
+		response.Log = append(response.Log, WebhookAttemptResponse{
+			Attempt:    attempt.Attempt,
+			StatusCode: attempt.StatusCode,
+			Error:      attempt.Error,
+			DurationMs: attempt.Duration,
+			CreatedAt:  attempt.CreatedAt,
+		})
+	}
+
+	return response
+}
+
+// webhookCaller - API-ключ, кошелек клиентского токена и признак администратора запроса
+func webhookCaller(c *gin.Context) service.Caller {
+	return service.Caller{
+		APIKeyID: c.GetInt(apiKeyIDKey),
+		Wallet:   principalWallet(c),
+		Admin:    domain.IsAdminFromContext(c.Request.Context()),
+	}
+}
+
+// principalWallet - кошелек клиентского токена, которым ограничен доступ к подпискам, 0 для остальных запросов
+func principalWallet(c *gin.Context) int {
+	principal := principalFromContext(c)
+	if principal == nil || principal.Role != RoleClient {
+		return 0
+	}
+
+	return principal.WalletNumber
+}
+
+func (wc *WebhookController) CreateWebhook(c *gin.Context) {
+	var req WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		wc.logger.Error("Failed to parse request body", logFields(c, err)...)
+		respondBindingError(c, err)
+		return
+	}
+
+	if req.WalletNumber == 0 {
+		req.WalletNumber = principalWallet(c)
+	}
+
+	if !authorizeRequisites(c, req.WalletNumber, 0) {
+		return
+	}
+
+	hook, err := wc.webhooks.CreateWebhook(c, webhookCaller(c), req.URL, req.Events, req.WalletNumber)
+	if err != nil {
+		wc.logger.Error("Failed to create webhook", logFields(c, err)...)
+		respondError(c, err, "Failed to create webhook")
+		return
+	}
+
+	response := newWebhookResponse(hook)
+	response.Secret = hook.Secret
+
+	c.Header("Location", c.Request.URL.Path+"/"+strconv.Itoa(hook.ID))
+	c.JSON(http.StatusCreated, response)
+}
+
+func (wc *WebhookController) ListWebhooks(c *gin.Context) {
+	var query WebhookListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		wc.logger.Error("Failed to parse query", logFields(c, err)...)
+		respondBindingError(c, err)
+		return
+	}
+
+	if query.WalletNumber == 0 {
+		query.WalletNumber = principalWallet(c)
+	}
+
+	if !authorizeRequisites(c, query.WalletNumber, 0) {
+		return
+	}
+
+	hooks, err := wc.webhooks.ListWebhooks(c, webhookCaller(c), query.WalletNumber)
+	if err != nil {
+		wc.logger.Error("Failed to list webhooks", logFields(c, err)...)
+		respondError(c, err, "Failed to list webhooks")
+		return
+	}
+
+	response := make([]WebhookResponse, 0, len(hooks))
+	for i := range hooks {
+		response = append(response, newWebhookResponse(&hooks[i]))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (wc *WebhookController) GetWebhook(c *gin.Context) {
+	var uri WebhookURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		wc.logger.Error("Failed to parse webhook id", logFields(c, err)...)
+		respondBindingError(c, err)
+		return
+	}
+
+	hook, err := wc.webhooks.GetWebhook(c, webhookCaller(c), uri.ID)
+	if err != nil {
+		wc.logger.Error("Failed to fetch webhook", logFields(c, err)...)
+		respondError(c, err, "Failed to fetch webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, newWebhookResponse(hook))
+}
+
+func (wc *WebhookController) DeleteWebhook(c *gin.Context) {
+	var uri WebhookURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		wc.logger.Error("Failed to parse webhook id", logFields(c, err)...)
+		respondBindingError(c, err)
+		return
+	}
+
+	err := wc.webhooks.DeleteWebhook(c, webhookCaller(c), uri.ID)
+	if err != nil {
+		wc.logger.Error("Failed to delete webhook", logFields(c, err)...)
+		respondError(c, err, "Failed to delete webhook")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListDeliveries - журнал доставок подписки, новые первыми. ?status=Failed оставляет только неудачные
+func (wc *WebhookController) ListDeliveries(c *gin.Context) {
+	var uri WebhookURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		wc.logger.Error("Failed to parse webhook id", logFields(c, err)...)
+		respondBindingError(c, err)
+		return
+	}
+
+	var query WebhookDeliveriesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		wc.logger.Error("Failed to parse query", logFields(c, err)...)
+		respondBindingError(c, err)
+		return
+	}
+
+	if query.Limit == 0 {
+		query.Limit = defaultDeliveriesLimit
+	}
+
+	deliveries, err := wc.webhooks.ListDeliveries(c, webhookCaller(c), uri.ID, query.Status, query.Limit)
+	if err != nil {
+		wc.logger.Error("Failed to list webhook deliveries", logFields(c, err)...)
+		respondError(c, err, "Failed to list webhook deliveries")
+		return
+	}
+
+	response := make([]WebhookDeliveryResponse, 0, len(deliveries))
+	for i := range deliveries {
+		response = append(response, newWebhookDeliveryResponse(&deliveries[i]))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ResendDelivery возвращает доставку в очередь и отвечает 202: отправка выполняется в фоне
+func (wc *WebhookController) ResendDelivery(c *gin.Context) {
+	var uri WebhookDeliveryURI
+	if err := c.ShouldBindUri(&uri); err != nil {
+		wc.logger.Error("Failed to parse webhook delivery id", logFields(c, err)...)
+		respondBindingError(c, err)
+		return
+	}
+
+	delivery, err := wc.webhooks.ResendDelivery(c, webhookCaller(c), uri.ID, uri.DeliveryID)
+	if err != nil {
+		wc.logger.Error("Failed to resend webhook delivery", logFields(c, err)...)
+		respondError(c, err, "Failed to resend webhook delivery")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, newWebhookDeliveryResponse(delivery))
+}
+
+// RegisterRoutes - ручки вебхуков версии v1
+func (wc *WebhookController) RegisterRoutes(rg gin.IRoutes) {
+	rg.POST("/webhooks", wc.CreateWebhook)
+	rg.GET("/webhooks", wc.ListWebhooks)
+	rg.GET("/webhooks/:id", wc.GetWebhook)
+	rg.DELETE("/webhooks/:id", wc.DeleteWebhook)
+	rg.GET("/webhooks/:id/deliveries", wc.ListDeliveries)
+	rg.POST("/webhooks/:id/deliveries/:delivery_id/resend", wc.ResendDelivery)
+}